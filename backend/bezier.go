@@ -0,0 +1,190 @@
+package backend
+
+import (
+	"math"
+	"sort"
+
+	"github.com/iliyanmotovski/raytracer/backend/vector"
+)
+
+// DefaultFlatteningTolerance is the maximum distance between a Bezier
+// curve and its flattened approximation used when none is configured
+const DefaultFlatteningTolerance = 0.5
+
+const (
+	// flatteningMaxDepth limits the recursive subdivision of a curve
+	flatteningMaxDepth = 16
+	// silhouetteSamples is the number of intervals in which a curve is sampled
+	// when searching for silhouette points, before each one is refined by bisection
+	silhouetteSamples = 64
+)
+
+// Bezier represents a quadratic or cubic Bezier curve, Points holds the
+// start point, the 1 or 2 control points and the end point in this order
+type Bezier struct {
+	Points vector.Vectors
+}
+
+// At returns the point of the curve at parameter t using de Casteljau's algorithm
+func (b *Bezier) At(t float64) vector.Vector {
+	points := make([]vector.Vector, len(b.Points))
+	for i, p := range b.Points {
+		points[i] = *p
+	}
+
+	for n := len(points) - 1; n > 0; n-- {
+		for i := 0; i < n; i++ {
+			points[i] = lerp(points[i], points[i+1], t)
+		}
+	}
+
+	return points[0]
+}
+
+// Derivative returns the tangent vector of the curve at parameter t
+func (b *Bezier) Derivative(t float64) vector.Vector {
+	n := len(b.Points) - 1
+	derivative := &Bezier{Points: make(vector.Vectors, n)}
+
+	for i := 0; i < n; i++ {
+		d := b.Points[i+1].Sub(*b.Points[i]).MultiplyByScalar(float64(n))
+		derivative.Points[i] = &d
+	}
+
+	return derivative.At(t)
+}
+
+// Split divides the curve at parameter t into two curves of the same degree
+func (b *Bezier) Split(t float64) (*Bezier, *Bezier) {
+	n := len(b.Points)
+	points := make([]vector.Vector, n)
+	for i, p := range b.Points {
+		points[i] = *p
+	}
+
+	left := make(vector.Vectors, n)
+	right := make(vector.Vectors, n)
+
+	for level := 0; level < n; level++ {
+		first := points[0]
+		last := points[n-1-level]
+		left[level] = &first
+		right[n-1-level] = &last
+
+		for i := 0; i < n-1-level; i++ {
+			points[i] = lerp(points[i], points[i+1], t)
+		}
+	}
+
+	return &Bezier{Points: left}, &Bezier{Points: right}
+}
+
+// IsFlat returns whether all control points are closer to the
+// line connecting the start and end points than the tolerance
+func (b *Bezier) IsFlat(tolerance float64) bool {
+	start := b.Points[0]
+	end := b.Points[len(b.Points)-1]
+	chord := end.Sub(*start)
+	length := chord.Length()
+
+	for _, control := range b.Points[1 : len(b.Points)-1] {
+		var distance float64
+		if length == 0 {
+			distance = control.Distance(*start)
+		} else {
+			distance = math.Abs(vector.Orientation(start, end, control)) / length
+		}
+
+		if distance > tolerance {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Flatten approximates the curve with a polyline which is never further from it than
+// the tolerance, it returns the polyline vertices without the start and end points.
+// The parameters in splits are always present as vertices of the polyline
+func (b *Bezier) Flatten(tolerance float64, splits []float64) vector.Vectors {
+	params := append([]float64{0}, splits...)
+	params = append(params, 1)
+	sort.Float64s(params)
+
+	result := vector.Vectors{}
+	for i := 0; i < len(params)-1; i++ {
+		if params[i+1]-params[i] <= 0 {
+			continue
+		}
+
+		result = append(result, b.sub(params[i], params[i+1]).flatten(tolerance, 0)...)
+	}
+
+	// the last point is the end point of the curve
+	return result[0 : len(result)-1]
+}
+
+// SilhouetteParams returns the parameters of the points where the line from the
+// light touches the curve, those are the points at which the curve casts the edge
+// of its shadow. The start and end points are excluded as they are vertices anyway
+func (b *Bezier) SilhouetteParams(light *vector.Vector) []float64 {
+	f := func(t float64) float64 {
+		p := b.At(t)
+		d := b.Derivative(t)
+		return (p.X-light.X)*d.Y - (p.Y-light.Y)*d.X
+	}
+
+	result := []float64{}
+	step := 1. / silhouetteSamples
+	for i := 0; i < silhouetteSamples; i++ {
+		lo, hi := float64(i)*step, float64(i+1)*step
+		flo, fhi := f(lo), f(hi)
+
+		if flo == 0 || (flo > 0) == (fhi > 0) {
+			continue
+		}
+
+		for j := 0; j < 50; j++ {
+			mid := (lo + hi) / 2
+			if fmid := f(mid); (fmid > 0) == (flo > 0) {
+				lo, flo = mid, fmid
+			} else {
+				hi = mid
+			}
+		}
+
+		if t := (lo + hi) / 2; t > 1e-9 && t < 1-1e-9 {
+			result = append(result, t)
+		}
+	}
+
+	return result
+}
+
+// sub returns the part of the curve between parameters t0 and t1
+func (b *Bezier) sub(t0, t1 float64) *Bezier {
+	left, _ := b.Split(t1)
+	if t1 == 0 {
+		return left
+	}
+
+	_, result := left.Split(t0 / t1)
+	return result
+}
+
+// flatten recursively subdivides the curve in halves until each part is flat
+// enough and returns the end points of all parts
+func (b *Bezier) flatten(tolerance float64, depth int) vector.Vectors {
+	if depth >= flatteningMaxDepth || b.IsFlat(tolerance) {
+		end := *b.Points[len(b.Points)-1]
+		return vector.Vectors{&end}
+	}
+
+	left, right := b.Split(0.5)
+	return append(left.flatten(tolerance, depth+1), right.flatten(tolerance, depth+1)...)
+}
+
+// lerp linearly interpolates between a and b
+func lerp(a, b vector.Vector, t float64) vector.Vector {
+	return vector.Vector{X: a.X + (b.X-a.X)*t, Y: a.Y + (b.Y-a.Y)*t}
+}
@@ -0,0 +1,139 @@
+package backend_test
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+	"github.com/iliyanmotovski/raytracer/backend/vector"
+)
+
+func TestBezierSplit(t *testing.T) {
+	curve := &backend.Bezier{Points: vector.Vectors{
+		{X: 0, Y: 0},
+		{X: 100, Y: 200},
+		{X: 300, Y: 200},
+		{X: 400, Y: 0},
+	}}
+
+	left, right := curve.Split(0.3)
+
+	for _, u := range []float64{0, 0.25, 0.5, 0.75, 1} {
+		want := curve.At(0.3 * u)
+		got := left.At(u)
+		assert.InDelta(t, want.X, got.X, 1e-9)
+		assert.InDelta(t, want.Y, got.Y, 1e-9)
+
+		want = curve.At(0.3 + 0.7*u)
+		got = right.At(u)
+		assert.InDelta(t, want.X, got.X, 1e-9)
+		assert.InDelta(t, want.Y, got.Y, 1e-9)
+	}
+}
+
+func TestBezierFlattenWithinTolerance(t *testing.T) {
+	curve := &backend.Bezier{Points: vector.Vectors{
+		{X: 0, Y: 0},
+		{X: 200, Y: 300},
+		{X: 400, Y: 0},
+	}}
+
+	split := 0.37
+	for _, tolerance := range []float64{5, 0.5, 0.05} {
+		points := curve.Flatten(tolerance, []float64{split})
+		polyline := append(vector.Vectors{curve.Points[0]}, points...)
+		polyline = append(polyline, curve.Points[2])
+
+		// the split parameter is always a vertice of the polyline
+		at := curve.At(split)
+		assert.True(t, (&backend.Polygon{Loop: vector.Loop(points)}).ContainsVertice(&at))
+
+		// every point of the curve is within tolerance of the polyline
+		for i := 0; i <= 1000; i++ {
+			p := curve.At(float64(i) / 1000)
+			assert.True(t, distanceToPolyline(p, polyline) <= tolerance, "tolerance %v", tolerance)
+		}
+	}
+
+	assert.True(t, len(curve.Flatten(0.05, nil)) > len(curve.Flatten(5, nil)))
+}
+
+func TestBezierSilhouetteParams(t *testing.T) {
+	curve := &backend.Bezier{Points: vector.Vectors{
+		{X: 300, Y: 100},
+		{X: 500, Y: 250},
+		{X: 300, Y: 400},
+	}}
+
+	light := &vector.Vector{X: 100, Y: 250}
+
+	// seen from the concave side the curve is touched at its end points only
+	got := curve.SilhouetteParams(light)
+
+	assert.Len(t, got, 0)
+
+	light = &vector.Vector{X: 600, Y: 100}
+	got = curve.SilhouetteParams(light)
+
+	assert.Len(t, got, 1)
+
+	p := curve.At(got[0])
+	d := curve.Derivative(got[0])
+	cross := (p.X-light.X)*d.Y - (p.Y-light.Y)*d.X
+
+	assert.InDelta(t, 0, cross/(p.Distance(*light)*d.Length()), 1e-9)
+}
+
+func TestSceneCurvedPolygonShadowEdge(t *testing.T) {
+	curve := &backend.Polygon{
+		VerticesCount: 3,
+		Loop: vector.Loop{
+			{X: 300, Y: 100},
+			{X: 300, Y: 400},
+			{X: 250, Y: 250},
+		},
+		Controls: []vector.Vectors{{{X: 500, Y: 250}}, nil, nil},
+	}
+
+	light := &vector.Vector{X: 600, Y: 100}
+	config := &backend.Config{
+		Light:               light,
+		Scene:               &vector.Vector{X: 800, Y: 500},
+		Polygons:            backend.Polygons{curve},
+		FlatteningTolerance: 2,
+	}
+
+	triangles, _, err := backend.NewScene(config).Process(context.Background())
+	assert.Nil(t, err)
+
+	side, _ := curve.Curve(0)
+	silhouette := side.At(side.SilhouetteParams(light)[0])
+
+	// some triangle of the fan ends exactly at the silhouette point
+	// even though the flattening tolerance is coarse
+	closest := math.Inf(1)
+	for _, triangle := range triangles {
+		for _, vertice := range triangle.Loop[1:] {
+			closest = math.Min(closest, vertice.Distance(silhouette))
+		}
+	}
+
+	assert.True(t, closest < 0.01)
+}
+
+func distanceToPolyline(p vector.Vector, polyline vector.Vectors) float64 {
+	result := math.Inf(1)
+
+	for i := 0; i < len(polyline)-1; i++ {
+		a, b := *polyline[i], *polyline[i+1]
+		ab := b.Sub(a)
+		t := math.Max(0, math.Min(1, p.Sub(a).Dot(ab)/ab.Dot(ab)))
+		closest := vector.Vector{X: a.X + ab.X*t, Y: a.Y + ab.Y*t}
+		result = math.Min(result, p.Distance(closest))
+	}
+
+	return result
+}
@@ -3,25 +3,40 @@ package backend
 import (
 	"bufio"
 	"context"
-	"log"
+	"errors"
+	"fmt"
 	"os"
 	"strconv"
 	"strings"
 
+	"github.com/iliyanmotovski/raytracer/backend/logging"
+	"github.com/iliyanmotovski/raytracer/backend/tracing"
 	"github.com/iliyanmotovski/raytracer/backend/vector"
 )
 
-// ConfigRepository is an abstraction over some repository
+// ConfigRepository is an abstraction over some repository which keeps the configs
+// by the ID of their scene, every Upsert creates a new version of the config with
+// the ID of the given one and Get returns the latest version or nil if there is none
 type ConfigRepository interface {
-	Get(context.Context) (*Config, error)
+	Get(ctx context.Context, sceneID string) (*Config, error)
 	Upsert(context.Context, *Config) (*Config, error)
+	ListVersions(ctx context.Context, sceneID string) (Versions, error)
+	GetVersion(ctx context.Context, sceneID string, version int) (*Config, error)
 }
 
 // Config represents the scene configuration
 type Config struct {
+	// ID is the ID of the scene the config is for
+	ID       string
 	Light    *vector.Vector
 	Scene    *vector.Vector
 	Polygons Polygons
+	Walls    Walls
+	Ellipses Ellipses
+	// FlatteningTolerance is the maximum distance between curved polygon
+	// sides and their approximation used for ray casting, when it is not
+	// set DefaultFlatteningTolerance is used
+	FlatteningTolerance float64
 }
 
 // Configurator is an abstraction over some configurator - txt file, yaml etc...
@@ -39,11 +54,20 @@ func NewTextFileConfigurator(path string) Configurator {
 	return &textFileConfigurator{path: path}
 }
 
-// Parse reads the txt file and populates the Config
-func (t *textFileConfigurator) Parse(ctx context.Context, configRepo ConfigRepository) (*Config, error) {
+// Parse reads the txt file and populates the Config, a malformed
+// file which the parser can not handle returns an error too
+func (t *textFileConfigurator) Parse(ctx context.Context, configRepo ConfigRepository) (config *Config, err error) {
+	ctx, span := tracing.Start(ctx, "config.parse", "path", t.path)
+	// deferred first, so it sees the error of a recovered panic
 	defer func() {
-		if err := recover(); err != nil {
-			log.Println(err)
+		span.SetError(err)
+		span.End()
+	}()
+
+	defer func() {
+		if r := recover(); r != nil {
+			logging.FromContext(ctx).Debug("config parser panicked", "path", t.path, "panic", r)
+			config, err = &Config{}, fmt.Errorf("malformed config file %s: %v", t.path, r)
 		}
 	}()
 
@@ -62,9 +86,10 @@ func (t *textFileConfigurator) Parse(ctx context.Context, configRepo ConfigRepos
 		fileTextLines = append(fileTextLines, scanner.Text())
 	}
 
-	c := &Config{Light: &vector.Vector{}, Scene: &vector.Vector{}}
+	// the config file always describes the default scene
+	c := &Config{ID: DefaultSceneID, Light: &vector.Vector{}, Scene: &vector.Vector{}}
 
-	j := 0
+	j, k, g := 0, 0, 0
 	polyCount := 0
 	for i, line := range fileTextLines {
 		if i == 0 || i == 1 {
@@ -76,6 +101,10 @@ func (t *textFileConfigurator) Parse(ctx context.Context, configRepo ConfigRepos
 			case 0:
 				c.Scene.X = x
 				c.Scene.Y = y
+				c.FlatteningTolerance, err = parseTolerance(line)
+				if err != nil {
+					return &Config{}, err
+				}
 			case 1:
 				c.Light.X = x
 				c.Light.Y = y
@@ -92,39 +121,67 @@ func (t *textFileConfigurator) Parse(ctx context.Context, configRepo ConfigRepos
 			continue
 		}
 
-		polyCoords := strings.Split(line, " ")
-
-		verteciesCount, err := strconv.Atoi(polyCoords[0])
-		if err != nil {
-			return &Config{}, err
+		// the optional walls section follows the polygons and
+		// starts with the walls count on a line of its own
+		if i == polyCount+3 {
+			wallCount, err := strconv.Atoi(line)
+			if err != nil {
+				return &Config{}, err
+			}
+			c.Walls = make(Walls, wallCount)
+			continue
 		}
 
-		polyCoords = polyCoords[1:len(polyCoords)]
-
-		c.Polygons[j] = &Polygon{VerticesCount: verteciesCount}
-		c.Polygons[j].Loop = make(vector.Loop, verteciesCount)
-
-		k := 0
-		for g := range c.Polygons[j].Loop {
-			x, err := strconv.ParseFloat(polyCoords[k], 64)
+		// the optional ellipses section follows the walls and
+		// starts with the ellipses count on a line of its own
+		if i == polyCount+len(c.Walls)+4 {
+			ellipseCount, err := strconv.Atoi(line)
 			if err != nil {
 				return &Config{}, err
 			}
-			y, err := strconv.ParseFloat(polyCoords[k+1], 64)
+			c.Ellipses = make(Ellipses, ellipseCount)
+			continue
+		}
+
+		if i > polyCount+len(c.Walls)+4 {
+			c.Ellipses[g], err = parseEllipse(line)
 			if err != nil {
 				return &Config{}, err
 			}
+			g++
+			continue
+		}
 
-			c.Polygons[j].Loop[g] = &vector.Vector{X: x, Y: y}
-			k += 2
+		verteciesCount, vertices, controls, err := parseVertices(line)
+		if err != nil {
+			return &Config{}, err
+		}
+
+		if j < polyCount {
+			c.Polygons[j] = &Polygon{VerticesCount: verteciesCount, Loop: vector.Loop(vertices), Controls: controls}
+			j++
+			continue
 		}
-		j++
+
+		if controls != nil {
+			return &Config{}, errors.New("wall sides can not be curved")
+		}
+
+		c.Walls[k] = &Wall{VerticesCount: verteciesCount, Vertices: vertices}
+		k++
 	}
 
 	if err := scanner.Err(); err != nil {
 		return &Config{}, err
 	}
 
+	// a truncated file, e.g. one which is still being saved, lacks some of the counted lines
+	if j < len(c.Polygons) || k < len(c.Walls) || g < len(c.Ellipses) {
+		return &Config{}, fmt.Errorf("config file %s has fewer polygons, walls or ellipses than counted", t.path)
+	}
+
+	span.SetAttributes("polygons", len(c.Polygons), "walls", len(c.Walls), "ellipses", len(c.Ellipses))
+
 	persisted, err := configRepo.Upsert(ctx, c)
 	if err != nil {
 		return &Config{}, err
@@ -133,6 +190,102 @@ func (t *textFileConfigurator) Parse(ctx context.Context, configRepo ConfigRepos
 	return persisted, nil
 }
 
+// parses a polygon or wall line - the vertices count followed by the vertices coordinates,
+// each vertice can be followed by "q X Y" or "c X1 Y1 X2 Y2" which makes the side starting
+// at it a quadratic or cubic Bezier curve with the given control points
+func parseVertices(line string) (int, vector.Vectors, []vector.Vectors, error) {
+	coords := strings.Split(line, " ")
+
+	verteciesCount, err := strconv.Atoi(coords[0])
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	coords = coords[1:len(coords)]
+	vertices := make(vector.Vectors, verteciesCount)
+	var controls []vector.Vectors
+
+	k := 0
+	for g := range vertices {
+		x, err := strconv.ParseFloat(coords[k], 64)
+		if err != nil {
+			return 0, nil, nil, err
+		}
+		y, err := strconv.ParseFloat(coords[k+1], 64)
+		if err != nil {
+			return 0, nil, nil, err
+		}
+
+		vertices[g] = &vector.Vector{X: x, Y: y}
+		k += 2
+
+		if k >= len(coords) || (coords[k] != "q" && coords[k] != "c") {
+			continue
+		}
+
+		controlsCount := 1
+		if coords[k] == "c" {
+			controlsCount = 2
+		}
+		k++
+
+		if controls == nil {
+			controls = make([]vector.Vectors, verteciesCount)
+		}
+
+		for h := 0; h < controlsCount; h++ {
+			cx, err := strconv.ParseFloat(coords[k], 64)
+			if err != nil {
+				return 0, nil, nil, err
+			}
+			cy, err := strconv.ParseFloat(coords[k+1], 64)
+			if err != nil {
+				return 0, nil, nil, err
+			}
+
+			controls[g] = append(controls[g], &vector.Vector{X: cx, Y: cy})
+			k += 2
+		}
+	}
+
+	return verteciesCount, vertices, controls, nil
+}
+
+// parses an ellipse line - center X Y followed by a single radius
+// for a circle or by the X and Y radiuses for an ellipse
+func parseEllipse(line string) (*Ellipse, error) {
+	coords := strings.Split(line, " ")
+	values := make([]float64, len(coords))
+
+	for i, coord := range coords {
+		value, err := strconv.ParseFloat(coord, 64)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+
+	switch len(values) {
+	case 3:
+		return NewCircle(values[0], values[1], values[2]), nil
+	case 4:
+		return &Ellipse{Center: &vector.Vector{X: values[0], Y: values[1]}, RadiusX: values[2], RadiusY: values[3]}, nil
+	default:
+		return nil, fmt.Errorf("ellipse line %q must contain 3 or 4 values", line)
+	}
+}
+
+// parses the optional flattening tolerance which follows the
+// scene width and height on the first line, 0 when there is none
+func parseTolerance(line string) (float64, error) {
+	values := strings.Split(line, " ")
+	if len(values) < 3 {
+		return 0, nil
+	}
+
+	return strconv.ParseFloat(values[2], 64)
+}
+
 // parses the first and second lines of the config as they are identical
 func parseFirstAndSecond(line string) (float64, float64, error) {
 	x, y, err := 0.0, 0.0, error(nil)
@@ -19,18 +19,55 @@ const data = "800 500\n" +
 	"6 131 188 54 136 86 32 220 32 238 114 209 163\n" +
 	"5 412 364 454 251 537 257 601 350 528 430"
 
+const dataWithWalls = "800 500\n" +
+	"250 300\n" +
+	"1\n" +
+	"3 600 200 646 133 646 261\n" +
+	"2\n" +
+	"2 100 100 300 100\n" +
+	"3 400 400 500 400 500 450"
+
+const dataWithEllipses = "800 500\n" +
+	"250 300\n" +
+	"0\n" +
+	"0\n" +
+	"2\n" +
+	"400 400 50\n" +
+	"600 100 80 40"
+
+const dataWithCurves = "800 500\n" +
+	"250 300\n" +
+	"1\n" +
+	"3 600 200 q 620 150 646 133 646 261 c 640 240 610 220"
+
 func TestMain(m *testing.M) {
 	f, _ := os.Create("test.txt")
 	f.WriteString(data)
 	f.Close()
 
+	f, _ = os.Create("test_walls.txt")
+	f.WriteString(dataWithWalls)
+	f.Close()
+
+	f, _ = os.Create("test_curves.txt")
+	f.WriteString(dataWithCurves)
+	f.Close()
+
+	f, _ = os.Create("test_ellipses.txt")
+	f.WriteString(dataWithEllipses)
+	f.Close()
+
 	m.Run()
 
 	os.Remove("test.txt")
+	os.Remove("test_walls.txt")
+	os.Remove("test_ellipses.txt")
+	os.Remove("test_curves.txt")
 }
 
 func TestParseConfigFromTextFile(t *testing.T) {
 	config := &backend.Config{
+		ID:    backend.DefaultSceneID,
 		Light: &vector.Vector{X: 250, Y: 300},
 		Scene: &vector.Vector{X: 800, Y: 500},
 		Polygons: backend.Polygons{
@@ -78,6 +115,147 @@ func TestParseConfigFromTextFile(t *testing.T) {
 	configRepo.AssertExpectations(t)
 }
 
+func TestParseConfigWithWallsFromTextFile(t *testing.T) {
+	config := &backend.Config{
+		ID:    backend.DefaultSceneID,
+		Light: &vector.Vector{X: 250, Y: 300},
+		Scene: &vector.Vector{X: 800, Y: 500},
+		Polygons: backend.Polygons{
+			{
+				VerticesCount: 3,
+				Loop: vector.Loop{
+					{X: 600, Y: 200},
+					{X: 646, Y: 133},
+					{X: 646, Y: 261},
+				},
+			},
+		},
+		Walls: backend.Walls{
+			{
+				VerticesCount: 2,
+				Vertices: vector.Vectors{
+					{X: 100, Y: 100},
+					{X: 300, Y: 100},
+				},
+			},
+			{
+				VerticesCount: 3,
+				Vertices: vector.Vectors{
+					{X: 400, Y: 400},
+					{X: 500, Y: 400},
+					{X: 500, Y: 450},
+				},
+			},
+		},
+	}
+
+	configRepo := new(backend.FakeConfigRepository)
+	configRepo.On("Upsert", config).Return(config, nil)
+
+	c := backend.NewTextFileConfigurator("test_walls.txt")
+	got, err := c.Parse(context.Background(), configRepo)
+
+	assert.Nil(t, err)
+	assert.Equal(t, config, got)
+
+	configRepo.AssertExpectations(t)
+}
+
+func TestParseConfigWithEllipsesFromTextFile(t *testing.T) {
+	config := &backend.Config{
+		ID:       backend.DefaultSceneID,
+		Light:    &vector.Vector{X: 250, Y: 300},
+		Scene:    &vector.Vector{X: 800, Y: 500},
+		Polygons: backend.Polygons{},
+		Walls:    backend.Walls{},
+		Ellipses: backend.Ellipses{
+			backend.NewCircle(400, 400, 50),
+			{Center: &vector.Vector{X: 600, Y: 100}, RadiusX: 80, RadiusY: 40},
+		},
+	}
+
+	configRepo := new(backend.FakeConfigRepository)
+	configRepo.On("Upsert", config).Return(config, nil)
+
+	c := backend.NewTextFileConfigurator("test_ellipses.txt")
+	got, err := c.Parse(context.Background(), configRepo)
+
+	assert.Nil(t, err)
+	assert.Equal(t, config, got)
+
+	configRepo.AssertExpectations(t)
+}
+
+func TestParseConfigWithCurvesFromTextFile(t *testing.T) {
+	config := &backend.Config{
+		ID:    backend.DefaultSceneID,
+		Light: &vector.Vector{X: 250, Y: 300},
+		Scene: &vector.Vector{X: 800, Y: 500},
+		Polygons: backend.Polygons{
+			{
+				VerticesCount: 3,
+				Loop: vector.Loop{
+					{X: 600, Y: 200},
+					{X: 646, Y: 133},
+					{X: 646, Y: 261},
+				},
+				Controls: []vector.Vectors{
+					{{X: 620, Y: 150}},
+					nil,
+					{{X: 640, Y: 240}, {X: 610, Y: 220}},
+				},
+			},
+		},
+	}
+
+	configRepo := new(backend.FakeConfigRepository)
+	configRepo.On("Upsert", config).Return(config, nil)
+
+	c := backend.NewTextFileConfigurator("test_curves.txt")
+	got, err := c.Parse(context.Background(), configRepo)
+
+	assert.Nil(t, err)
+	assert.Equal(t, config, got)
+
+	configRepo.AssertExpectations(t)
+}
+
+func TestParseConfigWithFlatteningToleranceFromTextFile(t *testing.T) {
+	f, _ := os.Create("test_tolerance.txt")
+	f.WriteString("800 500 0.1\n250 300\n1\n3 600 200 q 620 150 646 133 646 261")
+	f.Close()
+	defer os.Remove("test_tolerance.txt")
+
+	config := &backend.Config{
+		ID:    backend.DefaultSceneID,
+		Light: &vector.Vector{X: 250, Y: 300},
+		Scene: &vector.Vector{X: 800, Y: 500},
+		Polygons: backend.Polygons{
+			{
+				VerticesCount: 3,
+				Loop: vector.Loop{
+					{X: 600, Y: 200},
+					{X: 646, Y: 133},
+					{X: 646, Y: 261},
+				},
+				Controls: []vector.Vectors{{{X: 620, Y: 150}}, nil, nil},
+			},
+		},
+		FlatteningTolerance: 0.1,
+	}
+
+	configRepo := new(backend.FakeConfigRepository)
+	configRepo.On("Upsert", config).Return(config, nil)
+
+	c := backend.NewTextFileConfigurator("test_tolerance.txt")
+	got, err := c.Parse(context.Background(), configRepo)
+
+	assert.Nil(t, err)
+	assert.Equal(t, config, got)
+
+	configRepo.AssertExpectations(t)
+}
+
 func TestParseConfigFromNonexistentFile(t *testing.T) {
 	c := backend.NewTextFileConfigurator("non-existent.txt")
 	_, err := c.Parse(context.Background(), nil)
@@ -91,8 +269,35 @@ func TestParseConfigFromNonexistentFile(t *testing.T) {
 	assert.Equal(t, want, err)
 }
 
+func TestParseConfigFromMalformedTextFile(t *testing.T) {
+	f, _ := os.Create("test_malformed.txt")
+	f.WriteString("800 500\n250 300\n1\n3 600 200")
+	f.Close()
+	defer os.Remove("test_malformed.txt")
+
+	c := backend.NewTextFileConfigurator("test_malformed.txt")
+	config, err := c.Parse(context.Background(), new(backend.FakeConfigRepository))
+
+	assert.NotNil(t, err)
+	assert.Equal(t, &backend.Config{}, config)
+}
+
+func TestParseConfigFromTruncatedTextFile(t *testing.T) {
+	f, _ := os.Create("test_truncated.txt")
+	f.WriteString("800 500\n250 300\n2\n3 600 200 646 133 646 261")
+	f.Close()
+	defer os.Remove("test_truncated.txt")
+
+	c := backend.NewTextFileConfigurator("test_truncated.txt")
+	config, err := c.Parse(context.Background(), new(backend.FakeConfigRepository))
+
+	assert.NotNil(t, err)
+	assert.Equal(t, &backend.Config{}, config)
+}
+
 func TestParseConfigFromTextFileWithRepositoryFailure(t *testing.T) {
 	config := &backend.Config{
+		ID:    backend.DefaultSceneID,
 		Light: &vector.Vector{X: 250, Y: 300},
 		Scene: &vector.Vector{X: 800, Y: 500},
 		Polygons: backend.Polygons{
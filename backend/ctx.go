@@ -1,7 +1,16 @@
 package backend
 
-const (
-	Initial             = "initial"
-	HotReload           = "hotreload"
-	CreateConfigHandler = "createconfighandler"
-)
+import "context"
+
+// ContextError returns ErrContextCancelled if the context has been canceled,
+// ErrContextExpired if its deadline has expired and nil otherwise
+func ContextError(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return ErrContextCancelled
+	case context.DeadlineExceeded:
+		return ErrContextExpired
+	default:
+		return nil
+	}
+}
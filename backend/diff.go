@@ -0,0 +1,130 @@
+package backend
+
+import (
+	"math"
+
+	"github.com/iliyanmotovski/raytracer/backend/vector"
+)
+
+// SceneDiff represents the changes between two scenes
+type SceneDiff struct {
+	Added, Removed     Polygons
+	Moved              PolygonMoves
+	LightFrom, LightTo *vector.Vector
+	LitAreaFrom        float64
+	LitAreaTo          float64
+}
+
+// PolygonMove represents a polygon which kept its shape but was translated
+type PolygonMove struct {
+	From, To *Polygon
+	Offset   *vector.Vector
+}
+
+type PolygonMoves []*PolygonMove
+
+// LightOffset returns how much the light has moved
+func (d *SceneDiff) LightOffset() vector.Vector {
+	return d.LightTo.Sub(*d.LightFrom)
+}
+
+// LitAreaChange returns the change of the lit area in percents
+func (d *SceneDiff) LitAreaChange() float64 {
+	return math.Round((d.LitAreaTo-d.LitAreaFrom)*100) / 100
+}
+
+// Diff compares the scene with another one. Polygons present in both scenes
+// are unchanged, polygons with the same shape at a different position are
+// moved and all other polygons are either added or removed
+func (s *Scene) Diff(to *Scene) *SceneDiff {
+	result := &SceneDiff{
+		Added:       Polygons{},
+		Removed:     Polygons{},
+		Moved:       PolygonMoves{},
+		LightFrom:   s.Light,
+		LightTo:     to.Light,
+		LitAreaFrom: s.LitArea,
+		LitAreaTo:   to.LitArea,
+	}
+
+	removed := append(Polygons{}, s.Polygons...)
+	added := Polygons{}
+
+	// unchanged polygons are matched first, so they are never taken as moved
+	for _, polygon := range to.Polygons {
+		if i := removed.indexOf(polygon, func(a, b *Polygon) bool { return a.Equals(b) }); i != -1 {
+			removed = append(removed[:i], removed[i+1:]...)
+			continue
+		}
+
+		added = append(added, polygon)
+	}
+
+	for _, polygon := range added {
+		i := removed.indexOf(polygon, func(a, b *Polygon) bool { _, ok := a.TranslationTo(b); return ok })
+		if i == -1 {
+			result.Added = append(result.Added, polygon)
+			continue
+		}
+
+		offset, _ := removed[i].TranslationTo(polygon)
+		result.Moved = append(result.Moved, &PolygonMove{From: removed[i], To: polygon, Offset: offset})
+		removed = append(removed[:i], removed[i+1:]...)
+	}
+
+	result.Removed = append(result.Removed, removed...)
+	return result
+}
+
+// Equals returns whether both polygons have the same vertices and sides
+func (p *Polygon) Equals(other *Polygon) bool {
+	offset, ok := p.TranslationTo(other)
+	return ok && offset.X == 0 && offset.Y == 0
+}
+
+// TranslationTo returns the offset which moves the polygon onto the other one
+// and false if the other polygon has a different shape
+func (p *Polygon) TranslationTo(other *Polygon) (*vector.Vector, bool) {
+	if len(p.Loop) != len(other.Loop) || len(p.Loop) == 0 || p.IsCurved() != other.IsCurved() {
+		return nil, false
+	}
+
+	offset := other.Loop[0].Sub(*p.Loop[0])
+
+	for i, vertice := range p.Loop {
+		if !sameOffset(vertice, other.Loop[i], offset) {
+			return nil, false
+		}
+
+		if i < len(p.Controls) && i < len(other.Controls) {
+			if len(p.Controls[i]) != len(other.Controls[i]) {
+				return nil, false
+			}
+
+			for j, control := range p.Controls[i] {
+				if !sameOffset(control, other.Controls[i][j], offset) {
+					return nil, false
+				}
+			}
+		}
+	}
+
+	return &offset, true
+}
+
+// indexOf returns the index of the first polygon which matches p or -1
+func (ps Polygons) indexOf(p *Polygon, match func(a, b *Polygon) bool) int {
+	for i, polygon := range ps {
+		if match(polygon, p) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// sameOffset returns whether b equals a translated by the offset
+func sameOffset(a, b *vector.Vector, offset vector.Vector) bool {
+	const epsilon = 1e-9
+	return math.Abs(b.X-a.X-offset.X) < epsilon && math.Abs(b.Y-a.Y-offset.Y) < epsilon
+}
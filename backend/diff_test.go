@@ -0,0 +1,91 @@
+package backend_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+	"github.com/iliyanmotovski/raytracer/backend/vector"
+)
+
+func TestSceneDiff(t *testing.T) {
+	unchanged := &backend.Polygon{VerticesCount: 3, Loop: vector.Loop{{X: 100, Y: 100}, {X: 150, Y: 100}, {X: 150, Y: 150}}}
+	moved := &backend.Polygon{VerticesCount: 3, Loop: vector.Loop{{X: 600, Y: 200}, {X: 646, Y: 133}, {X: 646, Y: 261}}}
+	movedTo := &backend.Polygon{VerticesCount: 3, Loop: vector.Loop{{X: 610, Y: 180}, {X: 656, Y: 113}, {X: 656, Y: 241}}}
+	removed := &backend.Polygon{VerticesCount: 4, Loop: vector.Loop{{X: 300, Y: 300}, {X: 350, Y: 300}, {X: 350, Y: 350}, {X: 300, Y: 350}}}
+	added := &backend.Polygon{VerticesCount: 3, Loop: vector.Loop{{X: 400, Y: 50}, {X: 450, Y: 50}, {X: 400, Y: 90}}}
+
+	from := &backend.Scene{
+		LitArea:  60.123,
+		Light:    &vector.Vector{X: 250, Y: 300},
+		Polygons: backend.Polygons{unchanged, moved, removed},
+	}
+
+	to := &backend.Scene{
+		LitArea:  55.1,
+		Light:    &vector.Vector{X: 200, Y: 320},
+		Polygons: backend.Polygons{movedTo, added, unchanged},
+	}
+
+	got := from.Diff(to)
+
+	assert.Equal(t, backend.Polygons{added}, got.Added)
+	assert.Equal(t, backend.Polygons{removed}, got.Removed)
+	assert.Equal(t, backend.PolygonMoves{{From: moved, To: movedTo, Offset: &vector.Vector{X: 10, Y: -20}}}, got.Moved)
+	assert.Equal(t, vector.Vector{X: -50, Y: 20}, got.LightOffset())
+	assert.Equal(t, -5.02, got.LitAreaChange())
+
+	// the scenes must be left as they were
+	assert.Equal(t, backend.Polygons{unchanged, moved, removed}, from.Polygons)
+	assert.Equal(t, backend.Polygons{movedTo, added, unchanged}, to.Polygons)
+}
+
+func TestSceneDiffWithoutChanges(t *testing.T) {
+	scene := &backend.Scene{
+		LitArea: 60,
+		Light:   &vector.Vector{X: 250, Y: 300},
+		Polygons: backend.Polygons{
+			{VerticesCount: 3, Loop: vector.Loop{{X: 600, Y: 200}, {X: 646, Y: 133}, {X: 646, Y: 261}}},
+			{VerticesCount: 3, Loop: vector.Loop{{X: 600, Y: 200}, {X: 646, Y: 133}, {X: 646, Y: 261}}},
+		},
+	}
+
+	got := scene.Diff(scene)
+
+	assert.Empty(t, got.Added)
+	assert.Empty(t, got.Removed)
+	assert.Empty(t, got.Moved)
+	assert.Equal(t, vector.Vector{}, got.LightOffset())
+	assert.Equal(t, float64(0), got.LitAreaChange())
+}
+
+func TestPolygonTranslationTo(t *testing.T) {
+	polygon := &backend.Polygon{
+		VerticesCount: 3,
+		Loop:          vector.Loop{{X: 100, Y: 100}, {X: 200, Y: 100}, {X: 200, Y: 200}},
+		Controls:      []vector.Vectors{{{X: 150, Y: 50}}, nil, nil},
+	}
+
+	translated := &backend.Polygon{
+		VerticesCount: 3,
+		Loop:          vector.Loop{{X: 110, Y: 90}, {X: 210, Y: 90}, {X: 210, Y: 190}},
+		Controls:      []vector.Vectors{{{X: 160, Y: 40}}, nil, nil},
+	}
+
+	reshaped := &backend.Polygon{
+		VerticesCount: 3,
+		Loop:          vector.Loop{{X: 110, Y: 90}, {X: 210, Y: 90}, {X: 210, Y: 190}},
+		Controls:      []vector.Vectors{{{X: 160, Y: 60}}, nil, nil},
+	}
+
+	offset, ok := polygon.TranslationTo(translated)
+	assert.True(t, ok)
+	assert.Equal(t, &vector.Vector{X: 10, Y: -10}, offset)
+
+	_, ok = polygon.TranslationTo(reshaped)
+	assert.False(t, ok)
+
+	assert.True(t, polygon.Equals(polygon))
+	assert.False(t, polygon.Equals(translated))
+}
@@ -0,0 +1,201 @@
+package backend
+
+import (
+	"context"
+	"math"
+
+	"github.com/iliyanmotovski/raytracer/backend/vector"
+)
+
+// Ellipse represents a round, axis aligned obstacle defined by its center
+// and radiuses, a circle is an Ellipse with equal radiuses. Rays are cast
+// against it analytically, it is never broken down into boundaries
+type Ellipse struct {
+	Center           *vector.Vector
+	RadiusX, RadiusY float64
+}
+
+// NewCircle creates an Ellipse with equal radiuses
+func NewCircle(x, y, radius float64) *Ellipse {
+	return &Ellipse{Center: &vector.Vector{X: x, Y: y}, RadiusX: radius, RadiusY: radius}
+}
+
+// IsPointContainedInEllipse returns whether a given point is contained
+// strictly inside the Ellipse
+func (e *Ellipse) IsPointContainedInEllipse(p *vector.Vector) bool {
+	u := e.toUnit(p)
+	return u.Dot(u) < 1
+}
+
+// Intersection returns the closest point where a ray starting at origin with the
+// given normalized direction hits the Ellipse, and whether such point exists
+func (e *Ellipse) Intersection(origin, dir *vector.Vector) (*vector.Vector, bool) {
+	t, ok := e.intersect(origin, dir)
+	if !ok || t <= 0 {
+		return nil, false
+	}
+
+	return &vector.Vector{X: origin.X + t*dir.X, Y: origin.Y + t*dir.Y}, true
+}
+
+// Crosses returns whether the edge, treated as a finite segment,
+// enters the interior of the Ellipse
+func (e *Ellipse) Crosses(edge *vector.Edge) bool {
+	if e.IsPointContainedInEllipse(edge.A) || e.IsPointContainedInEllipse(edge.B) {
+		return true
+	}
+
+	dir := edge.B.Sub(*edge.A)
+	length := dir.Length()
+	if length == 0 {
+		return false
+	}
+
+	dir = dir.Normalize()
+	t, ok := e.intersect(edge.A, &dir)
+
+	return ok && t > 0 && t < length
+}
+
+// TangentPoints returns the 2 points on the Ellipse where the lines passing
+// through p touch it, and false if p is not outside of the Ellipse
+func (e *Ellipse) TangentPoints(p *vector.Vector) (vector.Vectors, bool) {
+	u := e.toUnit(p)
+	d := u.Length()
+	if d <= 1 {
+		return nil, false
+	}
+
+	// tangency is preserved by the affine transformation to the unit circle
+	// where the tangent points are at +/- acos(1/d) from the direction of p
+	angle := math.Atan2(u.Y, u.X)
+	offset := math.Acos(1 / d)
+
+	return vector.Vectors{e.PointAt(angle - offset), e.PointAt(angle + offset)}, true
+}
+
+// PointAt returns the point on the Ellipse at the given parametric angle
+func (e *Ellipse) PointAt(angle float64) *vector.Vector {
+	return &vector.Vector{
+		X: e.Center.X + e.RadiusX*math.Cos(angle),
+		Y: e.Center.Y + e.RadiusY*math.Sin(angle),
+	}
+}
+
+// AngleOf returns the parametric angle of a point lying on the Ellipse
+func (e *Ellipse) AngleOf(p *vector.Vector) float64 {
+	u := e.toUnit(p)
+	return math.Atan2(u.Y, u.X)
+}
+
+// Polygonize approximates the Ellipse with a polygon with the given number of
+// vertices, it must be used for rendering only, never for ray casting
+func (e *Ellipse) Polygonize(segments int) *Polygon {
+	result := &Polygon{Loop: make(vector.Loop, segments), VerticesCount: segments}
+
+	for i := range result.Loop {
+		result.Loop[i] = e.PointAt(2 * math.Pi * float64(i) / float64(segments))
+	}
+
+	return result
+}
+
+// toUnit transforms a point to the space where the Ellipse is the unit circle
+func (e *Ellipse) toUnit(p *vector.Vector) vector.Vector {
+	return vector.Vector{X: (p.X - e.Center.X) / e.RadiusX, Y: (p.Y - e.Center.Y) / e.RadiusY}
+}
+
+// intersect solves the ray - Ellipse equation in the unit circle space and returns the
+// smallest non negative distance along the direction, the distance is invariant to the
+// transformation as both the origin and the direction are scaled the same way
+func (e *Ellipse) intersect(origin, dir *vector.Vector) (float64, bool) {
+	o := e.toUnit(origin)
+	d := vector.Vector{X: dir.X / e.RadiusX, Y: dir.Y / e.RadiusY}
+
+	a := d.Dot(d)
+	b := 2 * o.Dot(d)
+	c := o.Dot(o) - 1
+
+	disc := b*b - 4*a*c
+	if a == 0 || disc < 0 {
+		return 0, false
+	}
+
+	sqrt := math.Sqrt(disc)
+	t := (-b - sqrt) / (2 * a)
+	if t < 0 {
+		t = (-b + sqrt) / (2 * a)
+	}
+
+	return t, t >= 0
+}
+
+type Ellipses []*Ellipse
+
+// Validate checks all ellipses and returns whether some is not entirely
+// inside the scene, overlaps another ellipse or is crossed by a polygon
+// or a wall. Overlapping between ellipses is checked with an approximation
+func (es Ellipses) Validate(ctx context.Context, width, height float64, polygons Polygons, walls Walls) error {
+	for i, ellipse := range es {
+		if err := ContextError(ctx); err != nil {
+			return err
+		}
+
+		c := ellipse.Center
+
+		if ellipse.RadiusX <= 0 || ellipse.RadiusY <= 0 {
+			return invalid("ellipse_radius", "ellipse radius must be positive")
+		}
+
+		if c.X-ellipse.RadiusX < 0 || c.X+ellipse.RadiusX > width || c.Y-ellipse.RadiusY < 0 || c.Y+ellipse.RadiusY > height {
+			return invalid("ellipse_outside_scene", "ellipse X: %v , Y: %v is outside the scene", c.X, c.Y)
+		}
+
+		for _, polygon := range polygons {
+			if polygon.IsPointContainedInPolygon(c) {
+				return invalid("ellipse_inside_polygon", "ellipse X: %v , Y: %v is inside a polygon", c.X, c.Y)
+			}
+
+			for _, side := range polygon.GetBoundaries() {
+				if ellipse.Crosses(&side.Edge) {
+					return invalid("ellipse_overlaps_polygon", "ellipse X: %v , Y: %v overlaps a polygon", c.X, c.Y)
+				}
+			}
+		}
+
+		for _, wall := range walls {
+			for _, segment := range wall.GetBoundaries() {
+				if ellipse.Crosses(&segment.Edge) {
+					return invalid("ellipse_crossed_by_wall", "ellipse X: %v , Y: %v is crossed by a wall", c.X, c.Y)
+				}
+			}
+		}
+
+		for _, other := range es[i+1:] {
+			if other.IsPointContainedInEllipse(c) || ellipse.IsPointContainedInEllipse(other.Center) {
+				return invalid("ellipse_overlaps_ellipse", "ellipse X: %v , Y: %v overlaps another ellipse", c.X, c.Y)
+			}
+
+			for _, side := range other.Polygonize(64).GetBoundaries() {
+				if ellipse.Crosses(&side.Edge) {
+					return invalid("ellipse_overlaps_ellipse", "ellipse X: %v , Y: %v overlaps another ellipse", c.X, c.Y)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Arc marks that the far side of a Triangle is not a straight line
+// but an arc of the Ellipse between the parametric angles From and To
+type Arc struct {
+	Ellipse  *Ellipse
+	From, To float64
+}
+
+// SegmentArea returns the area enclosed between the arc and its chord
+func (a *Arc) SegmentArea() float64 {
+	delta := math.Abs(math.Remainder(a.To-a.From, 2*math.Pi))
+	return a.Ellipse.RadiusX * a.Ellipse.RadiusY * (delta - math.Sin(delta)) / 2
+}
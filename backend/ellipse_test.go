@@ -0,0 +1,145 @@
+package backend_test
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+	"github.com/iliyanmotovski/raytracer/backend/vector"
+)
+
+func TestCastRayAgainstEllipse(t *testing.T) {
+	ray := backend.NewRay(&vector.Vector{X: 400, Y: 100})
+	ray.SetDir(400, 400)
+
+	circle := backend.NewCircle(400, 300, 50)
+
+	got, ok := ray.CastEllipse(circle)
+
+	assert.True(t, ok)
+	assert.Equal(t, &vector.Vector{X: 400, Y: 250}, got)
+
+	ellipse := &backend.Ellipse{Center: &vector.Vector{X: 400, Y: 300}, RadiusX: 100, RadiusY: 20}
+
+	got, ok = ray.CastEllipse(ellipse)
+
+	assert.True(t, ok)
+	assert.InDelta(t, 400, got.X, 1e-9)
+	assert.InDelta(t, 280, got.Y, 1e-9)
+}
+
+func TestCastRayAgainstEllipseNotIntersecting(t *testing.T) {
+	ray := backend.NewRay(&vector.Vector{X: 400, Y: 100})
+	ray.SetDir(400, 0)
+
+	got, ok := ray.CastEllipse(backend.NewCircle(400, 300, 50))
+
+	assert.False(t, ok)
+	assert.Equal(t, (*vector.Vector)(nil), got)
+}
+
+func TestEllipseTangentPoints(t *testing.T) {
+	circle := backend.NewCircle(0, 0, 1)
+
+	got, ok := circle.TangentPoints(&vector.Vector{X: 2, Y: 0})
+
+	assert.True(t, ok)
+	assert.InDelta(t, 0.5, got[0].X, 1e-9)
+	assert.InDelta(t, -math.Sqrt(3)/2, got[0].Y, 1e-9)
+	assert.InDelta(t, 0.5, got[1].X, 1e-9)
+	assert.InDelta(t, math.Sqrt(3)/2, got[1].Y, 1e-9)
+
+	_, ok = circle.TangentPoints(&vector.Vector{X: 0.5, Y: 0})
+	assert.False(t, ok)
+}
+
+func TestValidateEllipsesWithErrors(t *testing.T) {
+	polygons := backend.Polygons{
+		{
+			VerticesCount: 3,
+			Loop: vector.Loop{
+				{X: 600, Y: 200},
+				{X: 646, Y: 133},
+				{X: 646, Y: 261},
+			},
+		},
+	}
+
+	walls := backend.Walls{
+		{VerticesCount: 2, Vertices: vector.Vectors{{X: 100, Y: 100}, {X: 300, Y: 100}}},
+	}
+
+	cases := []*struct {
+		ellipses backend.Ellipses
+		want     error
+	}{
+		{
+			backend.Ellipses{backend.NewCircle(400, 400, 50)},
+			nil,
+		},
+		{
+			backend.Ellipses{backend.NewCircle(400, 400, 0)},
+			&backend.ValidationError{Reason: "ellipse_radius", Message: "ellipse radius must be positive"},
+		},
+		{
+			backend.Ellipses{backend.NewCircle(780, 400, 50)},
+			&backend.ValidationError{Reason: "ellipse_outside_scene", Message: "ellipse X: 780 , Y: 400 is outside the scene"},
+		},
+		{
+			backend.Ellipses{backend.NewCircle(580, 200, 30)},
+			&backend.ValidationError{Reason: "ellipse_overlaps_polygon", Message: "ellipse X: 580 , Y: 200 overlaps a polygon"},
+		},
+		{
+			backend.Ellipses{backend.NewCircle(200, 120, 30)},
+			&backend.ValidationError{Reason: "ellipse_crossed_by_wall", Message: "ellipse X: 200 , Y: 120 is crossed by a wall"},
+		},
+		{
+			backend.Ellipses{backend.NewCircle(400, 400, 50), backend.NewCircle(460, 400, 20)},
+			&backend.ValidationError{Reason: "ellipse_overlaps_ellipse", Message: "ellipse X: 400 , Y: 400 overlaps another ellipse"},
+		},
+	}
+
+	for i, c := range cases {
+		got := c.ellipses.Validate(context.Background(), 800, 500, polygons, walls)
+		assert.Equal(t, c.want, got, "case failed: %v", i)
+	}
+}
+
+func TestSceneCircleCastsExactShadow(t *testing.T) {
+	config := &backend.Config{
+		Light:    &vector.Vector{X: 400, Y: 250},
+		Scene:    &vector.Vector{X: 800, Y: 500},
+		Ellipses: backend.Ellipses{backend.NewCircle(600, 250, 50)},
+	}
+
+	triangles, _, err := backend.NewScene(config).Process(context.Background())
+	assert.Nil(t, err)
+
+	arcs := 0
+	for _, triangle := range triangles {
+		if triangle.Arc != nil {
+			arcs++
+		}
+	}
+	assert.Equal(t, 1, arcs)
+
+	// the lit area excludes the shadow trapezoid between the chord connecting the
+	// tangent points and the right side of the scene, and the disc segment in
+	// front of the chord which faces the light
+	half := math.Asin(50.0 / 200)
+	tangentDistance := math.Sqrt(200*200 - 50*50)
+	tangentX := 400 + tangentDistance*math.Cos(half)
+	tangentY := tangentDistance * math.Sin(half)
+	farY := 400 * math.Tan(half)
+
+	shadow := (800 - tangentX) * (tangentY + farY)
+	facingAngle := math.Pi - 2*half
+	facing := 50 * 50 * (facingAngle - math.Sin(facingAngle)) / 2
+
+	want := 800*500 - shadow - facing
+
+	assert.InDelta(t, want, triangles.Area(), 1)
+}
@@ -1,8 +1,34 @@
 package backend
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	ErrContextExpired   = errors.New("context deadline exceeded")
 	ErrContextCancelled = errors.New("context was canceled")
+	ErrRepositoryLocked = errors.New("repository is locked by another process")
+	ErrVersionNotFound  = errors.New("version not found")
+	ErrSceneNotFound    = errors.New("scene not found")
+	ErrSuperseded       = errors.New("superseded by a newer config of the same scene")
+	ErrInvalidSceneID   = errors.New("scene id must contain only letters, digits, '_' and '-'")
+	ErrLightOutside     = errors.New("light is outside the scene")
 )
+
+// ValidationError is returned when a polygon, a wall or an ellipse of the scene is invalid,
+// Reason is a short name of the failed check, e.g. polygon_not_convex, which unlike the
+// message does not depend on the coordinates, so the failures can be counted by it
+type ValidationError struct {
+	Reason  string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// invalid returns a ValidationError with the formatted message
+func invalid(reason, format string, args ...interface{}) error {
+	return &ValidationError{Reason: reason, Message: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,173 @@
+package backend
+
+import (
+	"context"
+	"sync"
+
+	"github.com/iliyanmotovski/raytracer/backend/vector"
+)
+
+// subscriptionBuffer is the number of events kept for a subscriber which is not
+// keeping up, when they are more the subscription is closed
+const subscriptionBuffer = 16
+
+// SceneEvent is published every time a new version of a scene is stored
+type SceneEvent struct {
+	SceneID string
+	Version *Version
+	Summary *SceneSummary
+}
+
+// SceneSummary holds the size of the scene, its light and lit area
+// and how many of each of the objects in it there are
+type SceneSummary struct {
+	Width, Height, LitArea float64
+	Light                  *vector.Vector
+	Polygons               int
+	Walls                  int
+	Ellipses               int
+	Triangles              int
+}
+
+// NewSceneSummary creates the summary of the scene
+func NewSceneSummary(scene *Scene) *SceneSummary {
+	return &SceneSummary{
+		Width:     scene.Width,
+		Height:    scene.Height,
+		LitArea:   scene.LitArea,
+		Light:     scene.Light,
+		Polygons:  len(scene.Polygons),
+		Walls:     len(scene.Walls),
+		Ellipses:  len(scene.Ellipses),
+		Triangles: len(scene.Triangles),
+	}
+}
+
+// SceneEvents delivers the published scene events to the subscribers of the
+// scene, it is concurrent safe
+type SceneEvents struct {
+	mu          sync.Mutex
+	subscribers map[chan *SceneEvent]string
+	closed      bool
+}
+
+// NewSceneEvents creates new SceneEvents without subscribers
+func NewSceneEvents() *SceneEvents {
+	return &SceneEvents{subscribers: map[chan *SceneEvent]string{}}
+}
+
+// Subscribe returns a chan which receives the events of the scene and a func which
+// cancels the subscription. A subscriber which does not keep up with the events is
+// never waited for, instead its chan is closed, so it can catch up with the versions
+// from the repository and subscribe again
+func (e *SceneEvents) Subscribe(sceneID string) (<-chan *SceneEvent, func()) {
+	c := make(chan *SceneEvent, subscriptionBuffer)
+
+	e.mu.Lock()
+	if e.closed {
+		close(c)
+	} else {
+		e.subscribers[c] = sceneID
+	}
+	e.mu.Unlock()
+
+	return c, func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+
+		if _, ok := e.subscribers[c]; ok {
+			delete(e.subscribers, c)
+			close(c)
+		}
+	}
+}
+
+// Close closes the chans of all subscribers and of the later subscriptions,
+// so the event streams end, e.g. when the server shuts down
+func (e *SceneEvents) Close() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.closed = true
+	for c := range e.subscribers {
+		delete(e.subscribers, c)
+		close(c)
+	}
+}
+
+// Publish sends the event to all subscribers of its scene
+func (e *SceneEvents) Publish(event *SceneEvent) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for c, sceneID := range e.subscribers {
+		if sceneID != event.SceneID {
+			continue
+		}
+
+		select {
+		case c <- event:
+		default:
+			delete(e.subscribers, c)
+			close(c)
+		}
+	}
+}
+
+// publishingSceneRepository is a SceneRepository which publishes
+// a SceneEvent every time a new scene is stored in the wrapped one
+type publishingSceneRepository struct {
+	SceneRepository
+	events *SceneEvents
+
+	mu sync.Mutex
+	// locks holds a lock for each scene, so the version read after
+	// the Upsert is the upserted one, without blocking the other scenes
+	locks map[string]*sync.Mutex
+}
+
+// NewPublishingSceneRepository wraps the repository, so every Upsert publishes
+// a SceneEvent with the stored version of the scene to the events
+func NewPublishingSceneRepository(repo SceneRepository, events *SceneEvents) SceneRepository {
+	return &publishingSceneRepository{SceneRepository: repo, events: events, locks: map[string]*sync.Mutex{}}
+}
+
+// Upsert stores the scene in the wrapped repository and publishes it
+func (r *publishingSceneRepository) Upsert(ctx context.Context, scene *Scene) (*Scene, error) {
+	lock := r.lock(scene.ID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	persisted, err := r.SceneRepository.Upsert(ctx, scene)
+	if err != nil {
+		return persisted, err
+	}
+
+	version, err := r.SceneRepository.LatestVersion(ctx, persisted.ID)
+	if err != nil || version == nil {
+		// the scene is stored anyway, only its event is lost
+		return persisted, nil
+	}
+
+	r.events.Publish(&SceneEvent{
+		SceneID: persisted.ID,
+		Version: version,
+		Summary: NewSceneSummary(persisted),
+	})
+
+	return persisted, nil
+}
+
+// lock returns the lock of the scene, creating it if needed
+func (r *publishingSceneRepository) lock(sceneID string) *sync.Mutex {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	lock, ok := r.locks[sceneID]
+	if !ok {
+		lock = &sync.Mutex{}
+		r.locks[sceneID] = lock
+	}
+
+	return lock
+}
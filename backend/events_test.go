@@ -0,0 +1,129 @@
+package backend_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+	"github.com/iliyanmotovski/raytracer/backend/persistent"
+	"github.com/iliyanmotovski/raytracer/backend/vector"
+)
+
+func TestPublishingSceneRepository(t *testing.T) {
+	events := backend.NewSceneEvents()
+	sceneRepo := backend.NewPublishingSceneRepository(persistent.NewInMemorySceneRepository(), events)
+
+	subscription, unsubscribe := events.Subscribe(backend.DefaultSceneID)
+	defer unsubscribe()
+
+	other, unsubscribeOther := events.Subscribe("other")
+	defer unsubscribeOther()
+
+	for i := 1; i <= 2; i++ {
+		scene := &backend.Scene{
+			ID:       backend.DefaultSceneID,
+			Width:    800,
+			Height:   500,
+			LitArea:  float64(i),
+			Light:    &vector.Vector{X: 250, Y: 300},
+			Polygons: backend.Polygons{{VerticesCount: 3, Loop: vector.Loop{{X: 600, Y: 200}, {X: 646, Y: 133}, {X: 646, Y: 261}}}},
+		}
+
+		_, err := sceneRepo.Upsert(context.Background(), scene)
+		assert.Nil(t, err)
+
+		event := <-subscription
+		assert.Equal(t, backend.DefaultSceneID, event.SceneID)
+		assert.Equal(t, i, event.Version.ID)
+		assert.Equal(t, &backend.SceneSummary{
+			Width:    800,
+			Height:   500,
+			LitArea:  float64(i),
+			Light:    &vector.Vector{X: 250, Y: 300},
+			Polygons: 1,
+		}, event.Summary)
+	}
+
+	assert.Empty(t, other)
+}
+
+func TestPublishingSceneRepositoryDoesNotBlockOtherScenes(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	slow := &backend.Scene{ID: "slow", Light: &vector.Vector{}}
+	fast := &backend.Scene{ID: "fast", Light: &vector.Vector{}}
+
+	// the Upsert of the slow scene is blocked until released
+	repo := new(backend.FakeSceneRepository)
+	repo.On("Upsert", slow).Return(slow, nil).Run(func(mock.Arguments) {
+		close(started)
+		<-release
+	})
+	repo.On("Upsert", fast).Return(fast, nil)
+	repo.On("LatestVersion", "slow").Return(&backend.Version{ID: 8}, nil)
+	repo.On("LatestVersion", "fast").Return(&backend.Version{ID: 3}, nil)
+
+	events := backend.NewSceneEvents()
+	sceneRepo := backend.NewPublishingSceneRepository(repo, events)
+
+	subscription, unsubscribe := events.Subscribe("fast")
+	defer unsubscribe()
+
+	done := make(chan error)
+	go func() {
+		_, err := sceneRepo.Upsert(context.Background(), slow)
+		done <- err
+	}()
+	<-started
+
+	_, err := sceneRepo.Upsert(context.Background(), fast)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, (<-subscription).Version.ID)
+
+	close(release)
+	assert.Nil(t, <-done)
+
+	// the stored version is read without listing the whole history
+	repo.AssertNotCalled(t, "ListVersions", mock.Anything)
+}
+
+func TestSceneEventsClosesSlowSubscription(t *testing.T) {
+	events := backend.NewSceneEvents()
+
+	subscription, unsubscribe := events.Subscribe(backend.DefaultSceneID)
+
+	// the subscriber never receives, so its buffer fills up
+	for i := 1; i <= 100; i++ {
+		events.Publish(&backend.SceneEvent{SceneID: backend.DefaultSceneID, Version: &backend.Version{ID: i}})
+	}
+
+	received := 0
+	for range subscription {
+		received++
+	}
+
+	assert.True(t, received > 0 && received < 100)
+
+	// unsubscribing the closed subscription is a no-op
+	unsubscribe()
+}
+
+func TestSceneEventsClose(t *testing.T) {
+	events := backend.NewSceneEvents()
+
+	subscription, unsubscribe := events.Subscribe(backend.DefaultSceneID)
+	events.Close()
+
+	_, ok := <-subscription
+	assert.False(t, ok)
+	unsubscribe()
+
+	// the later subscriptions are closed right away and publishing is a no-op
+	later, _ := events.Subscribe(backend.DefaultSceneID)
+	events.Publish(&backend.SceneEvent{SceneID: backend.DefaultSceneID, Version: &backend.Version{ID: 1}})
+
+	_, ok = <-later
+	assert.False(t, ok)
+}
@@ -13,17 +13,29 @@ type FakeConfigRepository struct {
 }
 
 // Get is a no-op
-func (f FakeConfigRepository) Get(context.Context) (*Config, error) {
-	args := f.Called()
+func (f *FakeConfigRepository) Get(ctx context.Context, sceneID string) (*Config, error) {
+	args := f.Called(sceneID)
 	return args.Get(0).(*Config), args.Error(1)
 }
 
 // Upsert is a no-op
-func (f FakeConfigRepository) Upsert(ctx context.Context, cfg *Config) (*Config, error) {
+func (f *FakeConfigRepository) Upsert(ctx context.Context, cfg *Config) (*Config, error) {
 	args := f.Called(cfg)
 	return args.Get(0).(*Config), args.Error(1)
 }
 
+// ListVersions is a no-op
+func (f *FakeConfigRepository) ListVersions(ctx context.Context, sceneID string) (Versions, error) {
+	args := f.Called(sceneID)
+	return args.Get(0).(Versions), args.Error(1)
+}
+
+// GetVersion is a no-op
+func (f *FakeConfigRepository) GetVersion(ctx context.Context, sceneID string, version int) (*Config, error) {
+	args := f.Called(sceneID, version)
+	return args.Get(0).(*Config), args.Error(1)
+}
+
 // FakeSceneRepository is a fake implementation of SceneRepository
 // using the testify mock package to generate mocks
 type FakeSceneRepository struct {
@@ -31,13 +43,32 @@ type FakeSceneRepository struct {
 }
 
 // Get is a no-op
-func (f FakeSceneRepository) Get(context.Context) (*Scene, error) {
-	args := f.Called()
+func (f *FakeSceneRepository) Get(ctx context.Context, sceneID string) (*Scene, error) {
+	args := f.Called(sceneID)
 	return args.Get(0).(*Scene), args.Error(1)
 }
 
 // Upsert is a no-op
-func (f FakeSceneRepository) Upsert(ctx context.Context, cfg *Scene) (*Scene, error) {
+func (f *FakeSceneRepository) Upsert(ctx context.Context, cfg *Scene) (*Scene, error) {
 	args := f.Called(cfg)
 	return args.Get(0).(*Scene), args.Error(1)
 }
+
+// ListVersions is a no-op
+func (f *FakeSceneRepository) ListVersions(ctx context.Context, sceneID string) (Versions, error) {
+	args := f.Called(sceneID)
+	return args.Get(0).(Versions), args.Error(1)
+}
+
+// LatestVersion is a no-op
+func (f *FakeSceneRepository) LatestVersion(ctx context.Context, sceneID string) (*Version, error) {
+	args := f.Called(sceneID)
+	version, _ := args.Get(0).(*Version)
+	return version, args.Error(1)
+}
+
+// GetVersion is a no-op
+func (f *FakeSceneRepository) GetVersion(ctx context.Context, sceneID string, version int) (*Scene, error) {
+	args := f.Called(sceneID, version)
+	return args.Get(0).(*Scene), args.Error(1)
+}
@@ -0,0 +1,180 @@
+// Package health serves the endpoints a process supervisor or a load balancer probes: whether the
+// process is alive, whether it is ready to handle requests, and which build of it is running
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// ErrShuttingDown is the error of the readiness check once the server is shutting down
+var ErrShuttingDown = errors.New("shutting down")
+
+// Check returns an error when a part of the server is not ready, e.g. a repository does not respond.
+// The context is done once the check takes longer than the timeout of the Readiness
+type Check func(ctx context.Context) error
+
+// Readiness runs the checks of the server to tell whether it is ready to handle requests
+type Readiness struct {
+	timeout time.Duration
+
+	mu           sync.Mutex
+	checks       map[string]Check
+	shuttingDown bool
+}
+
+// NewReadiness creates a Readiness without any check, which gives every run of a check the timeout
+func NewReadiness(timeout time.Duration) *Readiness {
+	return &Readiness{timeout: timeout, checks: map[string]Check{}}
+}
+
+// Add adds the check with the name, replacing the check which has the same name
+func (r *Readiness) Add(name string, check Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.checks[name] = check
+}
+
+// Shutdown marks that the server is shutting down, so it is never ready again
+func (r *Readiness) Shutdown() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.shuttingDown = true
+}
+
+// Check runs all checks concurrently and returns their errors by their names, the nil error of a
+// check means it has passed. The server is ready when all of them pass. Once it is shutting down,
+// none is run and only the shutdown check fails with ErrShuttingDown
+func (r *Readiness) Check(ctx context.Context) (bool, map[string]error) {
+	r.mu.Lock()
+	if r.shuttingDown {
+		r.mu.Unlock()
+		return false, map[string]error{"shutdown": ErrShuttingDown}
+	}
+
+	checks := make(map[string]Check, len(r.checks))
+	for name, check := range r.checks {
+		checks[name] = check
+	}
+	r.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	errs := make(map[string]error, len(checks))
+	ready := true
+
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check Check) {
+			defer wg.Done()
+
+			err := run(ctx, check)
+
+			mu.Lock()
+			errs[name] = err
+			ready = ready && err == nil
+			mu.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+
+	return ready, errs
+}
+
+// run runs the check, a check which does not return once the context is done fails with its error
+func run(ctx context.Context, check Check) error {
+	done := make(chan error, 1)
+	go func() { done <- check(ctx) }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// readinessDTO holds "ok" or the error of every check by its name
+type readinessDTO struct {
+	Ready  bool
+	Checks map[string]string
+}
+
+// ServeHTTP responds with 200 OK when the server is ready and with 503 Service Unavailable
+// otherwise, the body holds the result of every check, which is "ok" or its error
+func (r *Readiness) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	ready, errs := r.Check(req.Context())
+
+	resp := &readinessDTO{Ready: ready, Checks: make(map[string]string, len(errs))}
+	for name, err := range errs {
+		resp.Checks[name] = "ok"
+		if err != nil {
+			resp.Checks[name] = err.Error()
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if ready {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	json.NewEncoder(w).Encode(resp)
+}
+
+// Alive is an http handler which responds with 200 OK as long as the process serves requests
+func Alive(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	io.WriteString(w, "ok\n")
+}
+
+// BuildInfo describes the running build, it is served as JSON
+type BuildInfo struct {
+	// Version is the version of the build, e.g. v1.2.0, or "dev" when it is not known
+	Version string
+	// Commit is the revision the binary was built from or "" when it is not known
+	Commit string
+	// BuildTime is when the binary was built or "" when it is not known
+	BuildTime string
+	// GoVersion is the version of Go the binary was built with
+	GoVersion string
+}
+
+// NewBuildInfo creates the BuildInfo of the running binary, the version, the commit and the build
+// time are usually set with -ldflags -X at build time. When the version is empty, the version of
+// the main module is used, which is known when the binary is installed with go install, e.g.
+// go install github.com/iliyanmotovski/raytracer/cmd@v1.2.0
+func NewBuildInfo(version, commit, buildTime string) *BuildInfo {
+	if version == "" {
+		if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "(devel)" {
+			version = info.Main.Version
+		}
+	}
+
+	if version == "" {
+		version = "dev"
+	}
+
+	return &BuildInfo{Version: version, Commit: commit, BuildTime: buildTime, GoVersion: runtime.Version()}
+}
+
+// ServeHTTP serves the build info as JSON
+func (b *BuildInfo) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(b)
+}
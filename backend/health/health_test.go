@@ -0,0 +1,87 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iliyanmotovski/raytracer/backend/health"
+)
+
+func TestReadiness(t *testing.T) {
+	readiness := health.NewReadiness(50 * time.Millisecond)
+
+	ready, errs := readiness.Check(context.Background())
+	assert.True(t, ready)
+	assert.Empty(t, errs)
+
+	failed := errors.New("scene repository: closed")
+	readiness.Add("scene", func(context.Context) error { return nil })
+	readiness.Add("repositories", func(context.Context) error { return failed })
+	// a check which hangs fails once the timeout passes
+	release := make(chan struct{})
+	defer close(release)
+	readiness.Add("daemon", func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+
+	ready, errs = readiness.Check(context.Background())
+	assert.False(t, ready)
+	assert.Equal(t, map[string]error{"scene": nil, "repositories": failed, "daemon": context.DeadlineExceeded}, errs)
+
+	// the checks are replaced by their names
+	readiness.Add("repositories", func(context.Context) error { return nil })
+	readiness.Add("daemon", func(context.Context) error { return nil })
+
+	w := httptest.NewRecorder()
+	readiness.ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"Ready":true,"Checks":{"scene":"ok","repositories":"ok","daemon":"ok"}}`, w.Body.String())
+
+	readiness.Add("repositories", func(context.Context) error { return failed })
+
+	w = httptest.NewRecorder()
+	readiness.ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.JSONEq(t, `{"Ready":false,"Checks":{"scene":"ok","repositories":"scene repository: closed","daemon":"ok"}}`, w.Body.String())
+
+	// once the server is shutting down, it is never ready
+	readiness.Add("repositories", func(context.Context) error { return nil })
+	readiness.Shutdown()
+
+	w = httptest.NewRecorder()
+	readiness.ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.JSONEq(t, `{"Ready":false,"Checks":{"shutdown":"shutting down"}}`, w.Body.String())
+}
+
+func TestAlive(t *testing.T) {
+	w := httptest.NewRecorder()
+	health.Alive(w, httptest.NewRequest("GET", "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ok\n", w.Body.String())
+}
+
+func TestBuildInfo(t *testing.T) {
+	info := health.NewBuildInfo("v1.2.0", "abc123", "2026-10-19T12:00:00Z")
+	assert.Equal(t, &health.BuildInfo{Version: "v1.2.0", Commit: "abc123", BuildTime: "2026-10-19T12:00:00Z", GoVersion: runtime.Version()}, info)
+
+	w := httptest.NewRecorder()
+	info.ServeHTTP(w, httptest.NewRequest("GET", "/version", nil))
+
+	served := &health.BuildInfo{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), served))
+	assert.Equal(t, info, served)
+
+	// the tests are not built from a versioned module
+	assert.Equal(t, "dev", health.NewBuildInfo("", "", "").Version)
+}
@@ -0,0 +1,292 @@
+// Package logging writes leveled log lines with key value fields as text or JSON and carries
+// the request ID in the context, so all lines logged for one request can be correlated
+package logging
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Level is the severity of a log line, the lines below the level of the Logger are dropped
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = []string{"DEBUG", "INFO", "WARN", "ERROR"}
+
+func (l Level) String() string {
+	if l < LevelDebug || l > LevelError {
+		return "LEVEL(" + strconv.Itoa(int(l)) + ")"
+	}
+
+	return levelNames[l]
+}
+
+// ParseLevel parses debug, info, warn or error in any case
+func ParseLevel(s string) (Level, error) {
+	for i, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			return Level(i), nil
+		}
+	}
+
+	return LevelInfo, fmt.Errorf("unknown log level %q", s)
+}
+
+// Format is the format of the log lines
+type Format string
+
+const (
+	// TextFormat writes the lines as key=value pairs
+	TextFormat Format = "text"
+	// JSONFormat writes every line as a JSON object
+	JSONFormat Format = "json"
+)
+
+// ParseFormat parses text or json
+func ParseFormat(s string) (Format, error) {
+	switch format := Format(strings.ToLower(s)); format {
+	case TextFormat, JSONFormat:
+		return format, nil
+	default:
+		return TextFormat, fmt.Errorf("unknown log format %q", s)
+	}
+}
+
+// Logger writes the log lines of its level and above, each with the fields of the logger followed
+// by the fields of the line. The fields are given as alternating keys and values, e.g.
+// logger.Info("config processed", "scene", id, "duration", d). It is safe for concurrent use
+type Logger struct {
+	out    *output
+	fields []interface{}
+}
+
+// output is shared by a Logger and the loggers derived from it with With
+type output struct {
+	mu     sync.Mutex
+	w      io.Writer
+	level  Level
+	format Format
+}
+
+// New creates a Logger which writes the lines of the level and above to w in the format
+func New(w io.Writer, level Level, format Format) *Logger {
+	return &Logger{out: &output{w: w, level: level, format: format}}
+}
+
+// With returns a Logger which adds the fields to every line
+func (l *Logger) With(fields ...interface{}) *Logger {
+	return &Logger{out: l.out, fields: append(append([]interface{}(nil), l.fields...), fields...)}
+}
+
+// Enabled returns whether the lines of the level are written
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.out.level
+}
+
+// Debug logs the message and the fields at the debug level
+func (l *Logger) Debug(msg string, fields ...interface{}) {
+	l.log(LevelDebug, msg, fields)
+}
+
+// Info logs the message and the fields at the info level
+func (l *Logger) Info(msg string, fields ...interface{}) {
+	l.log(LevelInfo, msg, fields)
+}
+
+// Warn logs the message and the fields at the warning level
+func (l *Logger) Warn(msg string, fields ...interface{}) {
+	l.log(LevelWarn, msg, fields)
+}
+
+// Error logs the message and the fields at the error level
+func (l *Logger) Error(msg string, fields ...interface{}) {
+	l.log(LevelError, msg, fields)
+}
+
+func (l *Logger) log(level Level, msg string, fields []interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	line := &bytes.Buffer{}
+	all := append(append([]interface{}{"time", time.Now().UTC().Format(time.RFC3339Nano), "level", level.String(), "msg", msg}, l.fields...), fields...)
+
+	if l.out.format == JSONFormat {
+		writeJSON(line, all)
+	} else {
+		writeText(line, all)
+	}
+
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+
+	l.out.w.Write(line.Bytes())
+}
+
+// badKey is the key of a value which is not preceded by a key
+const badKey = "!BADKEY"
+
+// pairs calls f with every key and value of the alternating fields
+func pairs(fields []interface{}, f func(key string, value interface{})) {
+	for i := 0; i < len(fields); i += 2 {
+		if i+1 == len(fields) {
+			f(badKey, fields[i])
+			return
+		}
+
+		key, ok := fields[i].(string)
+		if !ok {
+			key = fmt.Sprint(fields[i])
+		}
+
+		f(key, fields[i+1])
+	}
+}
+
+func writeText(buf *bytes.Buffer, fields []interface{}) {
+	first := true
+	pairs(fields, func(key string, value interface{}) {
+		if !first {
+			buf.WriteByte(' ')
+		}
+		first = false
+
+		buf.WriteString(key)
+		buf.WriteByte('=')
+		buf.WriteString(quote(text(value)))
+	})
+	buf.WriteByte('\n')
+}
+
+func writeJSON(buf *bytes.Buffer, fields []interface{}) {
+	buf.WriteByte('{')
+
+	first := true
+	pairs(fields, func(key string, value interface{}) {
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+
+		k, _ := json.Marshal(key)
+		buf.Write(k)
+		buf.WriteByte(':')
+
+		switch v := value.(type) {
+		case error, fmt.Stringer:
+			value = text(v)
+		}
+
+		b, err := json.Marshal(value)
+		if err != nil {
+			b, _ = json.Marshal(fmt.Sprint(value))
+		}
+		buf.Write(b)
+	})
+
+	buf.WriteString("}\n")
+}
+
+// text returns the value as it is written in the text format
+func text(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "<nil>"
+	case string:
+		return v
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// quote quotes the value when it is empty or it contains spaces, quotes, = or control characters
+func quote(s string) string {
+	if s == "" {
+		return `""`
+	}
+
+	for _, r := range s {
+		if unicode.IsSpace(r) || r == '"' || r == '=' || !unicode.IsPrint(r) {
+			return strconv.Quote(s)
+		}
+	}
+
+	return s
+}
+
+var (
+	defaultMu     sync.RWMutex
+	defaultLogger = New(os.Stderr, LevelInfo, TextFormat)
+)
+
+// Default returns the default Logger, which writes the info lines and above as text to stderr until SetDefault
+func Default() *Logger {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+
+	return defaultLogger
+}
+
+// SetDefault replaces the default Logger
+func SetDefault(l *Logger) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+
+	defaultLogger = l
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of the context which carries the request ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID carried by the context or "" when there is none
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// NewRequestID returns a new random request ID
+func NewRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// FromContext returns the default Logger, which adds the request ID of the context to every line
+func FromContext(ctx context.Context) *Logger {
+	l := Default()
+	if id := RequestID(ctx); id != "" {
+		return l.With("request_id", id)
+	}
+
+	return l
+}
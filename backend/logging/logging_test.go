@@ -0,0 +1,80 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iliyanmotovski/raytracer/backend/logging"
+)
+
+func TestLoggerText(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := logging.New(buf, logging.LevelInfo, logging.TextFormat).With("scene", "default")
+
+	logger.Debug("dropped")
+	logger.Info("config processed", "polygons", 3, "duration", 1500*time.Millisecond, "path", "my config.txt")
+	logger.Warn("config not reloaded", "error", errors.New(`invalid "x"`), "odd")
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	assert.Len(t, lines, 2)
+
+	time := `time=\d{4}-\d\d-\d\dT[^ ]+Z `
+	assert.Regexp(t, regexp.MustCompile(`^`+time+`level=INFO msg="config processed" scene=default polygons=3 duration=1.5s path="my config.txt"$`), lines[0])
+	assert.Regexp(t, regexp.MustCompile(`^`+time+`level=WARN msg="config not reloaded" scene=default error="invalid \\"x\\"" !BADKEY=odd$`), lines[1])
+}
+
+func TestLoggerJSON(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := logging.New(buf, logging.LevelDebug, logging.JSONFormat)
+
+	logger.Debug("config processed", "lit_area", 93.38, "error", errors.New("failed"), "duration", time.Second)
+
+	line := map[string]interface{}{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.NotEmpty(t, line["time"])
+
+	delete(line, "time")
+	assert.Equal(t, map[string]interface{}{"level": "DEBUG", "msg": "config processed", "lit_area": 93.38, "error": "failed", "duration": "1s"}, line)
+}
+
+func TestFromContext(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	defaultLogger := logging.Default()
+	logging.SetDefault(logging.New(buf, logging.LevelInfo, logging.TextFormat))
+	defer logging.SetDefault(defaultLogger)
+
+	ctx := logging.WithRequestID(context.Background(), "abc123")
+	assert.Equal(t, "abc123", logging.RequestID(ctx))
+
+	logging.FromContext(ctx).Info("handled")
+	logging.FromContext(context.Background()).Info("reloaded")
+
+	lines := strings.Split(buf.String(), "\n")
+	assert.True(t, strings.HasSuffix(lines[0], "msg=handled request_id=abc123"), lines[0])
+	assert.True(t, strings.HasSuffix(lines[1], "msg=reloaded"), lines[1])
+}
+
+func TestParseLevelAndFormat(t *testing.T) {
+	level, err := logging.ParseLevel("warn")
+	assert.Nil(t, err)
+	assert.Equal(t, logging.LevelWarn, level)
+
+	_, err = logging.ParseLevel("verbose")
+	assert.NotNil(t, err)
+
+	format, err := logging.ParseFormat("JSON")
+	assert.Nil(t, err)
+	assert.Equal(t, logging.JSONFormat, format)
+
+	_, err = logging.ParseFormat("xml")
+	assert.NotNil(t, err)
+}
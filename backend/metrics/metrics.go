@@ -0,0 +1,323 @@
+// Package metrics implements counters, gauges and histograms which are
+// written in the Prometheus text exposition format, without any dependency
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the content type of the Prometheus text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets are the upper bounds of the histogram buckets of durations in seconds
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Registry holds the metrics and writes them in the order they were created
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+	names   map[string]bool
+}
+
+// metric is a named family of samples with the same type
+type metric interface {
+	name() string
+	write(w *bufio.Writer)
+}
+
+// NewRegistry creates an empty Registry
+func NewRegistry() *Registry {
+	return &Registry{names: map[string]bool{}}
+}
+
+// register adds the metric, it panics when there is already a metric with the same name
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.names[m.name()] {
+		panic(fmt.Sprintf("metric %s is already registered", m.name()))
+	}
+
+	r.names[m.name()] = true
+	r.metrics = append(r.metrics, m)
+}
+
+// Write writes all metrics in the Prometheus text exposition format
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	metrics := append([]metric(nil), r.metrics...)
+	r.mu.Unlock()
+
+	buf := bufio.NewWriter(w)
+	for _, m := range metrics {
+		m.write(buf)
+	}
+
+	return buf.Flush()
+}
+
+// ServeHTTP serves the metrics, so the Registry can be scraped by Prometheus
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	r.Write(w)
+}
+
+// family holds the samples of a metric by their label values
+type family struct {
+	metricName, help, kind string
+	labels                 []string
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	values []string
+	value  float64
+	// buckets and count are only used by the histograms
+	buckets []uint64
+	count   uint64
+}
+
+func newFamily(name, help, kind string, labels []string) *family {
+	return &family{metricName: name, help: help, kind: kind, labels: labels, series: map[string]*series{}}
+}
+
+func (f *family) name() string {
+	return f.metricName
+}
+
+// with returns the series of the label values, it must be called with the lock held
+func (f *family) with(values []string) *series {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metric %s has %d labels, got %d values", f.metricName, len(f.labels), len(values)))
+	}
+
+	key := strings.Join(values, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{values: append([]string(nil), values...)}
+		f.series[key] = s
+	}
+
+	return s
+}
+
+// sorted returns the series sorted by their label values, it must be called with the lock held
+func (f *family) sorted() []*series {
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	sorted := make([]*series, len(keys))
+	for i, key := range keys {
+		sorted[i] = f.series[key]
+	}
+
+	return sorted
+}
+
+func (f *family) writeHeader(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", f.metricName, escapeHelp(f.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.metricName, f.kind)
+}
+
+func (f *family) write(w *bufio.Writer) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.writeHeader(w)
+	for _, s := range f.sorted() {
+		writeSample(w, f.metricName, f.labels, s.values, "", "", s.value)
+	}
+}
+
+// CounterVec is a counter partitioned by its labels
+type CounterVec struct {
+	*family
+}
+
+// NewCounterVec creates and registers a counter with the label names
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{newFamily(name, help, "counter", labels)}
+	r.register(c)
+
+	return c
+}
+
+// Inc increments the counter of the label values by 1
+func (c *CounterVec) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add adds v, which must not be negative, to the counter of the label values
+func (c *CounterVec) Add(v float64, values ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.with(values).value += v
+}
+
+// GaugeVec is a gauge partitioned by its labels
+type GaugeVec struct {
+	*family
+}
+
+// NewGaugeVec creates and registers a gauge with the label names
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{newFamily(name, help, "gauge", labels)}
+	r.register(g)
+
+	return g
+}
+
+// Set sets the gauge of the label values to v
+func (g *GaugeVec) Set(v float64, values ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.with(values).value = v
+}
+
+// HistogramVec counts the observed values in buckets and is partitioned by its labels
+type HistogramVec struct {
+	*family
+	buckets []float64
+}
+
+// NewHistogramVec creates and registers a histogram with the upper bounds
+// of its buckets in increasing order and the label names
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{family: newFamily(name, help, "histogram", labels), buckets: buckets}
+	r.register(h)
+
+	return h
+}
+
+// Observe adds the value to the histogram of the label values
+func (h *HistogramVec) Observe(v float64, values ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := h.with(values)
+	if s.buckets == nil {
+		s.buckets = make([]uint64, len(h.buckets))
+	}
+
+	for i, upper := range h.buckets {
+		if v <= upper {
+			s.buckets[i]++
+		}
+	}
+
+	s.count++
+	s.value += v
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.writeHeader(w)
+	for _, s := range h.sorted() {
+		for i, upper := range h.buckets {
+			writeSample(w, h.metricName+"_bucket", h.labels, s.values, "le", formatFloat(upper), float64(s.buckets[i]))
+		}
+
+		writeSample(w, h.metricName+"_bucket", h.labels, s.values, "le", "+Inf", float64(s.count))
+		writeSample(w, h.metricName+"_sum", h.labels, s.values, "", "", s.value)
+		writeSample(w, h.metricName+"_count", h.labels, s.values, "", "", float64(s.count))
+	}
+}
+
+// funcMetric is a metric without labels whose value is read when it is written
+type funcMetric struct {
+	metricName, help, kind string
+	value                  func() float64
+}
+
+// NewGaugeFunc creates and registers a gauge whose value is returned by f
+func (r *Registry) NewGaugeFunc(name, help string, f func() float64) {
+	r.register(&funcMetric{metricName: name, help: help, kind: "gauge", value: f})
+}
+
+// NewCounterFunc creates and registers a counter whose value is returned by f, which must never decrease
+func (r *Registry) NewCounterFunc(name, help string, f func() float64) {
+	r.register(&funcMetric{metricName: name, help: help, kind: "counter", value: f})
+}
+
+func (m *funcMetric) name() string {
+	return m.metricName
+}
+
+func (m *funcMetric) write(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", m.metricName, escapeHelp(m.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", m.metricName, m.kind)
+	writeSample(w, m.metricName, nil, nil, "", "", m.value())
+}
+
+// writeSample writes a single sample line, with the extra label when its name is set
+func writeSample(w *bufio.Writer, name string, labels, values []string, extraLabel, extraValue string, v float64) {
+	w.WriteString(name)
+
+	if len(labels) > 0 || extraLabel != "" {
+		w.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+
+			fmt.Fprintf(w, "%s=\"%s\"", label, escapeLabel(values[i]))
+		}
+
+		if extraLabel != "" {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+
+			fmt.Fprintf(w, "%s=\"%s\"", extraLabel, extraValue)
+		}
+		w.WriteByte('}')
+	}
+
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(v))
+	w.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
@@ -0,0 +1,73 @@
+package metrics_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iliyanmotovski/raytracer/backend/metrics"
+)
+
+func TestRegistryWrite(t *testing.T) {
+	registry := metrics.NewRegistry()
+
+	requests := registry.NewCounterVec("requests_total", "Requests by status.", "route", "status")
+	requests.Inc("/scene", "200")
+	requests.Inc("/scene", "200")
+	requests.Add(3, "/a\"b", "404")
+
+	litArea := registry.NewGaugeVec("lit_area_percent", "Lit area\nof the scene.", "scene")
+	litArea.Set(12.5, "default")
+
+	durations := registry.NewHistogramVec("duration_seconds", "Durations.", []float64{0.1, 1}, "job")
+	durations.Observe(0.05, "config")
+	durations.Observe(0.5, "config")
+	durations.Observe(5, "config")
+
+	registry.NewGaugeFunc("queue_depth", "Pending jobs.", func() float64 { return 2 })
+
+	buf := &bytes.Buffer{}
+	assert.Nil(t, registry.Write(buf))
+
+	assert.Equal(t, `# HELP requests_total Requests by status.
+# TYPE requests_total counter
+requests_total{route="/a\"b",status="404"} 3
+requests_total{route="/scene",status="200"} 2
+# HELP lit_area_percent Lit area\nof the scene.
+# TYPE lit_area_percent gauge
+lit_area_percent{scene="default"} 12.5
+# HELP duration_seconds Durations.
+# TYPE duration_seconds histogram
+duration_seconds_bucket{job="config",le="0.1"} 1
+duration_seconds_bucket{job="config",le="1"} 2
+duration_seconds_bucket{job="config",le="+Inf"} 3
+duration_seconds_sum{job="config"} 5.55
+duration_seconds_count{job="config"} 3
+# HELP queue_depth Pending jobs.
+# TYPE queue_depth gauge
+queue_depth 2
+`, buf.String())
+}
+
+func TestRegistryServeHTTP(t *testing.T) {
+	registry := metrics.NewRegistry()
+	registry.NewCounterFunc("processed_total", "Processed jobs.", func() float64 { return 7 })
+
+	w := httptest.NewRecorder()
+	registry.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, metrics.ContentType, w.Header().Get("Content-Type"))
+	assert.Equal(t, "# HELP processed_total Processed jobs.\n# TYPE processed_total counter\nprocessed_total 7\n", w.Body.String())
+}
+
+func TestRegistryPanicsOnDuplicateNameAndWrongLabels(t *testing.T) {
+	registry := metrics.NewRegistry()
+	counter := registry.NewCounterVec("requests_total", "Requests.", "route")
+
+	assert.Panics(t, func() { registry.NewGaugeVec("requests_total", "Requests.") })
+	assert.Panics(t, func() { counter.Inc("/scene", "200") })
+}
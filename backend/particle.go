@@ -1,9 +1,11 @@
 package backend
 
 import (
+	"context"
 	"math"
 	"sort"
 
+	"github.com/iliyanmotovski/raytracer/backend/tracing"
 	"github.com/iliyanmotovski/raytracer/backend/vector"
 )
 
@@ -43,17 +45,40 @@ func NewParticle(x, y float64, sceneEdgesBounds Boundaries) *Particle {
 	return &Particle{Pos: &vector.Vector{X: x, Y: y}, Rays: baseRays}
 }
 
-// Process casts the rays
-func (p *Particle) Process(boundaries Boundaries, polygons Polygons) Triangles {
+// Process casts the rays, it stops with ErrContextCancelled or
+// ErrContextExpired as soon as the context is done
+func (p *Particle) Process(ctx context.Context, boundaries Boundaries, polygons Polygons, walls Walls, ellipses Ellipses) (Triangles, error) {
+	_, span := tracing.Start(ctx, "rays.generate")
 	// Adds 2 rays for each polygon vertice and sets their direction with a very
 	// small offset to the left and right of the vertice
 	p.SetRaysDirToPolyVertices(polygons)
+	// does the same for each wall vertice
+	p.SetRaysDirToWallVertices(walls)
+	// ellipses have no vertices, the rays go to their tangent points instead
+	p.SetRaysDirToEllipseTangents(ellipses)
+	span.SetAttributes("rays", len(p.Rays))
+	span.End()
+
 	// sorts the rays clockwise by angle
+	_, span = tracing.Start(ctx, "rays.sort")
 	p.SortRaysClockwise()
+	span.End()
 
+	_, span = tracing.Start(ctx, "rays.cast", "rays", len(p.Rays), "boundaries", len(boundaries), "ellipses", len(ellipses))
 	edges := vector.Loop{}
+	// the ellipse hit by the ray which produced the edge at the same index, if any
+	hits := Ellipses{}
 	for _, ray := range p.Rays {
+		// every ray is cast against all boundaries, so checking
+		// the context once per ray keeps the overhead negligible
+		if err := ContextError(ctx); err != nil {
+			span.SetError(err)
+			span.End()
+			return Triangles{}, err
+		}
+
 		var closest *vector.Vector
+		var hit *Ellipse
 		lastDistance := math.Inf(1)
 
 		for _, boundary := range boundaries {
@@ -70,30 +95,99 @@ func (p *Particle) Process(boundaries Boundaries, polygons Polygons) Triangles {
 			}
 		}
 
+		for _, ellipse := range ellipses {
+			intersection, ok := ray.CastEllipse(ellipse)
+			if ok {
+				distance := ray.A.Distance(*intersection)
+				if distance < lastDistance {
+					lastDistance = distance
+					closest = intersection
+					hit = ellipse
+				}
+			}
+		}
+
 		if closest != nil {
 			edges = append(edges, closest)
+			hits = append(hits, hit)
+		}
+	}
+	span.End()
+
+	_, span = tracing.Start(ctx, "triangles.fan")
+	defer span.End()
+
+	triangles := NewClockwiseTriangleFan(p.Pos, edges)
+
+	// when two neighbour rays hit the same ellipse, the lit area between
+	// them is bounded by the arc of the ellipse and not by the straight line
+	for i, triangle := range triangles {
+		next := (i + 1) % len(hits)
+		if hits[i] != nil && hits[i] == hits[next] {
+			triangle.Arc = &Arc{
+				Ellipse: hits[i],
+				From:    hits[i].AngleOf(edges[i]),
+				To:      hits[i].AngleOf(edges[next]),
+			}
 		}
 	}
+	span.SetAttributes("triangles", len(triangles))
 
-	return NewClockwiseTriangleFan(p.Pos, edges)
+	return triangles, nil
 }
 
 // SetRaysDirToPolyVertices adds 2 rays for each polygon vertice
 // and sets their direction with a very small offset to the left
-//and right of the vertice
+// and right of the vertice
 func (p *Particle) SetRaysDirToPolyVertices(polygons Polygons) {
-	for _, polygon := range polygons {
-		for _, vertex := range polygon.Loop {
+	p.setRaysDirToVertices(polygons.getAllVertices())
+}
+
+// SetRaysDirToWallVertices adds 2 rays for each wall vertice
+// and sets their direction with a very small offset to the left
+// and right of the vertice
+func (p *Particle) SetRaysDirToWallVertices(walls Walls) {
+	p.setRaysDirToVertices(walls.getAllVertices())
+}
+
+// SetRaysDirToEllipseTangents adds 2 rays for each tangent point
+// of each ellipse and sets their direction with a very small angle
+// to the left and right of the tangent, so one of them grazes the
+// ellipse and the other one passes by it
+func (p *Particle) SetRaysDirToEllipseTangents(ellipses Ellipses) {
+	for _, ellipse := range ellipses {
+		tangents, ok := ellipse.TangentPoints(p.Pos)
+		if !ok {
+			continue
+		}
+
+		for _, tangent := range tangents {
+			dir := tangent.Sub(*p.Pos)
+
+			left := dir.Rotate(-0.00001)
 			rayLeft := NewRay(p.Pos)
-			rayLeft.SetDir(vertex.X-0.0001, vertex.Y-0.0001)
+			rayLeft.SetDir(p.Pos.X+left.X, p.Pos.Y+left.Y)
+
+			right := dir.Rotate(0.00001)
 			rayRight := NewRay(p.Pos)
-			rayRight.SetDir(vertex.X+0.0001, vertex.Y+0.0001)
+			rayRight.SetDir(p.Pos.X+right.X, p.Pos.Y+right.Y)
 
 			p.Rays = append(p.Rays, rayLeft, rayRight)
 		}
 	}
 }
 
+func (p *Particle) setRaysDirToVertices(vertices vector.Vectors) {
+	for _, vertex := range vertices {
+		rayLeft := NewRay(p.Pos)
+		rayLeft.SetDir(vertex.X-0.0001, vertex.Y-0.0001)
+		rayRight := NewRay(p.Pos)
+		rayRight.SetDir(vertex.X+0.0001, vertex.Y+0.0001)
+
+		p.Rays = append(p.Rays, rayLeft, rayRight)
+	}
+}
+
 // SortRaysClockwise sorts the rays clockwise by angle
 func (p *Particle) SortRaysClockwise() {
 	sort.Slice(p.Rays, func(i, j int) bool {
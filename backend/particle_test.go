@@ -1,8 +1,10 @@
 package backend_test
 
 import (
+	"context"
 	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -58,7 +60,8 @@ func TestParticleProcess(t *testing.T) {
 
 	screenBounds = append(screenBounds, poly.GetBoundaries()...)
 
-	triangles := particle.Process(screenBounds, backend.Polygons{poly})
+	triangles, err := particle.Process(context.Background(), screenBounds, backend.Polygons{poly}, nil, nil)
+	assert.Nil(t, err)
 
 	got, _ := json.Marshal(triangles)
 
@@ -78,3 +81,23 @@ func TestParticleProcess(t *testing.T) {
 
 	assert.Equal(t, want, string(got))
 }
+
+func TestParticleProcessWithExpiredContext(t *testing.T) {
+	config := newLargeConfig(150)
+	scene := backend.NewScene(config)
+
+	boundaries := append(backend.Boundaries{}, scene.Boundaries...)
+	for _, polygon := range config.Polygons {
+		boundaries = append(boundaries, polygon.GetBoundaries()...)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	particle := backend.NewParticle(config.Light.X, config.Light.Y, scene.Boundaries)
+	_, err := particle.Process(ctx, boundaries, config.Polygons, nil, nil)
+
+	assert.Equal(t, backend.ErrContextExpired, err)
+	assert.True(t, time.Since(start) < 2*time.Second, "ray casting did not stop in time")
+}
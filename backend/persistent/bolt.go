@@ -0,0 +1,189 @@
+package persistent
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+)
+
+// OpenBoltDB creates the data directory if needed and opens the bolt database
+// inside it, bolt locks the database file so only one process can use it
+func OpenBoltDB(dir string) (*bolt.DB, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	db, err := bolt.Open(filepath.Join(dir, "raytracer.db"), 0644, &bolt.Options{Timeout: time.Second})
+	if err == bolt.ErrTimeout {
+		return nil, backend.ErrRepositoryLocked
+	}
+
+	return db, err
+}
+
+// boltRecord is the value stored under each version key, Value
+// is either a *backend.Config or a *backend.Scene
+type boltRecord struct {
+	Version *backend.Version
+	Value   interface{}
+}
+
+// boltVersionRecord is used to decode only the version of a record
+type boltVersionRecord struct {
+	Version *backend.Version
+}
+
+// createBucket creates the bucket if it does not exist yet
+func createBucket(db *bolt.DB, bucket []byte) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucket)
+		return err
+	})
+}
+
+// boltBucket returns the name of the bucket which holds the versions of the scene,
+// the default scene uses the kind bucket itself so data written before scenes had
+// IDs is still loaded as the default scene
+func boltBucket(kind []byte, sceneID string) []byte {
+	if sceneID == backend.DefaultSceneID {
+		return kind
+	}
+
+	return []byte(string(kind) + "/" + sceneID)
+}
+
+// boltPut stores the value as a new version in the bucket, creating it if needed
+func boltPut(db *bolt.DB, bucket []byte, value interface{}) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(bucket)
+		if err != nil {
+			return err
+		}
+
+		id, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(&boltRecord{
+			Version: &backend.Version{ID: int(id), CreatedAt: time.Now()},
+			Value:   value,
+		})
+		if err != nil {
+			return err
+		}
+
+		return b.Put(boltKey(int(id)), data)
+	})
+}
+
+// boltGet decodes the value stored with the given version into value,
+// it returns false if there is no such version or no such bucket
+func boltGet(db *bolt.DB, bucket []byte, id int, value interface{}) (bool, error) {
+	found := false
+
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		if b == nil {
+			return nil
+		}
+
+		data := b.Get(boltKey(id))
+		if data == nil {
+			return nil
+		}
+
+		found = true
+		return json.Unmarshal(data, &boltRecord{Value: value})
+	})
+
+	return found, err
+}
+
+// boltLast decodes the latest value in the bucket into value,
+// it returns false if the bucket is empty or does not exist
+func boltLast(db *bolt.DB, bucket []byte, value interface{}) (bool, error) {
+	found := false
+
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		if b == nil {
+			return nil
+		}
+
+		_, data := b.Cursor().Last()
+		if data == nil {
+			return nil
+		}
+
+		found = true
+		return json.Unmarshal(data, &boltRecord{Value: value})
+	})
+
+	return found, err
+}
+
+// boltVersions returns the versions of all values in the bucket, the oldest first
+func boltVersions(db *bolt.DB, bucket []byte) (backend.Versions, error) {
+	result := backend.Versions{}
+
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(_, data []byte) error {
+			record := &boltVersionRecord{}
+			if err := json.Unmarshal(data, record); err != nil {
+				return err
+			}
+
+			result = append(result, record.Version)
+			return nil
+		})
+	})
+
+	return result, err
+}
+
+// boltLastVersion returns the version of the latest value in the bucket,
+// nil if the bucket is empty or does not exist
+func boltLastVersion(db *bolt.DB, bucket []byte) (*backend.Version, error) {
+	var result *backend.Version
+
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		if b == nil {
+			return nil
+		}
+
+		_, data := b.Cursor().Last()
+		if data == nil {
+			return nil
+		}
+
+		record := &boltVersionRecord{}
+		if err := json.Unmarshal(data, record); err != nil {
+			return err
+		}
+
+		result = record.Version
+		return nil
+	})
+
+	return result, err
+}
+
+// boltKey encodes the version id so the keys are sorted numerically
+func boltKey(id int) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(id))
+	return key
+}
@@ -0,0 +1,90 @@
+package persistent
+
+import (
+	"context"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+)
+
+var configsBucket = []byte("configs")
+
+// boltConfigRepository is a concrete implementation of the ConfigRepository which
+// persists every version in an embedded bolt key-value store, in a bucket per scene
+type boltConfigRepository struct {
+	db *bolt.DB
+}
+
+// NewBoltConfigRepository creates new boltConfigRepository over an opened bolt database
+func NewBoltConfigRepository(db *bolt.DB) (backend.ConfigRepository, error) {
+	if err := createBucket(db, configsBucket); err != nil {
+		return nil, err
+	}
+
+	return &boltConfigRepository{db: db}, nil
+}
+
+// Get is used to get the latest config of the scene from the persistence
+func (r *boltConfigRepository) Get(ctx context.Context, sceneID string) (*backend.Config, error) {
+	if err := backend.ContextError(ctx); err != nil {
+		return &backend.Config{}, err
+	}
+
+	config := &backend.Config{}
+	ok, err := boltLast(r.db, boltBucket(configsBucket, sceneID), config)
+	if err != nil {
+		return &backend.Config{}, err
+	}
+
+	if !ok {
+		return nil, nil
+	}
+
+	return config, nil
+}
+
+// Upsert is used for create or update the config, it stores it as a new version of its scene
+func (r *boltConfigRepository) Upsert(ctx context.Context, cfg *backend.Config) (*backend.Config, error) {
+	if err := backend.ContextError(ctx); err != nil {
+		return &backend.Config{}, err
+	}
+
+	if err := backend.ValidateSceneID(cfg.ID); err != nil {
+		return &backend.Config{}, err
+	}
+
+	if err := boltPut(r.db, boltBucket(configsBucket, cfg.ID), cfg); err != nil {
+		return &backend.Config{}, err
+	}
+
+	return cfg, nil
+}
+
+// ListVersions is used to get all stored versions of the scene, the oldest first
+func (r *boltConfigRepository) ListVersions(ctx context.Context, sceneID string) (backend.Versions, error) {
+	if err := backend.ContextError(ctx); err != nil {
+		return backend.Versions{}, err
+	}
+
+	return boltVersions(r.db, boltBucket(configsBucket, sceneID))
+}
+
+// GetVersion is used to get the config of the scene stored with the given version
+func (r *boltConfigRepository) GetVersion(ctx context.Context, sceneID string, version int) (*backend.Config, error) {
+	if err := backend.ContextError(ctx); err != nil {
+		return &backend.Config{}, err
+	}
+
+	config := &backend.Config{}
+	ok, err := boltGet(r.db, boltBucket(configsBucket, sceneID), version, config)
+	if err != nil {
+		return &backend.Config{}, err
+	}
+
+	if !ok {
+		return &backend.Config{}, backend.ErrVersionNotFound
+	}
+
+	return config, nil
+}
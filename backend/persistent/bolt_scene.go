@@ -0,0 +1,99 @@
+package persistent
+
+import (
+	"context"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+)
+
+var scenesBucket = []byte("scenes")
+
+// boltSceneRepository is a concrete implementation of the SceneRepository which
+// persists every version in an embedded bolt key-value store, in a bucket per scene
+type boltSceneRepository struct {
+	db *bolt.DB
+}
+
+// NewBoltSceneRepository creates new boltSceneRepository over an opened bolt database
+func NewBoltSceneRepository(db *bolt.DB) (backend.SceneRepository, error) {
+	if err := createBucket(db, scenesBucket); err != nil {
+		return nil, err
+	}
+
+	return &boltSceneRepository{db: db}, nil
+}
+
+// Get is used to get the latest version of the scene from the persistence
+func (r *boltSceneRepository) Get(ctx context.Context, sceneID string) (*backend.Scene, error) {
+	if err := backend.ContextError(ctx); err != nil {
+		return &backend.Scene{}, err
+	}
+
+	scene := &backend.Scene{}
+	ok, err := boltLast(r.db, boltBucket(scenesBucket, sceneID), scene)
+	if err != nil {
+		return &backend.Scene{}, err
+	}
+
+	if !ok {
+		return nil, nil
+	}
+
+	return scene, nil
+}
+
+// Upsert is used for create or update the scene, it stores it as a new version
+func (r *boltSceneRepository) Upsert(ctx context.Context, scene *backend.Scene) (*backend.Scene, error) {
+	if err := backend.ContextError(ctx); err != nil {
+		return &backend.Scene{}, err
+	}
+
+	if err := backend.ValidateSceneID(scene.ID); err != nil {
+		return &backend.Scene{}, err
+	}
+
+	if err := boltPut(r.db, boltBucket(scenesBucket, scene.ID), scene); err != nil {
+		return &backend.Scene{}, err
+	}
+
+	return scene, nil
+}
+
+// ListVersions is used to get all stored versions of the scene, the oldest first
+func (r *boltSceneRepository) ListVersions(ctx context.Context, sceneID string) (backend.Versions, error) {
+	if err := backend.ContextError(ctx); err != nil {
+		return backend.Versions{}, err
+	}
+
+	return boltVersions(r.db, boltBucket(scenesBucket, sceneID))
+}
+
+// LatestVersion is used to get the version of the latest scene, nil if there is no such scene
+func (r *boltSceneRepository) LatestVersion(ctx context.Context, sceneID string) (*backend.Version, error) {
+	if err := backend.ContextError(ctx); err != nil {
+		return nil, err
+	}
+
+	return boltLastVersion(r.db, boltBucket(scenesBucket, sceneID))
+}
+
+// GetVersion is used to get the scene stored with the given version
+func (r *boltSceneRepository) GetVersion(ctx context.Context, sceneID string, version int) (*backend.Scene, error) {
+	if err := backend.ContextError(ctx); err != nil {
+		return &backend.Scene{}, err
+	}
+
+	scene := &backend.Scene{}
+	ok, err := boltGet(r.db, boltBucket(scenesBucket, sceneID), version, scene)
+	if err != nil {
+		return &backend.Scene{}, err
+	}
+
+	if !ok {
+		return &backend.Scene{}, backend.ErrVersionNotFound
+	}
+
+	return scene, nil
+}
@@ -3,40 +3,81 @@ package persistent
 import (
 	"context"
 	"sync"
+	"time"
 
 	"github.com/iliyanmotovski/raytracer/backend"
 )
 
-// inMemoryConfigRepository is a concrete implementation of the ConfigRepository
-// which persists the data in memory, it is concurrent safe
+// inMemoryConfigRepository is a concrete implementation of the ConfigRepository which
+// persists the data in memory, it is concurrent safe and keeps every version of every scene
 type inMemoryConfigRepository struct {
-	mu     sync.RWMutex
-	config *backend.Config
+	mu       sync.RWMutex
+	configs  map[string][]*backend.Config
+	versions map[string]backend.Versions
 }
 
 func NewInMemoryConfigRepository() backend.ConfigRepository {
-	return &inMemoryConfigRepository{}
+	return &inMemoryConfigRepository{
+		configs:  map[string][]*backend.Config{},
+		versions: map[string]backend.Versions{},
+	}
 }
 
-// Get is used to get the config from the persistence
-func (r *inMemoryConfigRepository) Get(ctx context.Context) (*backend.Config, error) {
-	if err := checkCtx(ctx); err != nil {
+// Get is used to get the latest config of the scene from the persistence
+func (r *inMemoryConfigRepository) Get(ctx context.Context, sceneID string) (*backend.Config, error) {
+	if err := backend.ContextError(ctx); err != nil {
 		return &backend.Config{}, err
 	}
 
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	return r.config, nil
+
+	configs := r.configs[sceneID]
+	if len(configs) == 0 {
+		return nil, nil
+	}
+
+	return configs[len(configs)-1], nil
 }
 
-// Upsert is used for create or update the config
+// Upsert is used for create or update the config, it stores it as a new version of its scene
 func (r *inMemoryConfigRepository) Upsert(ctx context.Context, cfg *backend.Config) (*backend.Config, error) {
-	if err := checkCtx(ctx); err != nil {
+	if err := backend.ContextError(ctx); err != nil {
 		return &backend.Config{}, err
 	}
 
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	r.config = cfg
-	return r.config, nil
+
+	r.configs[cfg.ID] = append(r.configs[cfg.ID], cfg)
+	r.versions[cfg.ID] = append(r.versions[cfg.ID], &backend.Version{ID: len(r.configs[cfg.ID]), CreatedAt: time.Now()})
+	return cfg, nil
+}
+
+// ListVersions is used to get all stored versions of the scene, the oldest first
+func (r *inMemoryConfigRepository) ListVersions(ctx context.Context, sceneID string) (backend.Versions, error) {
+	if err := backend.ContextError(ctx); err != nil {
+		return backend.Versions{}, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append(backend.Versions{}, r.versions[sceneID]...), nil
+}
+
+// GetVersion is used to get the config of the scene stored with the given version
+func (r *inMemoryConfigRepository) GetVersion(ctx context.Context, sceneID string, version int) (*backend.Config, error) {
+	if err := backend.ContextError(ctx); err != nil {
+		return &backend.Config{}, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	configs := r.configs[sceneID]
+	if version < 1 || version > len(configs) {
+		return &backend.Config{}, backend.ErrVersionNotFound
+	}
+
+	return configs[version-1], nil
 }
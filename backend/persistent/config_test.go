@@ -2,6 +2,10 @@ package persistent_test
 
 import (
 	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -12,49 +16,278 @@ import (
 )
 
 func TestInMemoryConfigRepository(t *testing.T) {
-	config := &backend.Config{
-		Light: &vector.Vector{X: 250, Y: 300},
-		Scene: &vector.Vector{X: 800, Y: 500},
-		Polygons: backend.Polygons{
-			{
-				VerticesCount: 3,
-				Loop: vector.Loop{
-					{X: 600, Y: 200},
-					{X: 646, Y: 133},
-					{X: 646, Y: 261},
-				},
-			},
-		},
-	}
+	testConfigRepository(t, persistent.NewInMemoryConfigRepository())
+}
+
+func TestInMemoryConfigRepositoryUpsertWithCancelledContext(t *testing.T) {
+	testConfigRepositoryUpsertWithCancelledContext(t, persistent.NewInMemoryConfigRepository())
+}
+
+func TestInMemoryConfigRepositoryGetWithCancelledContext(t *testing.T) {
+	testConfigRepositoryGetWithCancelledContext(t, persistent.NewInMemoryConfigRepository())
+}
+
+func TestInMemoryConfigRepositoryVersions(t *testing.T) {
+	testConfigRepositoryVersions(t, persistent.NewInMemoryConfigRepository())
+}
+
+func TestInMemoryConfigRepositoryScenes(t *testing.T) {
+	testConfigRepositoryScenes(t, persistent.NewInMemoryConfigRepository())
+}
+
+func TestFileConfigRepository(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	testConfigRepository(t, newFileConfigRepository(t, dir))
+}
+
+func TestFileConfigRepositoryUpsertWithCancelledContext(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	testConfigRepositoryUpsertWithCancelledContext(t, newFileConfigRepository(t, dir))
+}
+
+func TestFileConfigRepositoryGetWithCancelledContext(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	testConfigRepositoryGetWithCancelledContext(t, newFileConfigRepository(t, dir))
+}
+
+func TestFileConfigRepositoryVersions(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	testConfigRepositoryVersions(t, newFileConfigRepository(t, dir))
+}
+
+func TestFileConfigRepositoryScenes(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	testConfigRepositoryScenes(t, newFileConfigRepository(t, dir))
+}
+
+func TestBoltConfigRepository(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	testConfigRepository(t, newBoltConfigRepository(t, dir))
+}
+
+func TestBoltConfigRepositoryUpsertWithCancelledContext(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	testConfigRepositoryUpsertWithCancelledContext(t, newBoltConfigRepository(t, dir))
+}
+
+func TestBoltConfigRepositoryGetWithCancelledContext(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	testConfigRepositoryGetWithCancelledContext(t, newBoltConfigRepository(t, dir))
+}
+
+func TestBoltConfigRepositoryVersions(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	testConfigRepositoryVersions(t, newBoltConfigRepository(t, dir))
+}
+
+func TestBoltConfigRepositoryScenes(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	testConfigRepositoryScenes(t, newBoltConfigRepository(t, dir))
+}
+
+func TestFileConfigRepositoryLoadsOnStartup(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	db := newFileConfigRepository(t, dir)
+	persisted, err := db.Upsert(context.Background(), newConfig())
+	assert.Nil(t, err)
+	assert.Nil(t, db.(io.Closer).Close())
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.tmp"))
+	assert.Empty(t, files)
+
+	db = newFileConfigRepository(t, dir)
+	got, err := db.Get(context.Background(), backend.DefaultSceneID)
+
+	assert.Nil(t, err)
+	assert.Equal(t, persisted, got)
+
+	versions, err := db.ListVersions(context.Background(), backend.DefaultSceneID)
+
+	assert.Nil(t, err)
+	assert.Len(t, versions, 1)
+}
+
+func TestFileConfigRepositoryIsLocked(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	newFileConfigRepository(t, dir)
+
+	_, err := persistent.NewFileConfigRepository(dir)
+	assert.Equal(t, backend.ErrRepositoryLocked, err)
+}
+
+func testConfigRepository(t *testing.T, db backend.ConfigRepository) {
+	config := newConfig()
 
-	db := persistent.NewInMemoryConfigRepository()
 	persisted, err := db.Upsert(context.Background(), config)
 
 	assert.Nil(t, err)
 	assert.Equal(t, config, persisted)
 
-	got, err := db.Get(context.Background())
+	got, err := db.Get(context.Background(), backend.DefaultSceneID)
 
 	assert.Nil(t, err)
 	assert.Equal(t, got, persisted)
 }
 
-func TestInMemoryConfigRepositoryUpsertWithCancelledContext(t *testing.T) {
+func testConfigRepositoryVersions(t *testing.T, db backend.ConfigRepository) {
+	first := newConfig()
+	second := newConfig()
+	second.Light.X = 100
+
+	db.Upsert(context.Background(), first)
+	db.Upsert(context.Background(), second)
+
+	versions, err := db.ListVersions(context.Background(), backend.DefaultSceneID)
+
+	assert.Nil(t, err)
+	assert.Len(t, versions, 2)
+	assert.Equal(t, 1, versions[0].ID)
+	assert.Equal(t, 2, versions[1].ID)
+	assert.False(t, versions[1].CreatedAt.Before(versions[0].CreatedAt))
+
+	got, err := db.GetVersion(context.Background(), backend.DefaultSceneID, 1)
+
+	assert.Nil(t, err)
+	assert.Equal(t, first, got)
+
+	got, err = db.Get(context.Background(), backend.DefaultSceneID)
+
+	assert.Nil(t, err)
+	assert.Equal(t, second, got)
+
+	_, err = db.GetVersion(context.Background(), backend.DefaultSceneID, 3)
+
+	assert.Equal(t, backend.ErrVersionNotFound, err)
+}
+
+func testConfigRepositoryScenes(t *testing.T, db backend.ConfigRepository) {
+	first := newConfig()
+	second := newConfig()
+	second.ID = "second"
+	second.Light.X = 100
+
+	db.Upsert(context.Background(), first)
+	db.Upsert(context.Background(), second)
+	db.Upsert(context.Background(), second)
+
+	got, err := db.Get(context.Background(), backend.DefaultSceneID)
+
+	assert.Nil(t, err)
+	assert.Equal(t, first, got)
+
+	got, err = db.Get(context.Background(), "second")
+
+	assert.Nil(t, err)
+	assert.Equal(t, second, got)
+
+	versions, err := db.ListVersions(context.Background(), "second")
+
+	assert.Nil(t, err)
+	assert.Len(t, versions, 2)
+
+	got, err = db.Get(context.Background(), "missing")
+
+	assert.Nil(t, err)
+	assert.Nil(t, got)
+
+	versions, err = db.ListVersions(context.Background(), "missing")
+
+	assert.Nil(t, err)
+	assert.Empty(t, versions)
+
+	_, err = db.GetVersion(context.Background(), "missing", 1)
+
+	assert.Equal(t, backend.ErrVersionNotFound, err)
+}
+
+func testConfigRepositoryUpsertWithCancelledContext(t *testing.T, db backend.ConfigRepository) {
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel()
 
-	db := persistent.NewInMemoryConfigRepository()
 	_, err := db.Upsert(ctx, &backend.Config{})
 
 	assert.Equal(t, backend.ErrContextCancelled, err)
 }
 
-func TestInMemoryConfigRepositoryGetWithCancelledContext(t *testing.T) {
+func testConfigRepositoryGetWithCancelledContext(t *testing.T, db backend.ConfigRepository) {
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel()
 
-	db := persistent.NewInMemoryConfigRepository()
-	_, err := db.Get(ctx)
+	_, err := db.Get(ctx, backend.DefaultSceneID)
 
 	assert.Equal(t, backend.ErrContextCancelled, err)
 }
+
+func newConfig() *backend.Config {
+	return &backend.Config{
+		ID:    backend.DefaultSceneID,
+		Light: &vector.Vector{X: 250, Y: 300},
+		Scene: &vector.Vector{X: 800, Y: 500},
+		Polygons: backend.Polygons{
+			{
+				VerticesCount: 3,
+				Loop: vector.Loop{
+					{X: 600, Y: 200},
+					{X: 646, Y: 133},
+					{X: 646, Y: 261},
+				},
+			},
+		},
+	}
+}
+
+func newBoltConfigRepository(t *testing.T, dir string) backend.ConfigRepository {
+	boltDB, err := persistent.OpenBoltDB(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := persistent.NewBoltConfigRepository(boltDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func newFileConfigRepository(t *testing.T, dir string) backend.ConfigRepository {
+	db, err := persistent.NewFileConfigRepository(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "raytracer")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return dir
+}
@@ -0,0 +1,152 @@
+package persistent
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+)
+
+// scenesDir is the directory inside the data directory which holds a
+// directory for every scene, except the default one. The default scene
+// is kept in the data directory itself, so data written before scenes
+// had IDs is still loaded as the default scene
+const scenesDir = "scenes"
+
+// writeFileAtomic encodes v as JSON into a temporary file in the same
+// directory and renames it over path, so readers and crashes never
+// observe a partially written file
+func writeFileAtomic(path string, v interface{}) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if err := json.NewEncoder(tmp).Encode(v); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// readFile decodes the JSON file at path into v, it returns
+// false without an error if the file does not exist yet
+func readFile(path string, v interface{}) (bool, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	defer f.Close()
+
+	if err := json.NewDecoder(f).Decode(v); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// sceneDir returns the directory which holds the version files of the scene
+func sceneDir(dir, sceneID string) string {
+	if sceneID == backend.DefaultSceneID {
+		return dir
+	}
+
+	return filepath.Join(dir, scenesDir, sceneID)
+}
+
+// listSceneIDs returns the IDs of all scenes which have a directory, the default one first
+func listSceneIDs(dir string) ([]string, error) {
+	infos, err := ioutil.ReadDir(filepath.Join(dir, scenesDir))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	ids := []string{backend.DefaultSceneID}
+	for _, info := range infos {
+		if info.IsDir() && info.Name() != backend.DefaultSceneID && backend.ValidateSceneID(info.Name()) == nil {
+			ids = append(ids, info.Name())
+		}
+	}
+
+	return ids, nil
+}
+
+// versionFilePath returns the path of the file holding the given version
+func versionFilePath(dir, kind string, id int) string {
+	return filepath.Join(dir, fmt.Sprintf("%s-%d.json", kind, id))
+}
+
+// listVersionFiles returns the ids of all version files of the given kind, sorted
+func listVersionFiles(dir, kind string) ([]int, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, kind+"-*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []int{}
+	for _, path := range paths {
+		name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), kind+"-"), ".json")
+
+		id, err := strconv.Atoi(name)
+		if err != nil {
+			continue
+		}
+
+		ids = append(ids, id)
+	}
+
+	sort.Ints(ids)
+	return ids, nil
+}
+
+// openLocked creates the data directory if needed and takes an exclusive
+// lock on the given lock file, so no other process uses the same data
+func openLocked(dir, name string) (*os.File, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, name), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// closeLocked releases the lock and closes the lock file
+func closeLocked(f *os.File) error {
+	if err := unlockFile(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
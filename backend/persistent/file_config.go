@@ -0,0 +1,182 @@
+package persistent
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+)
+
+// fileConfigRepository is a concrete implementation of the ConfigRepository
+// which persists every version as a JSON file in a directory of its scene, it
+// is concurrent safe and holds a file lock on the data directory until it is closed
+type fileConfigRepository struct {
+	mu      sync.RWMutex
+	dir     string
+	lock    *os.File
+	configs map[string]*fileConfigHistory
+}
+
+// fileConfigHistory holds the latest config and all versions of a single scene
+type fileConfigHistory struct {
+	config   *backend.Config
+	versions backend.Versions
+}
+
+// configRecord is the content of a single version file
+type configRecord struct {
+	Version *backend.Version
+	Config  *backend.Config
+}
+
+// NewFileConfigRepository creates new fileConfigRepository in the given
+// directory and loads the versions of all scenes persisted there, if any
+func NewFileConfigRepository(dir string) (backend.ConfigRepository, error) {
+	lock, err := openLocked(dir, "config.lock")
+	if err != nil {
+		return nil, err
+	}
+
+	r := &fileConfigRepository{dir: dir, lock: lock, configs: map[string]*fileConfigHistory{}}
+
+	sceneIDs, err := listSceneIDs(dir)
+	if err != nil {
+		closeLocked(lock)
+		return nil, err
+	}
+
+	for _, sceneID := range sceneIDs {
+		ids, err := listVersionFiles(sceneDir(dir, sceneID), "config")
+		if err != nil {
+			closeLocked(lock)
+			return nil, err
+		}
+
+		for _, id := range ids {
+			record := &configRecord{}
+			if _, err := readFile(versionFilePath(sceneDir(dir, sceneID), "config", id), record); err != nil {
+				closeLocked(lock)
+				return nil, err
+			}
+
+			history := r.history(sceneID)
+			history.versions = append(history.versions, record.Version)
+			history.config = record.Config
+		}
+	}
+
+	return r, nil
+}
+
+// Get is used to get the latest config of the scene from the persistence
+func (r *fileConfigRepository) Get(ctx context.Context, sceneID string) (*backend.Config, error) {
+	if err := backend.ContextError(ctx); err != nil {
+		return &backend.Config{}, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	history, ok := r.configs[sceneID]
+	if !ok {
+		return nil, nil
+	}
+
+	return history.config, nil
+}
+
+// Upsert is used for create or update the config, it is stored as a
+// new version file in the directory of its scene which is written atomically
+func (r *fileConfigRepository) Upsert(ctx context.Context, cfg *backend.Config) (*backend.Config, error) {
+	if err := backend.ContextError(ctx); err != nil {
+		return &backend.Config{}, err
+	}
+
+	if err := backend.ValidateSceneID(cfg.ID); err != nil {
+		return &backend.Config{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	dir := sceneDir(r.dir, cfg.ID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return &backend.Config{}, err
+	}
+
+	history := r.history(cfg.ID)
+	version := &backend.Version{ID: len(history.versions) + 1, CreatedAt: time.Now()}
+	record := &configRecord{Version: version, Config: cfg}
+
+	if err := writeFileAtomic(versionFilePath(dir, "config", version.ID), record); err != nil {
+		return &backend.Config{}, err
+	}
+
+	history.versions = append(history.versions, version)
+	history.config = cfg
+	return history.config, nil
+}
+
+// ListVersions is used to get all stored versions of the scene, the oldest first
+func (r *fileConfigRepository) ListVersions(ctx context.Context, sceneID string) (backend.Versions, error) {
+	if err := backend.ContextError(ctx); err != nil {
+		return backend.Versions{}, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	history, ok := r.configs[sceneID]
+	if !ok {
+		return backend.Versions{}, nil
+	}
+
+	return append(backend.Versions{}, history.versions...), nil
+}
+
+// GetVersion is used to get the config of the scene stored with the given version
+func (r *fileConfigRepository) GetVersion(ctx context.Context, sceneID string, version int) (*backend.Config, error) {
+	if err := backend.ContextError(ctx); err != nil {
+		return &backend.Config{}, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.configs[sceneID]; !ok {
+		return &backend.Config{}, backend.ErrVersionNotFound
+	}
+
+	record := &configRecord{}
+	ok, err := readFile(versionFilePath(sceneDir(r.dir, sceneID), "config", version), record)
+	if err != nil {
+		return &backend.Config{}, err
+	}
+
+	if !ok {
+		return &backend.Config{}, backend.ErrVersionNotFound
+	}
+
+	return record.Config, nil
+}
+
+// Close releases the lock on the data directory
+func (r *fileConfigRepository) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return closeLocked(r.lock)
+}
+
+// history returns the history of the scene, creating it if needed,
+// it must be called with the lock held for writing
+func (r *fileConfigRepository) history(sceneID string) *fileConfigHistory {
+	history, ok := r.configs[sceneID]
+	if !ok {
+		history = &fileConfigHistory{versions: backend.Versions{}}
+		r.configs[sceneID] = history
+	}
+
+	return history
+}
@@ -0,0 +1,199 @@
+package persistent
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+)
+
+// fileSceneRepository is a concrete implementation of the SceneRepository
+// which persists every version as a JSON file in a directory of its scene, it
+// is concurrent safe and holds a file lock on the data directory until it is closed
+type fileSceneRepository struct {
+	mu     sync.RWMutex
+	dir    string
+	lock   *os.File
+	scenes map[string]*fileSceneHistory
+}
+
+// fileSceneHistory holds the latest scene and all versions of a single scene
+type fileSceneHistory struct {
+	scene    *backend.Scene
+	versions backend.Versions
+}
+
+// sceneRecord is the content of a single version file
+type sceneRecord struct {
+	Version *backend.Version
+	Scene   *backend.Scene
+}
+
+// NewFileSceneRepository creates new fileSceneRepository in the given
+// directory and loads the versions of all scenes persisted there, if any
+func NewFileSceneRepository(dir string) (backend.SceneRepository, error) {
+	lock, err := openLocked(dir, "scene.lock")
+	if err != nil {
+		return nil, err
+	}
+
+	r := &fileSceneRepository{dir: dir, lock: lock, scenes: map[string]*fileSceneHistory{}}
+
+	sceneIDs, err := listSceneIDs(dir)
+	if err != nil {
+		closeLocked(lock)
+		return nil, err
+	}
+
+	for _, sceneID := range sceneIDs {
+		ids, err := listVersionFiles(sceneDir(dir, sceneID), "scene")
+		if err != nil {
+			closeLocked(lock)
+			return nil, err
+		}
+
+		for _, id := range ids {
+			record := &sceneRecord{}
+			if _, err := readFile(versionFilePath(sceneDir(dir, sceneID), "scene", id), record); err != nil {
+				closeLocked(lock)
+				return nil, err
+			}
+
+			history := r.history(sceneID)
+			history.versions = append(history.versions, record.Version)
+			history.scene = record.Scene
+		}
+	}
+
+	return r, nil
+}
+
+// Get is used to get the latest version of the scene from the persistence
+func (r *fileSceneRepository) Get(ctx context.Context, sceneID string) (*backend.Scene, error) {
+	if err := backend.ContextError(ctx); err != nil {
+		return &backend.Scene{}, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	history, ok := r.scenes[sceneID]
+	if !ok {
+		return nil, nil
+	}
+
+	return history.scene, nil
+}
+
+// Upsert is used for create or update the scene, it is stored as a
+// new version file in the directory of its scene which is written atomically
+func (r *fileSceneRepository) Upsert(ctx context.Context, scene *backend.Scene) (*backend.Scene, error) {
+	if err := backend.ContextError(ctx); err != nil {
+		return &backend.Scene{}, err
+	}
+
+	if err := backend.ValidateSceneID(scene.ID); err != nil {
+		return &backend.Scene{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	dir := sceneDir(r.dir, scene.ID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return &backend.Scene{}, err
+	}
+
+	history := r.history(scene.ID)
+	version := &backend.Version{ID: len(history.versions) + 1, CreatedAt: time.Now()}
+	record := &sceneRecord{Version: version, Scene: scene}
+
+	if err := writeFileAtomic(versionFilePath(dir, "scene", version.ID), record); err != nil {
+		return &backend.Scene{}, err
+	}
+
+	history.versions = append(history.versions, version)
+	history.scene = scene
+	return history.scene, nil
+}
+
+// ListVersions is used to get all stored versions of the scene, the oldest first
+func (r *fileSceneRepository) ListVersions(ctx context.Context, sceneID string) (backend.Versions, error) {
+	if err := backend.ContextError(ctx); err != nil {
+		return backend.Versions{}, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	history, ok := r.scenes[sceneID]
+	if !ok {
+		return backend.Versions{}, nil
+	}
+
+	return append(backend.Versions{}, history.versions...), nil
+}
+
+// LatestVersion is used to get the version of the latest scene, nil if there is no such scene
+func (r *fileSceneRepository) LatestVersion(ctx context.Context, sceneID string) (*backend.Version, error) {
+	if err := backend.ContextError(ctx); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	history, ok := r.scenes[sceneID]
+	if !ok || len(history.versions) == 0 {
+		return nil, nil
+	}
+
+	return history.versions[len(history.versions)-1], nil
+}
+
+// GetVersion is used to get the scene stored with the given version
+func (r *fileSceneRepository) GetVersion(ctx context.Context, sceneID string, version int) (*backend.Scene, error) {
+	if err := backend.ContextError(ctx); err != nil {
+		return &backend.Scene{}, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.scenes[sceneID]; !ok {
+		return &backend.Scene{}, backend.ErrVersionNotFound
+	}
+
+	record := &sceneRecord{}
+	ok, err := readFile(versionFilePath(sceneDir(r.dir, sceneID), "scene", version), record)
+	if err != nil {
+		return &backend.Scene{}, err
+	}
+
+	if !ok {
+		return &backend.Scene{}, backend.ErrVersionNotFound
+	}
+
+	return record.Scene, nil
+}
+
+// Close releases the lock on the data directory
+func (r *fileSceneRepository) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return closeLocked(r.lock)
+}
+
+// history returns the history of the scene, creating it if needed,
+// it must be called with the lock held for writing
+func (r *fileSceneRepository) history(sceneID string) *fileSceneHistory {
+	history, ok := r.scenes[sceneID]
+	if !ok {
+		history = &fileSceneHistory{versions: backend.Versions{}}
+		r.scenes[sceneID] = history
+	}
+
+	return history
+}
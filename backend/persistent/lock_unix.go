@@ -0,0 +1,28 @@
+//go:build !windows
+// +build !windows
+
+package persistent
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+)
+
+// lockFile takes an exclusive non blocking lock on the file
+func lockFile(f *os.File) error {
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		if err == syscall.EWOULDBLOCK {
+			return backend.ErrRepositoryLocked
+		}
+		return err
+	}
+
+	return nil
+}
+
+// unlockFile releases the lock taken by lockFile
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,12 @@
+//go:build windows
+// +build windows
+
+package persistent
+
+import "os"
+
+// lockFile is a no-op on windows, the repository relies on a single process using the data directory
+func lockFile(f *os.File) error { return nil }
+
+// unlockFile is a no-op on windows
+func unlockFile(f *os.File) error { return nil }
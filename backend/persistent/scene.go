@@ -3,40 +3,98 @@ package persistent
 import (
 	"context"
 	"sync"
+	"time"
 
 	"github.com/iliyanmotovski/raytracer/backend"
 )
 
-// inMemorySceneRepository is a concrete implementation of the SceneRepository
-// which persists the data in memory, it is concurrent safe
+// inMemorySceneRepository is a concrete implementation of the SceneRepository which
+// persists the data in memory, it is concurrent safe and keeps every version of every scene
 type inMemorySceneRepository struct {
-	mu    sync.RWMutex
-	scene *backend.Scene
+	mu       sync.RWMutex
+	scenes   map[string][]*backend.Scene
+	versions map[string]backend.Versions
 }
 
 func NewInMemorySceneRepository() backend.SceneRepository {
-	return &inMemorySceneRepository{}
+	return &inMemorySceneRepository{
+		scenes:   map[string][]*backend.Scene{},
+		versions: map[string]backend.Versions{},
+	}
 }
 
-// Get is used to get the scene from the persistence
-func (r *inMemorySceneRepository) Get(ctx context.Context) (*backend.Scene, error) {
-	if err := checkCtx(ctx); err != nil {
+// Get is used to get the latest version of the scene from the persistence
+func (r *inMemorySceneRepository) Get(ctx context.Context, sceneID string) (*backend.Scene, error) {
+	if err := backend.ContextError(ctx); err != nil {
 		return &backend.Scene{}, err
 	}
 
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	return r.scene, nil
+
+	scenes := r.scenes[sceneID]
+	if len(scenes) == 0 {
+		return nil, nil
+	}
+
+	return scenes[len(scenes)-1], nil
 }
 
-// Upsert is used for create or update the scene
+// Upsert is used for create or update the scene, it stores it as a new version
 func (r *inMemorySceneRepository) Upsert(ctx context.Context, scene *backend.Scene) (*backend.Scene, error) {
-	if err := checkCtx(ctx); err != nil {
+	if err := backend.ContextError(ctx); err != nil {
 		return &backend.Scene{}, err
 	}
 
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	r.scene = scene
-	return r.scene, nil
+
+	r.scenes[scene.ID] = append(r.scenes[scene.ID], scene)
+	r.versions[scene.ID] = append(r.versions[scene.ID], &backend.Version{ID: len(r.scenes[scene.ID]), CreatedAt: time.Now()})
+	return scene, nil
+}
+
+// ListVersions is used to get all stored versions of the scene, the oldest first
+func (r *inMemorySceneRepository) ListVersions(ctx context.Context, sceneID string) (backend.Versions, error) {
+	if err := backend.ContextError(ctx); err != nil {
+		return backend.Versions{}, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append(backend.Versions{}, r.versions[sceneID]...), nil
+}
+
+// LatestVersion is used to get the version of the latest scene, nil if there is no such scene
+func (r *inMemorySceneRepository) LatestVersion(ctx context.Context, sceneID string) (*backend.Version, error) {
+	if err := backend.ContextError(ctx); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	versions := r.versions[sceneID]
+	if len(versions) == 0 {
+		return nil, nil
+	}
+
+	return versions[len(versions)-1], nil
+}
+
+// GetVersion is used to get the scene stored with the given version
+func (r *inMemorySceneRepository) GetVersion(ctx context.Context, sceneID string, version int) (*backend.Scene, error) {
+	if err := backend.ContextError(ctx); err != nil {
+		return &backend.Scene{}, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	scenes := r.scenes[sceneID]
+	if version < 1 || version > len(scenes) {
+		return &backend.Scene{}, backend.ErrVersionNotFound
+	}
+
+	return scenes[version-1], nil
 }
@@ -2,6 +2,8 @@ package persistent_test
 
 import (
 	"context"
+	"io"
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -12,7 +14,255 @@ import (
 )
 
 func TestInMemorySceneRepository(t *testing.T) {
-	scene := &backend.Scene{
+	testSceneRepository(t, persistent.NewInMemorySceneRepository())
+}
+
+func TestInMemorySceneRepositoryUpsertWithCancelledContext(t *testing.T) {
+	testSceneRepositoryUpsertWithCancelledContext(t, persistent.NewInMemorySceneRepository())
+}
+
+func TestInMemorySceneRepositoryGetWithCancelledContext(t *testing.T) {
+	testSceneRepositoryGetWithCancelledContext(t, persistent.NewInMemorySceneRepository())
+}
+
+func TestInMemorySceneRepositoryVersions(t *testing.T) {
+	testSceneRepositoryVersions(t, persistent.NewInMemorySceneRepository())
+}
+
+func TestInMemorySceneRepositoryScenes(t *testing.T) {
+	testSceneRepositoryScenes(t, persistent.NewInMemorySceneRepository())
+}
+
+func TestFileSceneRepository(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	testSceneRepository(t, newFileSceneRepository(t, dir))
+}
+
+func TestFileSceneRepositoryUpsertWithCancelledContext(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	testSceneRepositoryUpsertWithCancelledContext(t, newFileSceneRepository(t, dir))
+}
+
+func TestFileSceneRepositoryGetWithCancelledContext(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	testSceneRepositoryGetWithCancelledContext(t, newFileSceneRepository(t, dir))
+}
+
+func TestFileSceneRepositoryVersions(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	testSceneRepositoryVersions(t, newFileSceneRepository(t, dir))
+}
+
+func TestFileSceneRepositoryScenes(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	testSceneRepositoryScenes(t, newFileSceneRepository(t, dir))
+}
+
+func TestBoltSceneRepository(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	testSceneRepository(t, newBoltSceneRepository(t, dir))
+}
+
+func TestBoltSceneRepositoryUpsertWithCancelledContext(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	testSceneRepositoryUpsertWithCancelledContext(t, newBoltSceneRepository(t, dir))
+}
+
+func TestBoltSceneRepositoryGetWithCancelledContext(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	testSceneRepositoryGetWithCancelledContext(t, newBoltSceneRepository(t, dir))
+}
+
+func TestBoltSceneRepositoryVersions(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	testSceneRepositoryVersions(t, newBoltSceneRepository(t, dir))
+}
+
+func TestBoltSceneRepositoryScenes(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	testSceneRepositoryScenes(t, newBoltSceneRepository(t, dir))
+}
+
+func TestFileSceneRepositoryLoadsOnStartup(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	second := newScene()
+	second.ID = "second"
+
+	db := newFileSceneRepository(t, dir)
+	persisted, err := db.Upsert(context.Background(), newScene())
+	assert.Nil(t, err)
+	persistedSecond, err := db.Upsert(context.Background(), second)
+	assert.Nil(t, err)
+	assert.Nil(t, db.(io.Closer).Close())
+
+	db = newFileSceneRepository(t, dir)
+	got, err := db.Get(context.Background(), backend.DefaultSceneID)
+
+	assert.Nil(t, err)
+	assert.Equal(t, persisted, got)
+
+	got, err = db.Get(context.Background(), "second")
+
+	assert.Nil(t, err)
+	assert.Equal(t, persistedSecond, got)
+
+	versions, err := db.ListVersions(context.Background(), backend.DefaultSceneID)
+
+	assert.Nil(t, err)
+	assert.Len(t, versions, 1)
+}
+
+func TestFileSceneRepositoryWithInvalidSceneID(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	scene := newScene()
+	scene.ID = "../outside"
+
+	_, err := newFileSceneRepository(t, dir).Upsert(context.Background(), scene)
+
+	assert.Equal(t, backend.ErrInvalidSceneID, err)
+}
+
+func testSceneRepository(t *testing.T, db backend.SceneRepository) {
+	scene := newScene()
+
+	persisted, err := db.Upsert(context.Background(), scene)
+
+	assert.Nil(t, err)
+	assert.Equal(t, scene, persisted)
+
+	got, err := db.Get(context.Background(), backend.DefaultSceneID)
+
+	assert.Nil(t, err)
+	assert.Equal(t, got, persisted)
+}
+
+func testSceneRepositoryVersions(t *testing.T, db backend.SceneRepository) {
+	first := newScene()
+	second := newScene()
+	second.Light.X = 100
+
+	db.Upsert(context.Background(), first)
+	db.Upsert(context.Background(), second)
+
+	versions, err := db.ListVersions(context.Background(), backend.DefaultSceneID)
+
+	assert.Nil(t, err)
+	assert.Len(t, versions, 2)
+	assert.Equal(t, 1, versions[0].ID)
+	assert.Equal(t, 2, versions[1].ID)
+	assert.False(t, versions[1].CreatedAt.Before(versions[0].CreatedAt))
+
+	latest, err := db.LatestVersion(context.Background(), backend.DefaultSceneID)
+
+	assert.Nil(t, err)
+	assert.Equal(t, versions[1].ID, latest.ID)
+	assert.True(t, versions[1].CreatedAt.Equal(latest.CreatedAt))
+
+	got, err := db.GetVersion(context.Background(), backend.DefaultSceneID, 1)
+
+	assert.Nil(t, err)
+	assert.Equal(t, first, got)
+
+	got, err = db.Get(context.Background(), backend.DefaultSceneID)
+
+	assert.Nil(t, err)
+	assert.Equal(t, second, got)
+
+	_, err = db.GetVersion(context.Background(), backend.DefaultSceneID, 3)
+
+	assert.Equal(t, backend.ErrVersionNotFound, err)
+}
+
+func testSceneRepositoryScenes(t *testing.T, db backend.SceneRepository) {
+	first := newScene()
+	second := newScene()
+	second.ID = "second"
+	second.Light.X = 100
+
+	db.Upsert(context.Background(), first)
+	db.Upsert(context.Background(), second)
+	db.Upsert(context.Background(), second)
+
+	got, err := db.Get(context.Background(), backend.DefaultSceneID)
+
+	assert.Nil(t, err)
+	assert.Equal(t, first, got)
+
+	got, err = db.Get(context.Background(), "second")
+
+	assert.Nil(t, err)
+	assert.Equal(t, second, got)
+
+	versions, err := db.ListVersions(context.Background(), "second")
+
+	assert.Nil(t, err)
+	assert.Len(t, versions, 2)
+
+	got, err = db.Get(context.Background(), "missing")
+
+	assert.Nil(t, err)
+	assert.Nil(t, got)
+
+	versions, err = db.ListVersions(context.Background(), "missing")
+
+	assert.Nil(t, err)
+	assert.Empty(t, versions)
+
+	latest, err := db.LatestVersion(context.Background(), "missing")
+
+	assert.Nil(t, err)
+	assert.Nil(t, latest)
+
+	_, err = db.GetVersion(context.Background(), "missing", 1)
+
+	assert.Equal(t, backend.ErrVersionNotFound, err)
+}
+
+func testSceneRepositoryUpsertWithCancelledContext(t *testing.T, db backend.SceneRepository) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := db.Upsert(ctx, &backend.Scene{})
+
+	assert.Equal(t, backend.ErrContextCancelled, err)
+}
+
+func testSceneRepositoryGetWithCancelledContext(t *testing.T, db backend.SceneRepository) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := db.Get(ctx, backend.DefaultSceneID)
+
+	assert.Equal(t, backend.ErrContextCancelled, err)
+}
+
+func newScene() *backend.Scene {
+	return &backend.Scene{
+		ID:      backend.DefaultSceneID,
 		Width:   800,
 		Height:  500,
 		LitArea: 60,
@@ -40,35 +290,27 @@ func TestInMemorySceneRepository(t *testing.T) {
 			},
 		},
 	}
-
-	db := persistent.NewInMemorySceneRepository()
-	persisted, err := db.Upsert(context.Background(), scene)
-
-	assert.Nil(t, err)
-	assert.Equal(t, scene, persisted)
-
-	got, err := db.Get(context.Background())
-
-	assert.Nil(t, err)
-	assert.Equal(t, got, persisted)
 }
 
-func TestInMemorySceneRepositoryUpsertWithCancelledContext(t *testing.T) {
-	ctx, cancel := context.WithCancel(context.Background())
-	cancel()
+func newBoltSceneRepository(t *testing.T, dir string) backend.SceneRepository {
+	boltDB, err := persistent.OpenBoltDB(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	db := persistent.NewInMemorySceneRepository()
-	_, err := db.Upsert(ctx, &backend.Scene{})
+	db, err := persistent.NewBoltSceneRepository(boltDB)
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	assert.Equal(t, backend.ErrContextCancelled, err)
+	return db
 }
 
-func TestInMemorySceneRepositoryGetWithCancelledContext(t *testing.T) {
-	ctx, cancel := context.WithCancel(context.Background())
-	cancel()
-
-	db := persistent.NewInMemorySceneRepository()
-	_, err := db.Get(ctx)
+func newFileSceneRepository(t *testing.T, dir string) backend.SceneRepository {
+	db, err := persistent.NewFileSceneRepository(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	assert.Equal(t, backend.ErrContextCancelled, err)
+	return db
 }
@@ -1,8 +1,7 @@
 package backend
 
 import (
-	"errors"
-	"fmt"
+	"context"
 	"math"
 
 	"github.com/iliyanmotovski/raytracer/backend/vector"
@@ -13,6 +12,59 @@ import (
 type Polygon struct {
 	Loop          vector.Loop
 	VerticesCount int
+	// Controls holds the control points of the side starting at the vertice with
+	// the same index - none for a straight side, 1 for a quadratic and 2 for a
+	// cubic Bezier curve. It is nil when all sides are straight
+	Controls []vector.Vectors `json:",omitempty"`
+	// flattened is set on the result of Flatten for a curved polygon, which
+	// unlike a polygon with straight sides only is allowed to be concave
+	flattened bool
+}
+
+// IsCurved returns whether some of the polygon sides is a Bezier curve
+func (p *Polygon) IsCurved() bool {
+	for _, controls := range p.Controls {
+		if len(controls) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Curve returns the side starting at the vertice with the given index
+// as a Bezier curve and false if the side is straight
+func (p *Polygon) Curve(i int) (*Bezier, bool) {
+	if i >= len(p.Controls) || len(p.Controls[i]) == 0 {
+		return nil, false
+	}
+
+	points := vector.Vectors{p.Loop[i]}
+	points = append(points, p.Controls[i]...)
+	points = append(points, p.Loop[(i+1)%len(p.Loop)])
+
+	return &Bezier{Points: points}, true
+}
+
+// Flatten returns a polygon with straight sides only which approximates the curved
+// sides within the tolerance. The silhouette points of the curves, as seen from
+// the light, are always vertices of the result so the shadow edges are exact
+func (p *Polygon) Flatten(light *vector.Vector, tolerance float64) *Polygon {
+	if !p.IsCurved() {
+		return p
+	}
+
+	result := &Polygon{Loop: vector.Loop{}, flattened: true}
+	for i, vertex := range p.Loop {
+		result.Loop = append(result.Loop, vertex)
+
+		if curve, ok := p.Curve(i); ok {
+			result.Loop = append(result.Loop, curve.Flatten(tolerance, curve.SilhouetteParams(light))...)
+		}
+	}
+
+	result.VerticesCount = len(result.Loop)
+	return result
 }
 
 // GetBoundaries returns all boundaries (sides) of the polygon
@@ -77,12 +129,36 @@ func (p *Polygon) IsConvex() bool {
 	return true
 }
 
+// IsSimple checks that no side of the Polygon crosses another one,
+// the neighbouring sides which only share a vertice are not crossing
+func (p *Polygon) IsSimple() bool {
+	sides := p.GetBoundaries()
+
+	for i := range sides {
+		for j := i + 2; j < len(sides); j++ {
+			// the last side is a neighbour of the first one
+			if i == 0 && j == len(sides)-1 {
+				continue
+			}
+
+			if sides[i].Crosses(&sides[j].Edge) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
 type Polygons []*Polygon
 
 // Validate checks all polygons and returns whether they intersect
 // or some has a point which is outside of the scene, it also checks
-// if there is a non-convex polygon
-func (ps Polygons) Validate(width, height float64) error {
+// if there is a non-convex polygon with straight sides only. A curved
+// polygon, which is validated flattened, may be concave as long as
+// its sides do not cross each other. It stops with ErrContextCancelled
+// or ErrContextExpired as soon as the context is done
+func (ps Polygons) Validate(ctx context.Context, width, height float64) error {
 	vertices := ps.getAllVertices()
 
 	scene := &Polygon{Loop: vector.Loop{
@@ -92,12 +168,22 @@ func (ps Polygons) Validate(width, height float64) error {
 		{X: 0, Y: height},
 	}}
 
-	polygons := ps[0:len(ps)]
+	// a new slice, so the scene is never appended to the backing array of the caller
+	polygons := make(Polygons, 0, len(ps)+1)
+	polygons = append(polygons, ps...)
 	polygons = append(polygons, scene)
 
 	for i, polygon := range polygons {
-		if !polygon.IsConvex() {
-			return errors.New("polygon is not convex")
+		if err := ContextError(ctx); err != nil {
+			return err
+		}
+
+		if polygon.flattened {
+			if !polygon.IsSimple() {
+				return invalid("polygon_self_crossing", "polygon sides cross each other")
+			}
+		} else if !polygon.IsConvex() {
+			return invalid("polygon_not_convex", "polygon is not convex")
 		}
 
 		for _, vertice := range vertices {
@@ -105,11 +191,11 @@ func (ps Polygons) Validate(width, height float64) error {
 			contained := polygon.IsPointContainedInPolygon(vertice)
 
 			if i == l && !contained {
-				return fmt.Errorf("point X: %v , Y: %v is outside the scene", vertice.X, vertice.Y)
+				return invalid("polygon_outside_scene", "point X: %v , Y: %v is outside the scene", vertice.X, vertice.Y)
 			}
 
 			if i != l && contained && !polygon.ContainsVertice(vertice) {
-				return fmt.Errorf("point X: %v , Y: %v is inside another polygon", vertice.X, vertice.Y)
+				return invalid("polygon_inside_polygon", "point X: %v , Y: %v is inside another polygon", vertice.X, vertice.Y)
 			}
 		}
 	}
@@ -117,6 +203,28 @@ func (ps Polygons) Validate(width, height float64) error {
 	return nil
 }
 
+// Flatten returns the polygons with all curved sides flattened, see Polygon.Flatten
+func (ps Polygons) Flatten(light *vector.Vector, tolerance float64) Polygons {
+	result := make(Polygons, len(ps))
+
+	for i, polygon := range ps {
+		result[i] = polygon.Flatten(light, tolerance)
+	}
+
+	return result
+}
+
+// IsCurved returns whether some of the polygons has a curved side
+func (ps Polygons) IsCurved() bool {
+	for _, polygon := range ps {
+		if polygon.IsCurved() {
+			return true
+		}
+	}
+
+	return false
+}
+
 // getAllVertices returns all vertices of all polygons in an array
 func (ps Polygons) getAllVertices() vector.Vectors {
 	result := vector.Vectors{}
@@ -130,18 +238,26 @@ func (ps Polygons) getAllVertices() vector.Vectors {
 	return result
 }
 
-// Triangle extends Polygon
+// Triangle extends Polygon, when Arc is set the side
+// opposite to the first vertice is an arc of an Ellipse
 type Triangle struct {
 	Polygon
+	Arc *Arc `json:",omitempty"`
 }
 
-// Area returns the area of the triangle
+// Area returns the area of the triangle, without the
+// segment cut off by the arc if there is such
 func (t *Triangle) Area() float64 {
 	a := t.Loop[0]
 	b := t.Loop[1]
 	c := t.Loop[2]
 
-	return math.Abs((a.X*(b.Y-c.Y) + b.X*(c.Y-a.Y) + c.X*(a.Y-b.Y)) / 2)
+	area := math.Abs((a.X*(b.Y-c.Y) + b.X*(c.Y-a.Y) + c.X*(a.Y-b.Y)) / 2)
+	if t.Arc != nil {
+		area -= t.Arc.SegmentArea()
+	}
+
+	return area
 }
 
 type Triangles []*Triangle
@@ -161,7 +277,7 @@ func NewClockwiseTriangleFan(center *vector.Vector, edges vector.Loop) Triangles
 			next = edges[i+1]
 		}
 
-		result = append(result, &Triangle{Polygon{
+		result = append(result, &Triangle{Polygon: Polygon{
 			Loop: vector.Loop{
 				{X: center.X, Y: center.Y},
 				{X: edge.X, Y: edge.Y},
@@ -1,7 +1,7 @@
 package backend_test
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"testing"
 
@@ -153,10 +153,22 @@ func TestValidatePolygons(t *testing.T) {
 		},
 	}
 
-	got := polygons.Validate(800, 500)
+	got := polygons.Validate(context.Background(), 800, 500)
 	assert.Nil(t, got)
 }
 
+func TestValidatePolygonsLeavesBackingArrayUntouched(t *testing.T) {
+	triangle := &backend.Polygon{VerticesCount: 3, Loop: vector.Loop{{X: 600, Y: 200}, {X: 646, Y: 133}, {X: 646, Y: 261}}}
+
+	// the spare capacity must not be used for the scene polygon
+	polygons := make(backend.Polygons, 1, 2)
+	polygons[0] = triangle
+
+	assert.Nil(t, polygons.Validate(context.Background(), 800, 500))
+	assert.Equal(t, backend.Polygons{triangle}, polygons)
+	assert.Nil(t, polygons[:2][1])
+}
+
 func TestValidatePolygonsWithPointOutsideOfTheScene(t *testing.T) {
 	polygons := backend.Polygons{
 		{
@@ -169,8 +181,8 @@ func TestValidatePolygonsWithPointOutsideOfTheScene(t *testing.T) {
 		},
 	}
 
-	got := polygons.Validate(800, 500)
-	want := errors.New("point X: 850 , Y: 550 is outside the scene")
+	got := polygons.Validate(context.Background(), 800, 500)
+	want := &backend.ValidationError{Reason: "polygon_outside_scene", Message: "point X: 850 , Y: 550 is outside the scene"}
 	assert.Equal(t, want, got)
 }
 
@@ -189,8 +201,49 @@ func TestValidateNonConvexPolygons(t *testing.T) {
 		},
 	}
 
-	got := polygons.Validate(800, 500)
-	want := errors.New("polygon is not convex")
+	got := polygons.Validate(context.Background(), 800, 500)
+	want := &backend.ValidationError{Reason: "polygon_not_convex", Message: "polygon is not convex"}
+	assert.Equal(t, want, got)
+}
+
+func TestValidateConcaveCurvedPolygons(t *testing.T) {
+	// the top side of the square bends inward
+	polygons := backend.Polygons{
+		{
+			VerticesCount: 4,
+			Loop:          vector.Loop{{X: 100, Y: 100}, {X: 300, Y: 100}, {X: 300, Y: 300}, {X: 100, Y: 300}},
+			Controls:      []vector.Vectors{{{X: 200, Y: 200}}},
+		},
+	}
+
+	flattened := polygons.Flatten(&vector.Vector{X: 200, Y: 20}, backend.DefaultFlatteningTolerance)
+	assert.False(t, flattened[0].IsConvex())
+	assert.Nil(t, flattened.Validate(context.Background(), 800, 500))
+
+	// the same shape with straight sides only is still rejected
+	straight := backend.Polygons{
+		{
+			VerticesCount: 5,
+			Loop:          vector.Loop{{X: 100, Y: 100}, {X: 200, Y: 150}, {X: 300, Y: 100}, {X: 300, Y: 300}, {X: 100, Y: 300}},
+		},
+	}
+
+	want := &backend.ValidationError{Reason: "polygon_not_convex", Message: "polygon is not convex"}
+	assert.Equal(t, want, straight.Validate(context.Background(), 800, 500))
+}
+
+func TestValidateSelfCrossingCurvedPolygons(t *testing.T) {
+	// the top side of the square bends through its bottom side
+	polygons := backend.Polygons{
+		{
+			VerticesCount: 4,
+			Loop:          vector.Loop{{X: 100, Y: 100}, {X: 300, Y: 100}, {X: 300, Y: 300}, {X: 100, Y: 300}},
+			Controls:      []vector.Vectors{{{X: 200, Y: 600}}},
+		},
+	}
+
+	got := polygons.Flatten(&vector.Vector{X: 200, Y: 20}, backend.DefaultFlatteningTolerance).Validate(context.Background(), 800, 500)
+	want := &backend.ValidationError{Reason: "polygon_self_crossing", Message: "polygon sides cross each other"}
 	assert.Equal(t, want, got)
 }
 
@@ -217,8 +270,8 @@ func TestValidateOverlappingPolygons(t *testing.T) {
 		},
 	}
 
-	got := polygons.Validate(800, 500)
-	want := errors.New("point X: 601 , Y: 201 is inside another polygon")
+	got := polygons.Validate(context.Background(), 800, 500)
+	want := &backend.ValidationError{Reason: "polygon_inside_polygon", Message: "point X: 601 , Y: 201 is inside another polygon"}
 	assert.Equal(t, want, got)
 }
 
@@ -34,4 +34,11 @@ func (r *Ray) Cast(b *Boundary) (*vector.Vector, bool) {
 	return r.Intersection(&b.Edge)
 }
 
+// CastEllipse casts the ray in the given direction, and checks
+// if it hits the given ellipse, returns the closest point of
+// intersection and boolean if intersection occurred
+func (r *Ray) CastEllipse(e *Ellipse) (*vector.Vector, bool) {
+	return e.Intersection(r.A, r.B)
+}
+
 type Rays []*Ray
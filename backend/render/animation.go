@@ -0,0 +1,363 @@
+package render
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"io"
+	"sync"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+	"github.com/iliyanmotovski/raytracer/backend/vector"
+)
+
+const (
+	// MaxFrames limits the number of frames of an animation
+	MaxFrames = 1000
+	// MaxPixels limits the pixels of all frames of an animation together, the frames are
+	// kept in memory until the animation is encoded, e.g. 250 frames of 800 x 500 pixels
+	MaxPixels = 100 * 1000 * 1000
+	// DefaultDelay is the time of each GIF frame in 100ths of a second when the delay is not set
+	DefaultDelay = 4
+	// maxFramesInFlight limits the frames of an animation which are rendered
+	// or wait for a worker at once, so a long animation does not hold a task
+	// and a full size image for each of its frames
+	maxFramesInFlight = 8
+)
+
+// AnimationFormat is the format of a rendered animation
+type AnimationFormat string
+
+const (
+	// GIFFormat is an animated GIF which loops forever
+	GIFFormat AnimationFormat = "gif"
+	// PNGFormat is a ZIP archive of PNG frames named by FrameName
+	PNGFormat AnimationFormat = "png"
+)
+
+// ContentType returns the content type of the rendered animation
+func (f AnimationFormat) ContentType() string {
+	if f == PNGFormat {
+		return "application/zip"
+	}
+
+	return "image/gif"
+}
+
+// Runner runs the task and returns its error, e.g. on the workers
+// of a backend.SceneReloadDaemon with backend.RunTask
+type Runner func(ctx context.Context, task func(ctx context.Context) error) error
+
+// Animation holds the light positions of the frames of an animation and how they are rendered
+type Animation struct {
+	Lights vector.Vectors
+	Format AnimationFormat
+	// Delay is the time of each GIF frame in 100ths of a second, DefaultDelay when 0
+	Delay   int
+	Options Options
+}
+
+// LightPath returns the light positions of the frames of an animation. When the step is
+// positive they are spread the step apart along the polyline through the points, its last
+// point included, otherwise every point is a keyframe and there is a frame for each of them
+func LightPath(points vector.Vectors, step float64) vector.Vectors {
+	if step <= 0 || len(points) < 2 {
+		return points
+	}
+
+	lights := vector.Vectors{points[0]}
+
+	// offset is the distance of the next light from the start of the current side
+	offset := step
+	for i := 0; i+1 < len(points) && len(lights) <= MaxFrames; i++ {
+		a, b := points[i], points[i+1]
+		length := a.Distance(*b)
+
+		for ; offset <= length && len(lights) <= MaxFrames; offset += step {
+			t := offset / length
+			lights = append(lights, &vector.Vector{X: a.X + (b.X-a.X)*t, Y: a.Y + (b.Y-a.Y)*t})
+		}
+
+		offset -= length
+	}
+
+	last := points[len(points)-1]
+	if lights[len(lights)-1].Distance(*last) > 1e-9 {
+		lights = append(lights, last)
+	}
+
+	return lights
+}
+
+// FrameName returns the file name of the i-th PNG frame of an animation
+func FrameName(i int) string {
+	return fmt.Sprintf("frame-%04d.png", i)
+}
+
+// Validate checks if the animation of the scene can be rendered
+func (a *Animation) Validate(scene *backend.Scene) error {
+	if a.Format != GIFFormat && a.Format != PNGFormat {
+		return fmt.Errorf("unknown animation format %q, it must be %s or %s", a.Format, GIFFormat, PNGFormat)
+	}
+
+	if len(a.Lights) == 0 {
+		return errors.New("animation has no frames")
+	}
+
+	if len(a.Lights) > MaxFrames {
+		return fmt.Errorf("animation has more than %d frames", MaxFrames)
+	}
+
+	if a.Delay < 0 {
+		return errors.New("delay must not be negative")
+	}
+
+	if scene.Width <= 0 || scene.Height <= 0 {
+		return errors.New("scene has no size")
+	}
+
+	width, height := size(scene, a.Options)
+	if width <= 0 || height <= 0 {
+		return errors.New("image has no size")
+	}
+
+	if len(a.Lights)*width*height > MaxPixels {
+		return fmt.Errorf("animation has more than %d pixels, %d frames of %d x %d pixels, use fewer or smaller frames",
+			MaxPixels, len(a.Lights), width, height)
+	}
+
+	for _, light := range a.Lights {
+		if light.X < 0 || light.X > scene.Width || light.Y < 0 || light.Y > scene.Height {
+			return backend.ErrLightOutside
+		}
+	}
+
+	return nil
+}
+
+// Render renders the animation of the scene and writes it to w in its format
+func (a *Animation) Render(ctx context.Context, w io.Writer, scene *backend.Scene, run Runner) error {
+	if err := a.Validate(scene); err != nil {
+		return err
+	}
+
+	if a.Format == PNGFormat {
+		return a.renderPNG(ctx, w, scene, run)
+	}
+
+	return a.renderGIF(ctx, w, scene, run)
+}
+
+// renderGIF writes the frames as an animated GIF. All frames share a palette made of the
+// colors of the options and the blends between them, so the anti-aliased edges do not flicker
+func (a *Animation) renderGIF(ctx context.Context, w io.Writer, scene *backend.Scene, run Runner) error {
+	delay := a.Delay
+	if delay == 0 {
+		delay = DefaultDelay
+	}
+
+	p := animationPalette(a.Options)
+	anim := &gif.GIF{Image: make([]*image.Paletted, len(a.Lights)), Delay: make([]int, len(a.Lights))}
+
+	err := Frames(ctx, scene, a.Lights, a.Options, run, func(i int, img *image.RGBA) error {
+		anim.Image[i], anim.Delay[i] = paletted(img, p), delay
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return gif.EncodeAll(w, anim)
+}
+
+// renderPNG writes the frames as a ZIP archive of PNG images, they are
+// stored as they are since the PNG images are already compressed
+func (a *Animation) renderPNG(ctx context.Context, w io.Writer, scene *backend.Scene, run Runner) error {
+	frames := make([][]byte, len(a.Lights))
+
+	err := Frames(ctx, scene, a.Lights, a.Options, run, func(i int, img *image.RGBA) error {
+		buf := &bytes.Buffer{}
+		if err := png.Encode(buf, img); err != nil {
+			return err
+		}
+
+		frames[i] = buf.Bytes()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	archive := zip.NewWriter(w)
+	for i, frame := range frames {
+		f, err := archive.CreateHeader(&zip.FileHeader{Name: FrameName(i), Method: zip.Store})
+		if err != nil {
+			return err
+		}
+
+		if _, err := f.Write(frame); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+// Frames renders a frame of the scene for each of the light positions. Each frame is processed
+// and rasterized by a task run with run, so the frames are rendered concurrently, at most
+// maxFramesInFlight at once, and it is passed to frame with its index from the task, so frame
+// must be safe for concurrent use. It returns the first error and the context of the rest of
+// the tasks is cancelled
+func Frames(ctx context.Context, scene *backend.Scene, lights vector.Vectors, opts Options, run Runner, frame func(i int, img *image.RGBA) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var first error
+	fail := func(err error) {
+		once.Do(func() {
+			first = err
+			cancel()
+		})
+	}
+
+	slots := make(chan struct{}, maxFramesInFlight)
+	for i, light := range lights {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(i int, light *vector.Vector) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			err := run(ctx, func(ctx context.Context) error {
+				s := *scene
+				s.Light = light
+
+				triangles, litArea, err := s.Process(ctx)
+				if err != nil {
+					return err
+				}
+
+				s.Triangles, s.LitArea = triangles, litArea
+
+				img, err := Image(&s, opts)
+				if err != nil {
+					return err
+				}
+
+				return frame(i, img)
+			})
+			if err != nil {
+				fail(err)
+			}
+		}(i, light)
+	}
+
+	wg.Wait()
+
+	if first != nil {
+		return first
+	}
+
+	// no frame has failed, but the context may be done before all were started
+	return backend.ContextError(ctx)
+}
+
+// animationPalette returns the opaque colors of the layers as they are drawn over each other
+// and the blends between each two of them, which are the colors of the anti-aliased edges
+func animationPalette(opts Options) color.Palette {
+	background := opts.Background
+	background.A = 255
+
+	polygon := over(background, opts.Polygon)
+	base := []color.RGBA{
+		background,
+		polygon,
+		over(background, opts.Lit),
+		over(polygon, opts.Lit),
+		over(background, opts.Obstacle),
+		over(background, opts.Light),
+		over(background, opts.Text),
+	}
+
+	p := color.Palette{}
+	for _, c := range base {
+		if !containsColor(p, c) {
+			p = append(p, c)
+		}
+	}
+
+	pairs := len(p) * (len(p) - 1) / 2
+	if pairs == 0 {
+		return p
+	}
+
+	levels := (256 - len(p)) / pairs
+	colors := len(p)
+	for i := 0; i < colors; i++ {
+		for j := i + 1; j < colors; j++ {
+			a, b := p[i].(color.RGBA), p[j].(color.RGBA)
+			for k := 1; k <= levels; k++ {
+				alpha := float64(k) / float64(levels+1)
+				p = append(p, color.RGBA{R: blend(a.R, b.R, alpha), G: blend(a.G, b.G, alpha), B: blend(a.B, b.B, alpha), A: 255})
+			}
+		}
+	}
+
+	return p
+}
+
+func containsColor(p color.Palette, c color.RGBA) bool {
+	for _, pc := range p {
+		if pc == c {
+			return true
+		}
+	}
+
+	return false
+}
+
+// over returns the color src drawn over the opaque dst
+func over(dst, src color.RGBA) color.RGBA {
+	alpha := float64(src.A) / 255
+	return color.RGBA{R: blend(dst.R, src.R, alpha), G: blend(dst.G, src.G, alpha), B: blend(dst.B, src.B, alpha), A: 255}
+}
+
+// paletted converts the image to the palette, most of its pixels
+// have the same few colors, so their indexes are cached
+func paletted(img *image.RGBA, p color.Palette) *image.Paletted {
+	bounds := img.Bounds()
+	result := image.NewPaletted(bounds, p)
+	indexes := map[color.RGBA]uint8{}
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := img.RGBAAt(x, y)
+
+			index, ok := indexes[c]
+			if !ok {
+				index = uint8(p.Index(c))
+				indexes[c] = index
+			}
+
+			result.SetColorIndex(x, y, index)
+		}
+	}
+
+	return result
+}
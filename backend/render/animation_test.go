@@ -0,0 +1,278 @@
+package render_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"image"
+	"image/gif"
+	"image/png"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+	"github.com/iliyanmotovski/raytracer/backend/persistent"
+	"github.com/iliyanmotovski/raytracer/backend/render"
+	"github.com/iliyanmotovski/raytracer/backend/vector"
+)
+
+func TestLightPath(t *testing.T) {
+	points := vector.Vectors{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 5}}
+
+	// the step goes around the corner and the last point is always included
+	assert.Equal(t, vector.Vectors{{X: 0, Y: 0}, {X: 4, Y: 0}, {X: 8, Y: 0}, {X: 10, Y: 2}, {X: 10, Y: 5}}, render.LightPath(points, 4))
+	assert.Equal(t, vector.Vectors{{X: 0, Y: 0}, {X: 5, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 5}}, render.LightPath(points, 5))
+
+	// without a step the points are the keyframes
+	assert.Equal(t, points, render.LightPath(points, 0))
+
+	// a tiny step stops right after the maximum number of frames
+	assert.Len(t, render.LightPath(points, 1e-9), render.MaxFrames+2)
+}
+
+func TestAnimationGIF(t *testing.T) {
+	scene := loadScene(t, newAnimationConfig())
+
+	cc := make(chan *backend.ConfigChan)
+	daemon := backend.NewSceneReloadDaemon(persistent.NewInMemorySceneRepository(), cc)
+	daemon.Start(4)
+	defer close(cc)
+
+	run := func(ctx context.Context, task func(ctx context.Context) error) error {
+		return backend.RunTask(ctx, cc, task)
+	}
+
+	opts := render.DefaultOptions()
+	opts.Width = 200
+
+	animation := &render.Animation{
+		Lights:  render.LightPath(vector.Vectors{{X: 100, Y: 100}, {X: 700, Y: 100}}, 100),
+		Format:  render.GIFFormat,
+		Options: opts,
+	}
+
+	buf := &bytes.Buffer{}
+	assert.Nil(t, animation.Render(context.Background(), buf, scene, run))
+
+	// every frame is rendered on the workers of the daemon
+	assert.Equal(t, int64(7), daemon.Metrics().Tasks)
+
+	anim, err := gif.DecodeAll(buf)
+	assert.Nil(t, err)
+	assert.Len(t, anim.Image, 7)
+	assert.Equal(t, []int{4, 4, 4, 4, 4, 4, 4}, anim.Delay)
+	assert.Equal(t, 200, anim.Config.Width)
+	assert.Equal(t, 125, anim.Config.Height)
+
+	// the light moves, so do the lit triangles
+	assert.NotEqual(t, anim.Image[0].Pix, anim.Image[6].Pix)
+}
+
+func TestAnimationPNGFrames(t *testing.T) {
+	scene := loadScene(t, newAnimationConfig())
+
+	animation := &render.Animation{
+		Lights:  vector.Vectors{{X: 100, Y: 100}, {X: 400, Y: 400}, {X: 700, Y: 100}},
+		Format:  render.PNGFormat,
+		Options: render.DefaultOptions(),
+	}
+
+	buf := &bytes.Buffer{}
+	assert.Nil(t, animation.Render(context.Background(), buf, scene, runNow))
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+	assert.Len(t, archive.File, 3)
+
+	for i, f := range archive.File {
+		assert.Equal(t, render.FrameName(i), f.Name)
+
+		r, err := f.Open()
+		assert.Nil(t, err)
+
+		img, err := png.Decode(r)
+		assert.Nil(t, err)
+		assert.Equal(t, 800, img.Bounds().Dx())
+		r.Close()
+	}
+}
+
+func TestAnimationValidate(t *testing.T) {
+	scene := loadScene(t, newAnimationConfig())
+	lights := vector.Vectors{{X: 100, Y: 100}}
+
+	for name, animation := range map[string]*render.Animation{
+		"format":        {Lights: lights, Format: "mp4"},
+		"no frames":     {Format: render.GIFFormat},
+		"too many":      {Lights: make(vector.Vectors, render.MaxFrames+1), Format: render.GIFFormat},
+		"delay":         {Lights: lights, Format: render.GIFFormat, Delay: -1},
+		"light outside": {Lights: vector.Vectors{{X: 100, Y: 100}, {X: 900, Y: 100}}, Format: render.GIFFormat},
+		"too many pixels": {
+			Lights:  make(vector.Vectors, 10),
+			Format:  render.GIFFormat,
+			Options: render.Options{Width: render.MaxSize, Height: render.MaxSize},
+		},
+	} {
+		assert.NotNil(t, animation.Validate(scene), name)
+	}
+}
+
+func TestAnimationStopsAtFirstError(t *testing.T) {
+	scene := loadScene(t, newAnimationConfig())
+
+	// the workers give up on the frames after the first one
+	var mu sync.Mutex
+	started := 0
+	run := func(ctx context.Context, task func(ctx context.Context) error) error {
+		mu.Lock()
+		started++
+		first := started == 1
+		mu.Unlock()
+
+		if !first {
+			return backend.ErrContextCancelled
+		}
+
+		return task(ctx)
+	}
+
+	animation := &render.Animation{
+		Lights:  vector.Vectors{{X: 100, Y: 100}, {X: 400, Y: 400}, {X: 700, Y: 100}},
+		Format:  render.GIFFormat,
+		Options: render.DefaultOptions(),
+	}
+
+	buf := &bytes.Buffer{}
+	assert.Equal(t, backend.ErrContextCancelled, animation.Render(context.Background(), buf, scene, run))
+	assert.Zero(t, buf.Len())
+}
+
+func TestFramesInFlight(t *testing.T) {
+	scene := loadScene(t, newAnimationConfig())
+
+	lights := make(vector.Vectors, 50)
+	for i := range lights {
+		lights[i] = &vector.Vector{X: float64(10 + i), Y: 100}
+	}
+
+	// the tasks are only counted, so the runner sees how many frames are in flight at once
+	var mu sync.Mutex
+	inFlight, most, ran := 0, 0, 0
+	run := func(ctx context.Context, task func(ctx context.Context) error) error {
+		mu.Lock()
+		inFlight++
+		ran++
+		if inFlight > most {
+			most = inFlight
+		}
+		mu.Unlock()
+
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+
+		return nil
+	}
+
+	err := render.Frames(context.Background(), scene, lights, render.DefaultOptions(), run, func(int, *image.RGBA) error { return nil })
+	assert.Nil(t, err)
+	assert.Equal(t, len(lights), ran)
+	assert.True(t, most <= 8, most)
+}
+
+func TestAnimations(t *testing.T) {
+	scene := loadScene(t, newAnimationConfig())
+
+	release := make(chan struct{})
+	run := func(ctx context.Context, task func(ctx context.Context) error) error {
+		<-release
+		return task(ctx)
+	}
+
+	animations := render.NewAnimations(context.Background(), run, 1)
+
+	animation := &render.Animation{
+		Lights:  vector.Vectors{{X: 100, Y: 100}, {X: 700, Y: 100}},
+		Format:  render.GIFFormat,
+		Options: render.DefaultOptions(),
+	}
+
+	job, err := animations.Start(scene, animation)
+	assert.Nil(t, err)
+	assert.Equal(t, job, animations.Get(job.ID))
+	assert.Equal(t, render.AnimationStatus{}, job.Status())
+	assert.Nil(t, job.Result())
+
+	// the only kept job is still rendering
+	_, err = animations.Start(scene, animation)
+	assert.Equal(t, render.ErrTooManyAnimations, err)
+
+	close(release)
+	assert.Eventually(t, func() bool { return job.Status().Done }, 5*time.Second, time.Millisecond)
+	assert.Equal(t, render.AnimationStatus{Rendered: 2, Done: true}, job.Status())
+
+	anim, err := gif.DecodeAll(bytes.NewReader(job.Result()))
+	assert.Nil(t, err)
+	assert.Len(t, anim.Image, 2)
+
+	// the finished job makes room for the next one
+	next, err := animations.Start(scene, animation)
+	assert.Nil(t, err)
+	assert.Nil(t, animations.Get(job.ID))
+	assert.Equal(t, next, animations.Get(next.ID))
+
+	_, err = animations.Start(scene, &render.Animation{Format: render.GIFFormat})
+	assert.NotNil(t, err)
+}
+
+func TestAnimationsClose(t *testing.T) {
+	scene := loadScene(t, newAnimationConfig())
+
+	started := make(chan struct{}, 2)
+	run := func(ctx context.Context, task func(ctx context.Context) error) error {
+		started <- struct{}{}
+		<-ctx.Done()
+		return backend.ContextError(ctx)
+	}
+
+	animations := render.NewAnimations(context.Background(), run, 4)
+
+	animation := &render.Animation{
+		Lights:  vector.Vectors{{X: 100, Y: 100}, {X: 700, Y: 100}},
+		Format:  render.GIFFormat,
+		Options: render.DefaultOptions(),
+	}
+
+	job, err := animations.Start(scene, animation)
+	assert.Nil(t, err)
+
+	<-started
+	<-started
+
+	// the rendering job is cancelled and has finished once Close returns
+	animations.Close()
+	assert.Equal(t, render.AnimationStatus{Done: true, Err: backend.ErrContextCancelled}, job.Status())
+
+	_, err = animations.Start(scene, animation)
+	assert.Equal(t, render.ErrAnimationsClosed, err)
+}
+
+func runNow(ctx context.Context, task func(ctx context.Context) error) error {
+	return task(ctx)
+}
+
+func newAnimationConfig() *backend.Config {
+	return &backend.Config{
+		ID:    backend.DefaultSceneID,
+		Light: &vector.Vector{X: 100, Y: 100},
+		Scene: &vector.Vector{X: 800, Y: 500},
+		Polygons: backend.Polygons{
+			{VerticesCount: 4, Loop: vector.Loop{{X: 350, Y: 200}, {X: 450, Y: 200}, {X: 450, Y: 300}, {X: 350, Y: 300}}},
+		},
+	}
+}
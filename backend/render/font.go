@@ -0,0 +1,68 @@
+package render
+
+import (
+	"image"
+	"image/color"
+)
+
+// glyphWidth and glyphHeight are the size of the glyphs in font
+// pixels, glyphAdvance adds a column of spacing after each of them
+const (
+	glyphWidth   = 5
+	glyphHeight  = 7
+	glyphAdvance = glyphWidth + 1
+)
+
+// font is a tiny bitmap font which covers the characters of the lit area text,
+// the characters which are not in it are drawn as spaces
+var font = map[rune][glyphHeight]string{
+	'L': {"#....", "#....", "#....", "#....", "#....", "#....", "#####"},
+	'i': {"..#..", ".....", ".##..", "..#..", "..#..", "..#..", ".###."},
+	't': {".#...", ".#...", "####.", ".#...", ".#...", ".#..#", "..##."},
+	'a': {".....", ".....", ".###.", "....#", ".####", "#...#", ".####"},
+	'r': {".....", ".....", "#.##.", "##..#", "#....", "#....", "#...."},
+	'e': {".....", ".....", ".###.", "#...#", "#####", "#....", ".###."},
+	's': {".....", ".....", ".####", "#....", ".###.", "....#", "####."},
+	':': {".....", ".##..", ".##..", ".....", ".##..", ".##..", "....."},
+	'.': {".....", ".....", ".....", ".....", ".....", ".##..", ".##.."},
+	'%': {"##...", "##..#", "...#.", "..#..", ".#...", "#..##", "...##"},
+	'-': {".....", ".....", ".....", "#####", ".....", ".....", "....."},
+	'0': {".###.", "#...#", "#..##", "#.#.#", "##..#", "#...#", ".###."},
+	'1': {"..#..", ".##..", "..#..", "..#..", "..#..", "..#..", ".###."},
+	'2': {".###.", "#...#", "....#", "...#.", "..#..", ".#...", "#####"},
+	'3': {"#####", "...#.", "..#..", "...#.", "....#", "#...#", ".###."},
+	'4': {"...#.", "..##.", ".#.#.", "#..#.", "#####", "...#.", "...#."},
+	'5': {"#####", "#....", "####.", "....#", "....#", "#...#", ".###."},
+	'6': {"..##.", ".#...", "#....", "####.", "#...#", "#...#", ".###."},
+	'7': {"#####", "....#", "...#.", "..#..", ".#...", ".#...", ".#..."},
+	'8': {".###.", "#...#", "#...#", ".###.", "#...#", "#...#", ".###."},
+	'9': {".###.", "#...#", "#...#", ".####", "....#", "...#.", ".##.."},
+}
+
+// drawText draws the text with its top left corner at x, y,
+// every font pixel is drawn as a square of size image pixels
+func drawText(img *image.RGBA, text string, x, y, size int, c color.RGBA) {
+	for _, char := range text {
+		glyph, ok := font[char]
+		if ok {
+			for row, line := range glyph {
+				for column, dot := range line {
+					if dot != '#' {
+						continue
+					}
+
+					for dy := 0; dy < size; dy++ {
+						for dx := 0; dx < size; dx++ {
+							px, py := x+column*size+dx, y+row*size+dy
+							if (image.Point{X: px, Y: py}).In(img.Bounds()) {
+								img.SetRGBA(px, py, c)
+							}
+						}
+					}
+				}
+			}
+		}
+
+		x += glyphAdvance * size
+	}
+}
@@ -0,0 +1,199 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+	"github.com/iliyanmotovski/raytracer/backend/tracing"
+)
+
+var (
+	// ErrTooManyAnimations is returned when all of the kept animation jobs are still rendering
+	ErrTooManyAnimations = errors.New("too many animations are rendering")
+	// ErrAnimationsClosed is returned when an animation is started after the Animations are closed
+	ErrAnimationsClosed = errors.New("animations are closed")
+)
+
+// AnimationJob is an animation rendered in the background
+type AnimationJob struct {
+	ID      string
+	SceneID string
+	Format  AnimationFormat
+	Frames  int
+
+	mu       sync.Mutex
+	rendered int
+	done     bool
+	err      error
+	result   []byte
+}
+
+// AnimationStatus is the progress of an AnimationJob
+type AnimationStatus struct {
+	// Rendered is the number of rendered frames
+	Rendered int
+	Done     bool
+	// Err is the error of the finished job
+	Err error
+}
+
+// Status returns the progress of the job
+func (j *AnimationJob) Status() AnimationStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return AnimationStatus{Rendered: j.rendered, Done: j.done, Err: j.err}
+}
+
+// Result returns the rendered animation, it is nil until the job is done without an error
+func (j *AnimationJob) Result() []byte {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.result
+}
+
+func (j *AnimationJob) finished() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.done
+}
+
+// Animations renders animations in the background and keeps their jobs, so
+// their progress and their results can be fetched later
+type Animations struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	run    Runner
+	limit  int
+	// rendering counts the jobs which are still rendering
+	rendering sync.WaitGroup
+
+	mu     sync.Mutex
+	closed bool
+	jobs   map[string]*AnimationJob
+	// order holds the IDs of the jobs from the oldest one
+	order []string
+}
+
+// NewAnimations creates Animations which keep up to limit jobs and render their
+// frames with run, the rendering jobs are cancelled when the context is done or on Close
+func NewAnimations(ctx context.Context, run Runner, limit int) *Animations {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Animations{ctx: ctx, cancel: cancel, run: run, limit: limit, jobs: map[string]*AnimationJob{}}
+}
+
+// Start validates the animation of the scene and starts rendering it in the background.
+// When there are already as many jobs as the limit, the oldest finished one is dropped
+// and ErrTooManyAnimations is returned when none of them is finished
+func (a *Animations) Start(scene *backend.Scene, animation *Animation) (*AnimationJob, error) {
+	if err := animation.Validate(scene); err != nil {
+		return nil, err
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+
+	job := &AnimationJob{ID: id, SceneID: scene.ID, Format: animation.Format, Frames: len(animation.Lights)}
+
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil, ErrAnimationsClosed
+	}
+
+	if len(a.order) >= a.limit && !a.dropFinished() {
+		a.mu.Unlock()
+		return nil, ErrTooManyAnimations
+	}
+
+	a.jobs[id] = job
+	a.order = append(a.order, id)
+	a.rendering.Add(1)
+	a.mu.Unlock()
+
+	go a.render(job, scene, animation)
+
+	return job, nil
+}
+
+// Get returns the job with the ID or nil if there is no such job
+func (a *Animations) Get(id string) *AnimationJob {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.jobs[id]
+}
+
+// Close cancels the rendering jobs and waits for them to finish, so their
+// frames are no longer run, later animations fail with ErrAnimationsClosed
+func (a *Animations) Close() {
+	a.mu.Lock()
+	a.closed = true
+	a.mu.Unlock()
+
+	a.cancel()
+	a.rendering.Wait()
+}
+
+// dropFinished drops the oldest finished job, it must be called with the lock held
+func (a *Animations) dropFinished() bool {
+	for i, id := range a.order {
+		if a.jobs[id].finished() {
+			delete(a.jobs, id)
+			a.order = append(a.order[:i], a.order[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// render renders the animation of the job and counts its rendered frames
+func (a *Animations) render(job *AnimationJob, scene *backend.Scene, animation *Animation) {
+	defer a.rendering.Done()
+
+	run := func(ctx context.Context, task func(ctx context.Context) error) error {
+		err := a.run(ctx, task)
+		if err == nil {
+			job.mu.Lock()
+			job.rendered++
+			job.mu.Unlock()
+		}
+
+		return err
+	}
+
+	// the animation is rendered after its request is handled, so its frames are traced on their own
+	ctx, span := tracing.Start(a.ctx, "animation.render", "animation", job.ID, "scene", scene.ID,
+		"format", string(job.Format), "frames", job.Frames)
+	defer span.End()
+
+	buf := &bytes.Buffer{}
+	err := animation.Render(ctx, buf, scene, run)
+	span.SetError(err)
+
+	job.mu.Lock()
+	job.done, job.err = true, err
+	if err == nil {
+		job.result = buf.Bytes()
+	}
+	job.mu.Unlock()
+}
+
+func newJobID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
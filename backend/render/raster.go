@@ -0,0 +1,146 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sort"
+)
+
+// subsamples is the number of scanlines sampled within every row of pixels,
+// the horizontal coverage of each scanline is computed exactly
+const subsamples = 4
+
+// point is a point in the pixel space of the image
+type point struct {
+	X, Y float64
+}
+
+// rasterizer fills anti-aliased paths on an image. The coverage of all paths added
+// before a fill is summed up, so paths which share an edge, like the lit triangles,
+// are filled without seams between them
+type rasterizer struct {
+	img        *image.RGBA
+	cover      []float64
+	minY, maxY int
+}
+
+func newRasterizer(img *image.RGBA) *rasterizer {
+	size := img.Bounds().Size()
+	return &rasterizer{img: img, cover: make([]float64, size.X*size.Y), minY: size.Y, maxY: -1}
+}
+
+// addPath adds the coverage of the closed path using the even-odd rule
+func (r *rasterizer) addPath(path []point) {
+	if len(path) < 3 {
+		return
+	}
+
+	size := r.img.Bounds().Size()
+
+	top, bottom := math.Inf(1), math.Inf(-1)
+	for _, p := range path {
+		top = math.Min(top, p.Y)
+		bottom = math.Max(bottom, p.Y)
+	}
+
+	fromRow := int(math.Max(math.Floor(top), 0))
+	toRow := int(math.Min(math.Ceil(bottom), float64(size.Y)))
+
+	crossings := []float64{}
+	for row := fromRow; row < toRow; row++ {
+		for s := 0; s < subsamples; s++ {
+			y := float64(row) + (float64(s)+0.5)/subsamples
+
+			crossings = crossings[:0]
+			for i, a := range path {
+				b := path[(i+1)%len(path)]
+				if (a.Y <= y && y < b.Y) || (b.Y <= y && y < a.Y) {
+					crossings = append(crossings, a.X+(y-a.Y)*(b.X-a.X)/(b.Y-a.Y))
+				}
+			}
+
+			sort.Float64s(crossings)
+			for i := 0; i+1 < len(crossings); i += 2 {
+				r.addSpan(row, crossings[i], crossings[i+1], size.X)
+			}
+		}
+
+		if row < r.minY {
+			r.minY = row
+		}
+		if row > r.maxY {
+			r.maxY = row
+		}
+	}
+}
+
+// addSpan adds the coverage of a single scanline between x0 and x1 to the row
+func (r *rasterizer) addSpan(row int, x0, x1 float64, width int) {
+	x0 = math.Max(x0, 0)
+	x1 = math.Min(x1, float64(width))
+	if x1 <= x0 {
+		return
+	}
+
+	for x := int(x0); x < width && float64(x) < x1; x++ {
+		overlap := math.Min(x1, float64(x+1)) - math.Max(x0, float64(x))
+		r.cover[row*width+x] += overlap / subsamples
+	}
+}
+
+// fill blends the color into the image by the coverage of the added paths and clears it
+func (r *rasterizer) fill(c color.RGBA) {
+	width := r.img.Bounds().Size().X
+
+	for row := r.minY; row <= r.maxY; row++ {
+		for x := 0; x < width; x++ {
+			i := row*width + x
+			if r.cover[i] <= 0 {
+				continue
+			}
+
+			alpha := math.Min(r.cover[i], 1) * float64(c.A) / 255
+			r.cover[i] = 0
+
+			dst := r.img.RGBAAt(x, row)
+			r.img.SetRGBA(x, row, color.RGBA{
+				R: blend(dst.R, c.R, alpha),
+				G: blend(dst.G, c.G, alpha),
+				B: blend(dst.B, c.B, alpha),
+				A: blend(dst.A, 255, alpha),
+			})
+		}
+	}
+
+	r.minY, r.maxY = r.img.Bounds().Size().Y, -1
+}
+
+func blend(dst, src uint8, alpha float64) uint8 {
+	return uint8(math.Round(float64(dst)*(1-alpha) + float64(src)*alpha))
+}
+
+// line returns the path of a straight line with the given width between a and b
+func line(a, b point, width float64) []point {
+	length := math.Hypot(b.X-a.X, b.Y-a.Y)
+	if length == 0 {
+		return nil
+	}
+
+	// the normal of the line scaled to half of its width
+	nx := -(b.Y - a.Y) / length * width / 2
+	ny := (b.X - a.X) / length * width / 2
+
+	return []point{{a.X + nx, a.Y + ny}, {b.X + nx, b.Y + ny}, {b.X - nx, b.Y - ny}, {a.X - nx, a.Y - ny}}
+}
+
+// circle returns the path of a circle approximated by a polygon
+func circle(center point, radius float64, segments int) []point {
+	path := make([]point, segments)
+	for i := range path {
+		angle := 2 * math.Pi * float64(i) / float64(segments)
+		path[i] = point{center.X + radius*math.Cos(angle), center.Y + radius*math.Sin(angle)}
+	}
+
+	return path
+}
@@ -0,0 +1,253 @@
+// Package render draws scenes as images on the server, the same
+// way the frontend draws them in the browser
+package render
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+	"github.com/iliyanmotovski/raytracer/backend/vector"
+)
+
+const (
+	// curveTolerance is the maximum distance in pixels between a
+	// curved polygon side and the straight lines it is drawn with
+	curveTolerance = 0.25
+	// ellipseSegments is the number of vertices of the drawn ellipses
+	ellipseSegments = 64
+	// arcSegments is the number of straight lines an arc side of a lit triangle is drawn with
+	arcSegments = 16
+	// wallWidth and lightRadius are in scene units, they are scaled with the image
+	wallWidth   = 3
+	lightRadius = 10
+)
+
+// MaxSize limits the width and the height of the images in pixels
+const MaxSize = 4096
+
+// ColorNames are the names of the colors of Options which can be set with SetColor
+var ColorNames = []string{"background", "polygon", "lit", "obstacle", "light", "text"}
+
+// Options holds the size and the colors of the rendered image
+type Options struct {
+	// Width and Height of the image in pixels, when one of them is 0 it is
+	// computed from the other one keeping the aspect ratio of the scene and
+	// when both are 0 the image has the size of the scene. An image which would be
+	// larger than MaxSize is scaled down to fit it, keeping its aspect ratio
+	Width, Height int
+	Background    color.RGBA
+	Polygon       color.RGBA
+	Lit           color.RGBA
+	// Obstacle is the color of the walls and the ellipses
+	Obstacle color.RGBA
+	Light    color.RGBA
+	Text     color.RGBA
+}
+
+// DefaultOptions returns the options with the colors used by the frontend
+func DefaultOptions() Options {
+	return Options{
+		Background: color.RGBA{R: 117, G: 114, B: 107, A: 255},
+		Polygon:    color.RGBA{R: 181, G: 121, B: 24, A: 255},
+		Lit:        color.RGBA{R: 217, G: 206, B: 189, A: 255},
+		Obstacle:   color.RGBA{R: 181, G: 121, B: 24, A: 255},
+		Light:      color.RGBA{R: 255, G: 204, B: 0, A: 255},
+		Text:       color.RGBA{R: 0, G: 0, B: 0, A: 255},
+	}
+}
+
+// SetColor sets the color with one of the ColorNames to a hex value - RRGGBB or RRGGBBAA,
+// optionally prefixed with #
+func (o *Options) SetColor(name, hex string) error {
+	c, err := parseColor(hex)
+	if err != nil {
+		return err
+	}
+
+	switch name {
+	case "background":
+		o.Background = c
+	case "polygon":
+		o.Polygon = c
+	case "lit":
+		o.Lit = c
+	case "obstacle":
+		o.Obstacle = c
+	case "light":
+		o.Light = c
+	case "text":
+		o.Text = c
+	default:
+		return fmt.Errorf("unknown color %q", name)
+	}
+
+	return nil
+}
+
+func parseColor(hex string) (color.RGBA, error) {
+	hex = strings.TrimPrefix(hex, "#")
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+
+	value, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || len(hex) != 8 {
+		return color.RGBA{}, fmt.Errorf("invalid color %q, it must be RRGGBB or RRGGBBAA", hex)
+	}
+
+	return color.RGBA{R: uint8(value >> 24), G: uint8(value >> 16), B: uint8(value >> 8), A: uint8(value)}, nil
+}
+
+// PNG renders the scene and writes it to w encoded as PNG
+func PNG(w io.Writer, scene *backend.Scene, opts Options) error {
+	img, err := Image(scene, opts)
+	if err != nil {
+		return err
+	}
+
+	return png.Encode(w, img)
+}
+
+// Image renders the background, the polygons, the lit triangles, the walls,
+// the ellipses, the lit area text and the light of the scene in this order
+func Image(scene *backend.Scene, opts Options) (*image.RGBA, error) {
+	if scene.Width <= 0 || scene.Height <= 0 {
+		return nil, errors.New("scene has no size")
+	}
+
+	width, height := size(scene, opts)
+	if width <= 0 || height <= 0 {
+		return nil, errors.New("image has no size")
+	}
+
+	c := &canvas{
+		img:    image.NewRGBA(image.Rect(0, 0, width, height)),
+		scaleX: float64(width) / scene.Width,
+		scaleY: float64(height) / scene.Height,
+		height: scene.Height,
+	}
+	c.raster = newRasterizer(c.img)
+	scale := math.Min(c.scaleX, c.scaleY)
+
+	c.raster.addPath([]point{{0, 0}, {float64(width), 0}, {float64(width), float64(height)}, {0, float64(height)}})
+	c.raster.fill(opts.Background)
+
+	polygons := scene.Polygons.Flatten(scene.Light, curveTolerance/scale)
+	for _, polygon := range polygons {
+		c.raster.addPath(c.path(polygon.Loop))
+	}
+	c.raster.fill(opts.Polygon)
+
+	for _, triangle := range scene.Triangles {
+		c.raster.addPath(c.triangle(triangle))
+	}
+	c.raster.fill(opts.Lit)
+
+	for _, wall := range scene.Walls {
+		path := c.path(wall.Vertices)
+		for i := 0; i+1 < len(path); i++ {
+			c.raster.addPath(line(path[i], path[i+1], wallWidth*scale))
+		}
+	}
+	for _, ellipse := range scene.Ellipses {
+		c.raster.addPath(c.path(ellipse.Polygonize(ellipseSegments).Loop))
+	}
+	c.raster.fill(opts.Obstacle)
+
+	textSize := int(math.Max(1, math.Round(2*scale)))
+	drawText(c.img, fmt.Sprintf("Lit area is: %v%%", scene.LitArea), int(10*scale), int(14*scale), textSize, opts.Text)
+
+	if scene.Light != nil {
+		c.drawLight(c.point(scene.Light.X, scene.Light.Y), lightRadius*scale, opts.Light)
+	}
+
+	return c.img, nil
+}
+
+// size returns the size of the image, see Options
+func size(scene *backend.Scene, opts Options) (int, int) {
+	width, height := opts.Width, opts.Height
+
+	switch {
+	case width <= 0 && height <= 0:
+		width, height = int(math.Round(scene.Width)), int(math.Round(scene.Height))
+	case height <= 0:
+		height = int(math.Round(float64(width) * scene.Height / scene.Width))
+	case width <= 0:
+		width = int(math.Round(float64(height) * scene.Width / scene.Height))
+	}
+
+	if width > MaxSize || height > MaxSize {
+		scale := math.Min(float64(MaxSize)/float64(width), float64(MaxSize)/float64(height))
+		width = int(math.Max(1, math.Round(float64(width)*scale)))
+		height = int(math.Max(1, math.Round(float64(height)*scale)))
+	}
+
+	return width, height
+}
+
+// canvas maps the scene to the pixel space of the image, the Y axis of the scene
+// points up while the one of the image points down, the same as in the frontend
+type canvas struct {
+	img            *image.RGBA
+	raster         *rasterizer
+	scaleX, scaleY float64
+	height         float64
+}
+
+func (c *canvas) point(x, y float64) point {
+	return point{X: x * c.scaleX, Y: (c.height - y) * c.scaleY}
+}
+
+func (c *canvas) path(loop []*vector.Vector) []point {
+	path := make([]point, len(loop))
+	for i, v := range loop {
+		path[i] = c.point(v.X, v.Y)
+	}
+
+	return path
+}
+
+// triangle returns the path of the lit triangle, when its far side is an arc of
+// an ellipse the arc is followed instead of the straight side
+func (c *canvas) triangle(triangle *backend.Triangle) []point {
+	path := c.path(triangle.Loop)
+	if triangle.Arc == nil || len(path) != 3 {
+		return path
+	}
+
+	arc := triangle.Arc
+	delta := math.Remainder(arc.To-arc.From, 2*math.Pi)
+
+	path = path[:2]
+	for i := 1; i < arcSegments; i++ {
+		v := arc.Ellipse.PointAt(arc.From + delta*float64(i)/arcSegments)
+		path = append(path, c.point(v.X, v.Y))
+	}
+
+	return append(path, c.point(triangle.Loop[2].X, triangle.Loop[2].Y))
+}
+
+// drawLight draws the light as a sun - a disc surrounded by 8 rays
+func (c *canvas) drawLight(center point, radius float64, col color.RGBA) {
+	c.raster.addPath(circle(center, radius*0.6, 32))
+
+	for i := 0; i < 8; i++ {
+		angle := math.Pi * float64(i) / 4
+		dx, dy := math.Cos(angle), math.Sin(angle)
+
+		from := point{center.X + dx*radius*0.8, center.Y + dy*radius*0.8}
+		to := point{center.X + dx*radius*1.3, center.Y + dy*radius*1.3}
+		c.raster.addPath(line(from, to, math.Max(radius*0.2, 1)))
+	}
+
+	c.raster.fill(col)
+}
@@ -0,0 +1,168 @@
+package render_test
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+	"github.com/iliyanmotovski/raytracer/backend/persistent"
+	"github.com/iliyanmotovski/raytracer/backend/render"
+	"github.com/iliyanmotovski/raytracer/backend/vector"
+)
+
+var update = flag.Bool("update", false, "update the golden images in testdata")
+
+func TestRenderPNG(t *testing.T) {
+	scene := loadScene(t, &backend.Config{
+		ID:    backend.DefaultSceneID,
+		Light: &vector.Vector{X: 250, Y: 300},
+		Scene: &vector.Vector{X: 800, Y: 500},
+		Polygons: backend.Polygons{
+			{VerticesCount: 3, Loop: vector.Loop{{X: 600, Y: 200}, {X: 646, Y: 133}, {X: 646, Y: 261}}},
+			{VerticesCount: 6, Loop: vector.Loop{{X: 131, Y: 188}, {X: 54, Y: 136}, {X: 86, Y: 32}, {X: 220, Y: 32}, {X: 238, Y: 114}, {X: 209, Y: 163}}},
+			{VerticesCount: 5, Loop: vector.Loop{{X: 412, Y: 364}, {X: 454, Y: 251}, {X: 537, Y: 257}, {X: 601, Y: 350}, {X: 528, Y: 430}}},
+		},
+	})
+
+	assertGolden(t, "scene.png", scene, render.DefaultOptions())
+}
+
+func TestRenderPNGWithOptions(t *testing.T) {
+	scene := loadScene(t, &backend.Config{
+		ID:    backend.DefaultSceneID,
+		Light: &vector.Vector{X: 250, Y: 300},
+		Scene: &vector.Vector{X: 800, Y: 500},
+		Polygons: backend.Polygons{
+			{
+				VerticesCount: 3,
+				Loop:          vector.Loop{{X: 600, Y: 200}, {X: 646, Y: 133}, {X: 646, Y: 261}},
+				Controls:      []vector.Vectors{{{X: 620, Y: 150}}, nil, nil},
+			},
+		},
+		Walls: backend.Walls{
+			{VerticesCount: 3, Vertices: vector.Vectors{{X: 100, Y: 100}, {X: 300, Y: 100}, {X: 300, Y: 50}}},
+		},
+		Ellipses: backend.Ellipses{
+			{Center: &vector.Vector{X: 400, Y: 400}, RadiusX: 50, RadiusY: 30},
+		},
+	})
+
+	opts := render.DefaultOptions()
+	opts.Width = 400
+	assert.Nil(t, opts.SetColor("background", "#202020"))
+	assert.Nil(t, opts.SetColor("lit", "fff5d0c0"))
+
+	assertGolden(t, "scene_options.png", scene, opts)
+}
+
+func TestRenderImageSize(t *testing.T) {
+	scene := &backend.Scene{Width: 800, Height: 500, Light: &vector.Vector{X: 250, Y: 300}}
+
+	for _, size := range []struct{ width, height, wantWidth, wantHeight int }{
+		{0, 0, 800, 500},
+		{400, 0, 400, 250},
+		{0, 100, 160, 100},
+		{100, 100, 100, 100},
+	} {
+		opts := render.DefaultOptions()
+		opts.Width, opts.Height = size.width, size.height
+
+		img, err := render.Image(scene, opts)
+		assert.Nil(t, err)
+		assert.Equal(t, image.Pt(size.wantWidth, size.wantHeight), img.Bounds().Size())
+	}
+}
+
+func TestRenderImageSizeLimit(t *testing.T) {
+	for _, size := range []struct {
+		sceneWidth, sceneHeight float64
+		width, height           int
+		wantWidth, wantHeight   int
+	}{
+		// the height kept in the aspect ratio of a very tall scene
+		{10, 10000, render.MaxSize, 0, 4, render.MaxSize},
+		// the size of a very large scene
+		{20000, 10000, 0, 0, render.MaxSize, render.MaxSize / 2},
+	} {
+		scene := &backend.Scene{Width: size.sceneWidth, Height: size.sceneHeight, Light: &vector.Vector{X: 5, Y: 5}}
+
+		opts := render.DefaultOptions()
+		opts.Width, opts.Height = size.width, size.height
+
+		img, err := render.Image(scene, opts)
+		assert.Nil(t, err)
+		assert.Equal(t, image.Pt(size.wantWidth, size.wantHeight), img.Bounds().Size())
+	}
+}
+
+func TestSetInvalidColor(t *testing.T) {
+	opts := render.DefaultOptions()
+
+	assert.NotNil(t, opts.SetColor("background", "#12345"))
+	assert.NotNil(t, opts.SetColor("background", "#zzzzzz"))
+	assert.NotNil(t, opts.SetColor("sky", "#123456"))
+	assert.Equal(t, render.DefaultOptions(), opts)
+}
+
+func loadScene(t *testing.T, config *backend.Config) *backend.Scene {
+	scene, err := backend.NewScene(config).Load(context.Background(), persistent.NewInMemorySceneRepository())
+	assert.Nil(t, err)
+
+	return scene
+}
+
+// assertGolden compares the rendered scene with the golden image, allowing a difference of
+// 2 per channel to tolerate the floating point differences between the platforms
+func assertGolden(t *testing.T, name string, scene *backend.Scene, opts render.Options) {
+	buf := &bytes.Buffer{}
+	assert.Nil(t, render.PNG(buf, scene, opts))
+
+	path := filepath.Join("testdata", name)
+	if *update {
+		assert.Nil(t, ioutil.WriteFile(path, buf.Bytes(), 0644))
+	}
+
+	f, err := os.Open(path)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer f.Close()
+
+	want, err := png.Decode(f)
+	assert.Nil(t, err)
+
+	got, err := png.Decode(buf)
+	assert.Nil(t, err)
+
+	if !assert.Equal(t, want.Bounds(), got.Bounds()) {
+		return
+	}
+
+	diff := 0
+	for y := want.Bounds().Min.Y; y < want.Bounds().Max.Y; y++ {
+		for x := want.Bounds().Min.X; x < want.Bounds().Max.X; x++ {
+			wr, wg, wb, wa := want.At(x, y).RGBA()
+			gr, gg, gb, ga := got.At(x, y).RGBA()
+
+			if !near(wr, gr) || !near(wg, gg) || !near(wb, gb) || !near(wa, ga) {
+				diff++
+			}
+		}
+	}
+
+	assert.Equal(t, 0, diff, "pixels different from %s, run the tests with -update to accept them", path)
+}
+
+func near(a, b uint32) bool {
+	// the channels are 16 bit, 2 in 8 bit
+	return a >= b && a-b <= 2*0x101 || b > a && b-a <= 2*0x101
+}
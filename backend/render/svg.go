@@ -0,0 +1,203 @@
+package render
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+	"github.com/iliyanmotovski/raytracer/backend/vector"
+)
+
+// SVG writes the scene to w as an SVG image with the same layers as Image. The
+// Y axis is flipped the same way as in the frontend and in Image, so the SVG
+// coordinates are the scene ones with Y measured from the top. Every element has
+// an id and a class by what it is, e.g. the first polygon is
+// <path id="polygon-0" class="polygon curved" ...> when some of its sides is curved.
+// The width and the height of Options set the size of the SVG, its view box
+// always is the whole scene
+func SVG(w io.Writer, scene *backend.Scene, opts Options) error {
+	if scene.Width <= 0 || scene.Height <= 0 {
+		return errors.New("scene has no size")
+	}
+
+	width, height := size(scene, opts)
+	s := &svgWriter{w: bufio.NewWriter(w), height: scene.Height}
+
+	s.printf(`<svg xmlns="http://www.w3.org/2000/svg" id="scene-%s" class="scene" width="%d" height="%d" viewBox="0 0 %s %s" data-lit-area="%s">`+"\n",
+		escape(scene.ID), width, height, num(scene.Width), num(scene.Height), num(scene.LitArea))
+
+	s.printf(`  <rect id="bounds" class="bounds" x="0" y="0" width="%s" height="%s" %s/>`+"\n",
+		num(scene.Width), num(scene.Height), fill(opts.Background))
+
+	s.printf(`  <g id="polygons" %s>`+"\n", fill(opts.Polygon))
+	for i, polygon := range scene.Polygons {
+		class := "polygon"
+		if polygon.IsCurved() {
+			class += " curved"
+		}
+
+		s.printf(`    <path id="polygon-%d" class="%s" data-vertices="%d" d="%s"/>`+"\n", i, class, len(polygon.Loop), s.polygonPath(polygon))
+	}
+	s.printf("  </g>\n")
+
+	// the stroke of the triangles hides the seams between them left by the anti-aliasing of the viewers
+	s.printf(`  <g id="triangles" %s %s stroke-width="0.5" stroke-linejoin="round">`+"\n", fill(opts.Lit), stroke(opts.Lit))
+	for i, triangle := range scene.Triangles {
+		class := "triangle"
+		if triangle.Arc != nil {
+			class += " arc"
+		}
+
+		s.printf(`    <path id="triangle-%d" class="%s" d="%s"/>`+"\n", i, class, s.trianglePath(triangle))
+	}
+	s.printf("  </g>\n")
+
+	s.printf(`  <g id="walls" fill="none" %s stroke-width="%d" stroke-linejoin="round">`+"\n", stroke(opts.Obstacle), wallWidth)
+	for i, wall := range scene.Walls {
+		s.printf(`    <polyline id="wall-%d" class="wall" data-vertices="%d" points="%s"/>`+"\n", i, len(wall.Vertices), s.points(wall.Vertices))
+	}
+	s.printf("  </g>\n")
+
+	s.printf(`  <g id="ellipses" %s>`+"\n", fill(opts.Obstacle))
+	for i, ellipse := range scene.Ellipses {
+		s.printf(`    <ellipse id="ellipse-%d" class="ellipse" cx="%s" cy="%s" rx="%s" ry="%s"/>`+"\n",
+			i, num(ellipse.Center.X), num(s.y(ellipse.Center.Y)), num(ellipse.RadiusX), num(ellipse.RadiusY))
+	}
+	s.printf("  </g>\n")
+
+	s.printf(`  <text id="lit-area" class="lit-area" x="10" y="30" font-family="monospace" font-size="19" %s>Lit area is: %s%%</text>`+"\n",
+		fill(opts.Text), num(scene.LitArea))
+
+	if scene.Light != nil {
+		s.printf(`  <circle id="light" class="light" cx="%s" cy="%s" r="%d" %s/>`+"\n",
+			num(scene.Light.X), num(s.y(scene.Light.Y)), lightRadius*6/10, fill(opts.Light))
+	}
+
+	s.printf("</svg>\n")
+
+	if s.err != nil {
+		return s.err
+	}
+
+	return s.w.Flush()
+}
+
+// svgWriter writes the elements and keeps the first error, so they are checked once at the end
+type svgWriter struct {
+	w      *bufio.Writer
+	height float64
+	err    error
+}
+
+func (s *svgWriter) printf(format string, args ...interface{}) {
+	if s.err == nil {
+		_, s.err = fmt.Fprintf(s.w, format, args...)
+	}
+}
+
+// y flips the Y coordinate of the scene
+func (s *svgWriter) y(y float64) float64 {
+	return s.height - y
+}
+
+func (s *svgWriter) xy(v *vector.Vector) string {
+	return num(v.X) + " " + num(s.y(v.Y))
+}
+
+func (s *svgWriter) points(vertices vector.Vectors) string {
+	points := make([]string, len(vertices))
+	for i, v := range vertices {
+		points[i] = num(v.X) + "," + num(s.y(v.Y))
+	}
+
+	return strings.Join(points, " ")
+}
+
+// polygonPath returns the path data of the polygon, its curved sides are
+// exact quadratic and cubic Bezier curves instead of their flattening
+func (s *svgWriter) polygonPath(polygon *backend.Polygon) string {
+	if len(polygon.Loop) == 0 {
+		return ""
+	}
+
+	d := []string{"M " + s.xy(polygon.Loop[0])}
+	for i := range polygon.Loop {
+		next := polygon.Loop[(i+1)%len(polygon.Loop)]
+
+		var controls vector.Vectors
+		if i < len(polygon.Controls) {
+			controls = polygon.Controls[i]
+		}
+
+		switch len(controls) {
+		case 1:
+			d = append(d, "Q "+s.xy(controls[0])+" "+s.xy(next))
+		case 2:
+			d = append(d, "C "+s.xy(controls[0])+" "+s.xy(controls[1])+" "+s.xy(next))
+		default:
+			if i != len(polygon.Loop)-1 {
+				d = append(d, "L "+s.xy(next))
+			}
+		}
+	}
+
+	return strings.Join(append(d, "Z"), " ")
+}
+
+// trianglePath returns the path data of the lit triangle, when its far
+// side is an arc of an ellipse it is drawn as an elliptical arc
+func (s *svgWriter) trianglePath(triangle *backend.Triangle) string {
+	if len(triangle.Loop) != 3 {
+		return ""
+	}
+
+	d := "M " + s.xy(triangle.Loop[0]) + " L " + s.xy(triangle.Loop[1])
+	if arc := triangle.Arc; arc != nil {
+		// the Y flip turns the counter clockwise arcs of the scene into clockwise
+		// ones, which are drawn with the sweep flag set
+		sweep := 0
+		if math.Remainder(arc.To-arc.From, 2*math.Pi) > 0 {
+			sweep = 1
+		}
+
+		return d + fmt.Sprintf(" A %s %s 0 0 %d %s Z", num(arc.Ellipse.RadiusX), num(arc.Ellipse.RadiusY), sweep, s.xy(triangle.Loop[2]))
+	}
+
+	return d + " L " + s.xy(triangle.Loop[2]) + " Z"
+}
+
+// num formats the number with at most 3 decimals
+func num(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1000)/1000, 'f', -1, 64)
+}
+
+func escape(s string) string {
+	b := &strings.Builder{}
+	xml.EscapeText(b, []byte(s))
+	return b.String()
+}
+
+func fill(c color.RGBA) string {
+	return paint("fill", c)
+}
+
+func stroke(c color.RGBA) string {
+	return paint("stroke", c)
+}
+
+// paint returns the attributes of the color, with an opacity when it is translucent
+func paint(attr string, c color.RGBA) string {
+	result := fmt.Sprintf(`%s="#%02x%02x%02x"`, attr, c.R, c.G, c.B)
+	if c.A != 255 {
+		result += fmt.Sprintf(` %s-opacity="%s"`, attr, num(float64(c.A)/255))
+	}
+
+	return result
+}
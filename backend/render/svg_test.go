@@ -0,0 +1,57 @@
+package render_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+	"github.com/iliyanmotovski/raytracer/backend/render"
+	"github.com/iliyanmotovski/raytracer/backend/vector"
+)
+
+func TestRenderSVG(t *testing.T) {
+	scene := loadScene(t, &backend.Config{
+		ID:    backend.DefaultSceneID,
+		Light: &vector.Vector{X: 250, Y: 300},
+		Scene: &vector.Vector{X: 800, Y: 500},
+		Polygons: backend.Polygons{
+			{VerticesCount: 3, Loop: vector.Loop{{X: 600, Y: 200}, {X: 646, Y: 133}, {X: 646, Y: 261}}},
+			{
+				VerticesCount: 3,
+				Loop:          vector.Loop{{X: 300, Y: 100}, {X: 346, Y: 33}, {X: 346, Y: 161}},
+				Controls:      []vector.Vectors{{{X: 310, Y: 50}}, nil, {{X: 330, Y: 150}, {X: 310, Y: 130}}},
+			},
+		},
+		Walls: backend.Walls{
+			{VerticesCount: 3, Vertices: vector.Vectors{{X: 100, Y: 100}, {X: 200, Y: 100}, {X: 200, Y: 50}}},
+		},
+		Ellipses: backend.Ellipses{
+			{Center: &vector.Vector{X: 400, Y: 400}, RadiusX: 50, RadiusY: 30},
+		},
+	})
+
+	opts := render.DefaultOptions()
+	opts.Width = 400
+	assert.Nil(t, opts.SetColor("lit", "fff5d0c0"))
+
+	buf := &bytes.Buffer{}
+	assert.Nil(t, render.SVG(buf, scene, opts))
+
+	path := filepath.Join("testdata", "scene.svg")
+	if *update {
+		assert.Nil(t, ioutil.WriteFile(path, buf.Bytes(), 0644))
+	}
+
+	want, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, string(want), buf.String(), "run the tests with -update to accept the changes")
+}
+
+func TestRenderSVGWithoutSize(t *testing.T) {
+	err := render.SVG(&bytes.Buffer{}, &backend.Scene{}, render.DefaultOptions())
+	assert.NotNil(t, err)
+}
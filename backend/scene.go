@@ -2,63 +2,116 @@ package backend
 
 import (
 	"context"
-	"log"
 	"math"
+	"regexp"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/iliyanmotovski/raytracer/backend/logging"
+	"github.com/iliyanmotovski/raytracer/backend/tracing"
 	"github.com/iliyanmotovski/raytracer/backend/vector"
 )
 
-// SceneRepository is an abstraction over some repository
+// DefaultSceneID is the ID of the scene loaded from the config file
+// and served by the endpoints which do not specify a scene
+const DefaultSceneID = "default"
+
+// sceneIDPattern restricts the scene IDs, so they are safe to be used in file names and URLs
+var sceneIDPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// SceneRepository is an abstraction over some repository which keeps the scenes
+// by their ID, every Upsert creates a new version of the scene with the ID of the
+// given one and Get returns the latest version or nil if there is no such scene,
+// the same as LatestVersion returns the version of the latest scene or nil
 type SceneRepository interface {
-	Get(context.Context) (*Scene, error)
+	Get(ctx context.Context, sceneID string) (*Scene, error)
 	Upsert(context.Context, *Scene) (*Scene, error)
+	ListVersions(ctx context.Context, sceneID string) (Versions, error)
+	LatestVersion(ctx context.Context, sceneID string) (*Version, error)
+	GetVersion(ctx context.Context, sceneID string, version int) (*Scene, error)
+}
+
+// ValidateSceneID returns ErrInvalidSceneID if the ID is empty, longer than
+// 64 characters or contains anything but letters, digits, '_' and '-'
+func ValidateSceneID(sceneID string) error {
+	if !sceneIDPattern.MatchString(sceneID) {
+		return ErrInvalidSceneID
+	}
+
+	return nil
 }
 
 // Scene represents the state of the scene
 type Scene struct {
+	ID                     string
 	Width, Height, LitArea float64
+	FlatteningTolerance    float64
 	Light                  *vector.Vector
 	Polygons               Polygons
+	Walls                  Walls
+	Ellipses               Ellipses
 	Triangles              Triangles
 	Boundaries             Boundaries
+	// Rays is the number of rays cast from the light to get the triangles
+	Rays int
 }
 
 // NewScene creates a new Scene with the 4 basic boundaries - up, right, down, left in this order
 func NewScene(config *Config) *Scene {
-	width := config.Scene.X
-	height := config.Scene.Y
+	return &Scene{
+		ID:                  config.ID,
+		Width:               config.Scene.X,
+		Height:              config.Scene.Y,
+		FlatteningTolerance: config.FlatteningTolerance,
+		Light:               config.Light,
+		Boundaries:          edgeBoundaries(config.Scene.X, config.Scene.Y),
+		Polygons:            config.Polygons,
+		Walls:               config.Walls,
+		Ellipses:            config.Ellipses,
+	}
+}
 
+// edgeBoundaries returns the 4 boundaries of the scene edges - up, right, down, left in this order
+func edgeBoundaries(width, height float64) Boundaries {
 	b := make(Boundaries, 4)
 	b[0] = &Boundary{vector.Edge{A: &vector.Vector{0, 0}, B: &vector.Vector{width, 0}}}
 	b[1] = &Boundary{vector.Edge{A: &vector.Vector{width, 0}, B: &vector.Vector{width, height}}}
 	b[2] = &Boundary{vector.Edge{A: &vector.Vector{width, height}, B: &vector.Vector{0, height}}}
 	b[3] = &Boundary{vector.Edge{A: &vector.Vector{0, height}, B: &vector.Vector{0, 0}}}
 
-	return &Scene{Width: width, Height: height, Light: config.Light, Boundaries: b, Polygons: config.Polygons}
+	return b
+}
+
+// Config returns the configuration the scene was created from
+func (s *Scene) Config() *Config {
+	return &Config{
+		ID:                  s.ID,
+		Light:               &vector.Vector{X: s.Light.X, Y: s.Light.Y},
+		Scene:               &vector.Vector{X: s.Width, Y: s.Height},
+		Polygons:            s.Polygons,
+		Walls:               s.Walls,
+		Ellipses:            s.Ellipses,
+		FlatteningTolerance: s.FlatteningTolerance,
+	}
 }
 
 // Load reloads the scene with the new configuration and persists it
 func (s *Scene) Load(ctx context.Context, repo SceneRepository) (*Scene, error) {
-	triangles, litArea, err := s.Process()
+	ctx, span := tracing.Start(ctx, "scene.load", "scene", s.ID)
+	defer span.End()
+
+	scene, err := s.process(ctx)
 	if err != nil {
+		span.SetError(err)
 		return &Scene{}, err
 	}
 
-	log.Printf("lit area is %v percent", litArea)
-
-	scene := &Scene{
-		Width:      s.Width,
-		Height:     s.Height,
-		LitArea:    litArea,
-		Light:      s.Light,
-		Polygons:   s.Polygons,
-		Triangles:  triangles,
-		Boundaries: s.Boundaries,
-	}
+	logging.FromContext(ctx).Debug("scene processed", "scene", s.ID, "lit_area", scene.LitArea, "rays", scene.Rays)
 
 	persisted, err := repo.Upsert(ctx, scene)
 	if err != nil {
+		span.SetError(err)
 		return &Scene{}, err
 	}
 
@@ -67,74 +120,732 @@ func (s *Scene) Load(ctx context.Context, repo SceneRepository) (*Scene, error)
 
 // Process creates a new particle and casts all rays, returns the triangles
 // which represent the lit area, the lit area's area in % of the whole scene
-// and an error if any. It Validates the polygons as well
-func (s *Scene) Process() (Triangles, float64, error) {
-	for _, polygon := range s.Polygons {
-		s.Boundaries = append(s.Boundaries, polygon.GetBoundaries()...)
+// and an error if any. It Validates the polygons, walls and ellipses as well.
+// The context is checked periodically during both the validation and the ray
+// casting, which stop with ErrContextCancelled or ErrContextExpired when it is done.
+// The scene is left untouched, so it is safe to process it many times concurrently
+func (s *Scene) Process(ctx context.Context) (Triangles, float64, error) {
+	scene, err := s.process(ctx)
+	if err != nil {
+		return Triangles{}, 0, err
 	}
 
-	if err := s.Polygons.Validate(s.Width, s.Height); err != nil {
-		return Triangles{}, 0, err
+	return scene.Triangles, scene.LitArea, nil
+}
+
+// process is the same as Process, but returns a copy of the scene with the triangles,
+// the lit area, the boundaries the rays were cast against and the number of the rays
+func (s *Scene) process(ctx context.Context) (*Scene, error) {
+	ctx, span := tracing.Start(ctx, "scene.process", "scene", s.ID)
+	defer span.End()
+
+	// curved sides are cast against as their flattened approximation
+	_, boundariesSpan := tracing.Start(ctx, "scene.boundaries")
+	polygons := s.flatten(s.Light)
+	boundaries := s.boundaries(polygons)
+	boundariesSpan.SetAttributes("polygons", len(polygons), "boundaries", len(boundaries))
+	boundariesSpan.End()
+
+	validateCtx, validateSpan := tracing.Start(ctx, "scene.validate")
+	err := s.validate(validateCtx, polygons)
+	validateSpan.SetError(err)
+	validateSpan.End()
+	if err != nil {
+		span.SetError(err)
+		return &Scene{}, err
+	}
+
+	particle := NewParticle(s.Light.X, s.Light.Y, boundaries[0:4])
+	triangles, err := particle.Process(ctx, boundaries, polygons, s.Walls, s.Ellipses)
+	if err != nil {
+		span.SetError(err)
+		return &Scene{}, err
+	}
+
+	return &Scene{
+		ID:                  s.ID,
+		Width:               s.Width,
+		Height:              s.Height,
+		LitArea:             s.litAreaPercentage(triangles),
+		FlatteningTolerance: s.FlatteningTolerance,
+		Light:               s.Light,
+		Polygons:            s.Polygons,
+		Walls:               s.Walls,
+		Ellipses:            s.Ellipses,
+		Triangles:           triangles,
+		Boundaries:          boundaries,
+		Rays:                len(particle.Rays),
+	}, nil
+}
+
+// Validate validates the polygons, the walls and the ellipses of the scene
+// the same way as Process does, but without casting the rays
+func (s *Scene) Validate(ctx context.Context) error {
+	return s.validate(ctx, s.flatten(s.Light))
+}
+
+// validate validates the scene with its already flattened polygons
+func (s *Scene) validate(ctx context.Context, polygons Polygons) error {
+	if err := polygons.Validate(ctx, s.Width, s.Height); err != nil {
+		return err
+	}
+
+	if err := s.Walls.Validate(ctx, s.Width, s.Height, polygons); err != nil {
+		return err
+	}
+
+	return s.Ellipses.Validate(ctx, s.Width, s.Height, polygons, s.Walls)
+}
+
+// flatten returns the polygons of the scene with their curved sides
+// flattened within the flattening tolerance, as seen from the light
+func (s *Scene) flatten(light *vector.Vector) Polygons {
+	tolerance := s.FlatteningTolerance
+	if tolerance <= 0 {
+		tolerance = DefaultFlatteningTolerance
+	}
+
+	return s.Polygons.Flatten(light, tolerance)
+}
+
+// boundaries returns a new slice with the 4 scene edges followed
+// by the sides of the given polygons and the segments of the walls
+func (s *Scene) boundaries(polygons Polygons) Boundaries {
+	boundaries := edgeBoundaries(s.Width, s.Height)
+
+	for _, polygon := range polygons {
+		boundaries = append(boundaries, polygon.GetBoundaries()...)
+	}
+
+	for _, wall := range s.Walls {
+		boundaries = append(boundaries, wall.GetBoundaries()...)
+	}
+
+	return boundaries
+}
+
+// MoveLight moves the light of the already loaded scene and persists the result as a new
+// version. The polygons, walls and ellipses were validated when the scene was loaded, so
+// unlike Load it does not validate them again and reuses the cached boundaries, only the
+// visibility fan of the new light position is recomputed. It returns ErrLightOutside
+// when the light is not within the scene
+func (s *Scene) MoveLight(ctx context.Context, repo SceneRepository, light *vector.Vector) (*Scene, error) {
+	ctx, span := tracing.Start(ctx, "scene.move_light", "scene", s.ID)
+	defer span.End()
+
+	scene, err := s.lit(ctx, light)
+	if err != nil {
+		span.SetError(err)
+		return &Scene{}, err
+	}
+
+	persisted, err := repo.Upsert(ctx, scene)
+	if err != nil {
+		span.SetError(err)
+		return &Scene{}, err
+	}
+
+	return persisted, nil
+}
+
+// PreviewLight is the same as MoveLight, but the result is not persisted, so
+// the positions of a light which is being dragged do not flood the versions
+func (s *Scene) PreviewLight(ctx context.Context, light *vector.Vector) (*Scene, error) {
+	ctx, span := tracing.Start(ctx, "scene.preview_light", "scene", s.ID)
+	defer span.End()
+
+	scene, err := s.lit(ctx, light)
+	span.SetError(err)
+
+	return scene, err
+}
+
+// lit returns a copy of the scene lit from the light, see MoveLight
+func (s *Scene) lit(ctx context.Context, light *vector.Vector) (*Scene, error) {
+	if light.X < 0 || light.X > s.Width || light.Y < 0 || light.Y > s.Height {
+		return &Scene{}, ErrLightOutside
 	}
 
-	particle := NewParticle(s.Light.X, s.Light.Y, s.Boundaries[0:4])
-	triangles := particle.Process(s.Boundaries, s.Polygons)
+	// the flattened curves depend on the light position, so
+	// their cached boundaries are stale and all are rebuilt
+	_, boundariesSpan := tracing.Start(ctx, "scene.boundaries")
+	polygons := s.flatten(light)
+	boundaries := s.Boundaries
+	if len(boundaries) < 4 || s.Polygons.IsCurved() {
+		boundaries = s.boundaries(polygons)
+	}
+	boundariesSpan.SetAttributes("polygons", len(polygons), "boundaries", len(boundaries))
+	boundariesSpan.End()
 
+	particle := NewParticle(light.X, light.Y, boundaries[0:4])
+	triangles, err := particle.Process(ctx, boundaries, polygons, s.Walls, s.Ellipses)
+	if err != nil {
+		return &Scene{}, err
+	}
+
+	return &Scene{
+		ID:                  s.ID,
+		Width:               s.Width,
+		Height:              s.Height,
+		LitArea:             s.litAreaPercentage(triangles),
+		FlatteningTolerance: s.FlatteningTolerance,
+		Light:               &vector.Vector{X: light.X, Y: light.Y},
+		Polygons:            s.Polygons,
+		Walls:               s.Walls,
+		Ellipses:            s.Ellipses,
+		Triangles:           triangles,
+		Boundaries:          boundaries,
+		Rays:                len(particle.Rays),
+	}, nil
+}
+
+// litAreaPercentage returns the area of the triangles in % of the whole scene
+func (s *Scene) litAreaPercentage(triangles Triangles) float64 {
 	totalArea := s.Width * s.Height
 	litArea := triangles.Area()
 
-	litAreaPercentage := math.Round(((litArea/totalArea)*100)*100) / 100
-	return triangles, litAreaPercentage, nil
+	return math.Round(((litArea/totalArea)*100)*100) / 100
 }
 
 // SceneReloadDaemon represents a daemon which listens for new scene configuration
 // over the provided channel and generates the new scene according the config,
-// then sends back the newly generated scene and an error of any on the provided
-// return channel
+// then sends back the newly generated scene and an error if any on the response
+// channel of the job.
+// Only the latest pending config of each scene is processed, when a newer config
+// of the same scene arrives before the pending one is picked by a worker, the
+// pending one is dropped and its caller gets ErrSuperseded. The configs of a
+// single scene are never processed concurrently, so the latest one always wins.
+// The workers also run the tasks sent with RunTask, see ConfigChan.Task
 type SceneReloadDaemon struct {
-	sceneRepo               SceneRepository
-	configChan              chan *ConfigChan
-	sceneReloadResponseChan SceneReloadResponseChanFactory
+	// the counters are accessed atomically and must stay
+	// 64-bit aligned, so they are kept at the top
+	processed, superseded, cancelled, tasks int64
+
+	sceneRepo  SceneRepository
+	configChan chan *ConfigChan
+	timeout    time.Duration
+	observe    func(*JobStats)
+
+	once    sync.Once
+	workers sync.WaitGroup
+	// abort is closed to cancel the jobs when the daemon does not stop in time
+	abort     chan struct{}
+	abortOnce sync.Once
+
+	mu   sync.Mutex
+	cond *sync.Cond
+	// queue holds the IDs of the scenes with a pending job in order of arrival
+	queue   []string
+	pending map[string]*ConfigChan
+	running map[string]bool
+	// pendingTasks holds the tasks in order of arrival, they are not coalesced
+	pendingTasks []*ConfigChan
+	runningTasks int
+	closed       bool
+	// alive is the number of the started workers which have not stopped yet
+	alive int
+	// heartbeat is when a worker has last started, picked a job or finished one
+	heartbeat time.Time
+	// waitingSince is when a job has started waiting for a worker while none was waiting
+	waitingSince time.Time
+}
+
+// JobKind is the kind of a job processed by the SceneReloadDaemon
+type JobKind string
+
+const (
+	// ConfigJob processes a whole config
+	ConfigJob JobKind = "config"
+	// LightJob moves the light of a persisted scene
+	LightJob JobKind = "light"
+	// TaskJob runs a task sent with RunTask
+	TaskJob JobKind = "task"
+)
+
+// JobSource is where a job processed by the SceneReloadDaemon comes from
+type JobSource string
+
+const (
+	// InitialSource is the config loaded when the server starts
+	InitialSource JobSource = "initial"
+	// HotReloadSource is the config reloaded when its file changes or on SIGHUP
+	HotReloadSource JobSource = "hotreload"
+	// APISource is a job sent by an API handler, it is the source of the jobs which do not set one
+	APISource JobSource = "api"
+)
+
+// JobStats describes a job processed by a worker of the SceneReloadDaemon
+type JobStats struct {
+	Kind     JobKind
+	Source   JobSource
+	Duration time.Duration
+	// Scene is the persisted scene, it is nil for the tasks and the failed jobs
+	Scene *Scene
+	Err   error
+}
+
+// DaemonMetrics holds the counters of the jobs handled by the SceneReloadDaemon
+type DaemonMetrics struct {
+	// Processed is the number of processed configs, valid or not
+	Processed int64
+	// Superseded is the number of pending jobs dropped because
+	// a newer config of the same scene has arrived
+	Superseded int64
+	// Cancelled is the number of jobs dropped because their
+	// context was done before a worker has picked them
+	Cancelled int64
+	// Tasks is the number of run tasks
+	Tasks int64
+	// Pending is the number of jobs waiting for a worker
+	Pending int64
+	// Running is the number of jobs being processed
+	Running int64
 }
 
 // NewSceneReloadDaemon creates new SceneReloadDaemon
-func NewSceneReloadDaemon(sceneRepo SceneRepository, cc chan *ConfigChan, srrc SceneReloadResponseChanFactory) *SceneReloadDaemon {
-	return &SceneReloadDaemon{
-		sceneRepo:               sceneRepo,
-		configChan:              cc,
-		sceneReloadResponseChan: srrc,
+func NewSceneReloadDaemon(sceneRepo SceneRepository, cc chan *ConfigChan) *SceneReloadDaemon {
+	d := &SceneReloadDaemon{
+		sceneRepo:  sceneRepo,
+		configChan: cc,
+		pending:    map[string]*ConfigChan{},
+		running:    map[string]bool{},
+		abort:      make(chan struct{}),
+	}
+
+	d.cond = sync.NewCond(&d.mu)
+	return d
+}
+
+// SetProcessingTimeout limits the time in which each config must be processed and its
+// scene persisted, on top of the deadline of the job context. Zero means no limit,
+// it must be called before Start
+func (d *SceneReloadDaemon) SetProcessingTimeout(timeout time.Duration) {
+	d.timeout = timeout
+}
+
+// SetObserver sets a func which is called with every processed job once its response
+// is sent, e.g. to export metrics, the jobs dropped before they are processed are not
+// observed. The func is called concurrently by the workers, it must be set before Start
+func (d *SceneReloadDaemon) SetObserver(observe func(*JobStats)) {
+	d.observe = observe
+}
+
+// Metrics returns the current counters of the daemon
+func (d *SceneReloadDaemon) Metrics() DaemonMetrics {
+	d.mu.Lock()
+	pending := int64(d.waiting())
+	running := int64(len(d.running) + d.runningTasks)
+	d.mu.Unlock()
+
+	return DaemonMetrics{
+		Processed:  atomic.LoadInt64(&d.processed),
+		Superseded: atomic.LoadInt64(&d.superseded),
+		Cancelled:  atomic.LoadInt64(&d.cancelled),
+		Tasks:      atomic.LoadInt64(&d.tasks),
+		Pending:    pending,
+		Running:    running,
+	}
+}
+
+// Heartbeat returns when a worker has last started, picked a job or finished one,
+// it is the zero time until the daemon is started
+func (d *SceneReloadDaemon) Heartbeat() time.Time {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.heartbeat
+}
+
+// Stalled returns whether the daemon is wedged: no worker is running, or jobs have been waiting
+// for a worker for longer than the duration while none of the workers has picked or finished
+// a job, e.g. because all of them are stuck processing configs which take too long. A daemon
+// without any waiting job is never stalled, however long its workers are idle
+func (d *SceneReloadDaemon) Stalled(after time.Duration) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.alive == 0 {
+		return true
+	}
+
+	if d.waiting() == 0 {
+		return false
+	}
+
+	since := d.heartbeat
+	if d.waitingSince.After(since) {
+		since = d.waitingSince
 	}
+
+	return time.Since(since) > after
+}
+
+// waiting returns the number of the jobs waiting for a worker, it must be called with the lock held
+func (d *SceneReloadDaemon) waiting() int {
+	return len(d.pending) + len(d.pendingTasks)
 }
 
-// Start starts the scene reload daemon with provided workers
+// Start starts the scene reload daemon with provided workers, the workers
+// stop when the config chan is closed and all pending jobs are processed
 func (d *SceneReloadDaemon) Start(workers int) {
+	d.once.Do(func() {
+		go d.dispatch()
+	})
+
+	d.mu.Lock()
+	d.alive += workers
+	d.heartbeat = time.Now()
+	d.mu.Unlock()
+
+	d.workers.Add(workers)
 	for i := 0; i < workers; i++ {
 		go func() {
-			for c := range d.configChan {
-				start := time.Now()
+			defer d.workers.Done()
+			defer func() {
+				d.mu.Lock()
+				d.alive--
+				d.mu.Unlock()
+			}()
+
+			for c := d.next(); c != nil; c = d.next() {
+				d.process(c)
+				d.done(c)
+			}
+		}()
+	}
+}
 
-				scene := NewScene(c.Config)
-				loaded, err := scene.Load(c.Ctx, d.sceneRepo)
+// Wait blocks until the workers stop, which happens once the config chan is closed and
+// all pending jobs are processed. When the context is done first, the jobs are aborted:
+// the running ones are cancelled, the pending ones are dropped with ErrContextCancelled
+// and Wait returns ErrContextCancelled or ErrContextExpired once the workers stop
+func (d *SceneReloadDaemon) Wait(ctx context.Context) error {
+	stopped := make(chan struct{})
+	go func() {
+		d.workers.Wait()
+		close(stopped)
+	}()
 
-				srrc := d.sceneReloadResponseChan[c.ResponseChan]
-				srrc <- &SceneReloadResponse{
-					Scene: loaded,
-					Err:   err,
-				}
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+	}
+
+	d.abortOnce.Do(func() { close(d.abort) })
+	<-stopped
 
-				end := time.Now()
-				log.Printf("config containing (%d) polygons processed for: %v", len(c.Config.Polygons), end.Sub(start))
+	return ContextError(ctx)
+}
+
+// dispatch receives the jobs from the config chan and queues them,
+// replacing the pending job of the same scene if there is one
+func (d *SceneReloadDaemon) dispatch() {
+	for c := range d.configChan {
+		d.mu.Lock()
+
+		if d.waiting() == 0 {
+			d.waitingSince = time.Now()
+		}
+
+		if c.Task != nil {
+			d.pendingTasks = append(d.pendingTasks, c)
+			d.cond.Broadcast()
+			d.mu.Unlock()
+			continue
+		}
+
+		if superseded, ok := d.pending[c.Config.ID]; ok {
+			// a light move is applied on top of the pending full config, so the config is not lost
+			if c.LightOnly && !superseded.LightOnly {
+				config := *superseded.Config
+				config.Light = c.Config.Light
+				c.Config, c.LightOnly = &config, false
 			}
-		}()
+
+			// a preview of the light is stored once it replaces a job which is stored
+			if !superseded.Preview {
+				c.Preview = false
+			}
+
+			superseded.Response <- &SceneReloadResponse{Scene: &Scene{}, Err: ErrSuperseded}
+			logging.FromContext(superseded.Ctx).Debug("config superseded", "scene", c.Config.ID, "by", logging.RequestID(c.Ctx))
+			atomic.AddInt64(&d.superseded, 1)
+		} else {
+			d.queue = append(d.queue, c.Config.ID)
+		}
+
+		d.pending[c.Config.ID] = c
+		d.cond.Broadcast()
+		d.mu.Unlock()
+	}
+
+	d.mu.Lock()
+	d.closed = true
+	d.cond.Broadcast()
+	d.mu.Unlock()
+}
+
+// next blocks until there is a pending job of a scene which is not being processed
+// or a pending task and returns it, or nil when the config chan is closed and no job
+// is left. The configs go first, so the tasks do not slow down the interactive reloads
+func (d *SceneReloadDaemon) next() *ConfigChan {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for {
+		for i, id := range d.queue {
+			if d.running[id] {
+				continue
+			}
+
+			c := d.pending[id]
+			d.queue = append(d.queue[:i], d.queue[i+1:]...)
+			delete(d.pending, id)
+			d.running[id] = true
+			d.heartbeat = time.Now()
+			return c
+		}
+
+		if len(d.pendingTasks) > 0 {
+			c := d.pendingTasks[0]
+			d.pendingTasks = d.pendingTasks[1:]
+			d.runningTasks++
+			d.heartbeat = time.Now()
+			return c
+		}
+
+		if d.closed && len(d.queue) == 0 {
+			return nil
+		}
+
+		d.cond.Wait()
+	}
+}
+
+// done marks that the job is processed, so the next one of the same scene can be picked
+func (d *SceneReloadDaemon) done(c *ConfigChan) {
+	d.mu.Lock()
+	if c.Task != nil {
+		d.runningTasks--
+	} else {
+		delete(d.running, c.Config.ID)
+	}
+	d.heartbeat = time.Now()
+	d.cond.Broadcast()
+	d.mu.Unlock()
+}
+
+// process generates the scene of the job and sends it back on its response chan
+func (d *SceneReloadDaemon) process(c *ConfigChan) {
+	// the caller has already given up or the jobs are aborted, so the config is not processed at all
+	err := ContextError(c.Ctx)
+	select {
+	case <-d.abort:
+		err = ErrContextCancelled
+	default:
+	}
+
+	if err != nil {
+		atomic.AddInt64(&d.cancelled, 1)
+		c.Response <- &SceneReloadResponse{Scene: &Scene{}, Err: err}
+		logging.FromContext(c.Ctx).Debug("job dropped", "error", err)
+		return
+	}
+
+	ctx, cancel := d.jobContext(c.Ctx)
+	start := time.Now()
+
+	if c.Task != nil {
+		ctx, span := tracing.Start(ctx, "daemon.job", "kind", TaskJob, "source", c.Source)
+		err := c.Task(ctx)
+		cancel()
+		// the span ends before the response is sent, so it is part of the trace of the caller
+		span.SetError(err)
+		span.End()
+
+		atomic.AddInt64(&d.tasks, 1)
+		c.Response <- &SceneReloadResponse{Scene: &Scene{}, Err: err}
+		d.observed(&JobStats{Kind: TaskJob, Source: c.Source, Duration: time.Since(start), Err: err})
+		return
+	}
+
+	kind := ConfigJob
+	if c.LightOnly {
+		kind = LightJob
+	}
+
+	ctx, span := tracing.Start(ctx, "daemon.job", "kind", kind, "source", c.Source, "scene", c.Config.ID)
+	var loaded *Scene
+	if c.LightOnly {
+		loaded, err = d.moveLight(ctx, c.Config, c.Preview)
+	} else {
+		loaded, err = NewScene(c.Config).Load(ctx, d.sceneRepo)
+	}
+	cancel()
+	span.SetError(err)
+	span.End()
+
+	atomic.AddInt64(&d.processed, 1)
+	c.Response <- &SceneReloadResponse{
+		Scene: loaded,
+		Err:   err,
+	}
+
+	stats := &JobStats{Kind: kind, Source: c.Source, Duration: time.Since(start), Err: err}
+	if err == nil {
+		stats.Scene = loaded
+	}
+	d.observed(stats)
+
+	logger := logging.FromContext(c.Ctx).With("scene", c.Config.ID, "source", c.Source, "duration", stats.Duration)
+	switch {
+	case err != nil && c.LightOnly:
+		logger.Warn("light not moved", "error", err)
+	case err != nil:
+		logger.Warn("config not processed", "polygons", len(c.Config.Polygons), "error", err)
+	case c.LightOnly:
+		logger.Info("light moved", "lit_area", loaded.LitArea)
+	default:
+		logger.Info("config processed", "polygons", len(c.Config.Polygons), "rays", loaded.Rays, "lit_area", loaded.LitArea)
+	}
+}
+
+// observed passes the stats of the processed job to the observer if there is one
+func (d *SceneReloadDaemon) observed(stats *JobStats) {
+	if d.observe != nil {
+		d.observe(stats)
 	}
 }
 
-// ConfigChan represents the data sent through the
-// actual config chan
+// jobContext returns the context of the job limited by the processing timeout,
+// which is also cancelled when the jobs are aborted
+func (d *SceneReloadDaemon) jobContext(parent context.Context) (context.Context, context.CancelFunc) {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if d.timeout > 0 {
+		ctx, cancel = context.WithTimeout(parent, d.timeout)
+	} else {
+		ctx, cancel = context.WithCancel(parent)
+	}
+
+	go func() {
+		select {
+		case <-d.abort:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, cancel
+}
+
+// moveLight moves the light of the persisted scene to the light of the config,
+// a preview is only computed and not persisted, see Scene.PreviewLight
+func (d *SceneReloadDaemon) moveLight(ctx context.Context, config *Config, preview bool) (*Scene, error) {
+	scene, err := d.sceneRepo.Get(ctx, config.ID)
+	if err != nil {
+		return &Scene{}, err
+	}
+
+	if scene == nil {
+		return &Scene{}, ErrSceneNotFound
+	}
+
+	if preview {
+		return scene.PreviewLight(ctx, config.Light)
+	}
+
+	return scene.MoveLight(ctx, d.sceneRepo, config.Light)
+}
+
+// ReloadScene sends the config to the daemon through the config chan and waits for the
+// newly generated scene. It gives up as soon as the context is done, both while waiting
+// for a free worker and for the response, and returns ErrContextCancelled or ErrContextExpired.
+// It returns ErrSuperseded when a newer config of the same scene replaced this one
+// before it was processed
+func ReloadScene(ctx context.Context, cc chan<- *ConfigChan, config *Config) (*Scene, error) {
+	return ReloadSceneFrom(ctx, cc, config, APISource)
+}
+
+// ReloadSceneFrom is the same as ReloadScene, but the job is marked with the source
+// instead of APISource, e.g. for the config loaded on startup or hot reloaded
+func ReloadSceneFrom(ctx context.Context, cc chan<- *ConfigChan, config *Config, source JobSource) (*Scene, error) {
+	job := NewConfigChan(ctx, config)
+	job.Source = source
+
+	return sendJob(ctx, cc, job)
+}
+
+// MoveSceneLight sends the new light position of the scene to the daemon, which moves
+// the light of the persisted scene without processing its whole config again, see
+// Scene.MoveLight. It returns ErrSceneNotFound when there is no such scene, otherwise
+// it behaves the same way as ReloadScene
+func MoveSceneLight(ctx context.Context, cc chan<- *ConfigChan, sceneID string, light *vector.Vector) (*Scene, error) {
+	job := NewConfigChan(ctx, &Config{ID: sceneID, Light: light})
+	job.LightOnly = true
+
+	return sendJob(ctx, cc, job)
+}
+
+// PreviewSceneLight is the same as MoveSceneLight, but the moved scene is not stored
+// as a new version, see Scene.PreviewLight. It is used while the light is dragged
+func PreviewSceneLight(ctx context.Context, cc chan<- *ConfigChan, sceneID string, light *vector.Vector) (*Scene, error) {
+	job := NewConfigChan(ctx, &Config{ID: sceneID, Light: light})
+	job.LightOnly, job.Preview = true, true
+
+	return sendJob(ctx, cc, job)
+}
+
+// RunTask sends the task to the daemon and waits until one of its workers runs it, the task
+// gets the context limited by the processing timeout of the daemon. It gives up the same
+// way as ReloadScene and returns the error of the task otherwise
+func RunTask(ctx context.Context, cc chan<- *ConfigChan, task func(ctx context.Context) error) error {
+	job := NewConfigChan(ctx, nil)
+	job.Task = task
+
+	_, err := sendJob(ctx, cc, job)
+	return err
+}
+
+// sendJob sends the job to the daemon and waits for its response or the context to be done
+func sendJob(ctx context.Context, cc chan<- *ConfigChan, job *ConfigChan) (*Scene, error) {
+	select {
+	case cc <- job:
+	case <-ctx.Done():
+		return &Scene{}, ContextError(ctx)
+	}
+
+	select {
+	case resp := <-job.Response:
+		return resp.Scene, resp.Err
+	case <-ctx.Done():
+		return &Scene{}, ContextError(ctx)
+	}
+}
+
+// ConfigChan represents the job sent through the actual config chan,
+// the daemon sends the result of processing it on its own Response chan
 type ConfigChan struct {
-	Ctx          context.Context
-	Config       *Config
-	ResponseChan string
+	Ctx    context.Context
+	Config *Config
+	// LightOnly marks that the config holds only the scene ID and the new light
+	// position, which is applied to the persisted scene as it is
+	LightOnly bool
+	// Preview marks a light move which is not stored as a new version of the scene
+	Preview bool
+	// Source is where the job comes from, see JobSource
+	Source JobSource
+	// Task, when set, is run by a worker instead of processing the config, which is nil.
+	// The tasks are never superseded, any number of them run concurrently
+	Task     func(ctx context.Context) error
+	Response chan *SceneReloadResponse
+}
+
+// NewConfigChan creates a new job with a Response chan which has room for the
+// response, so the daemon never blocks on callers which are no longer waiting
+func NewConfigChan(ctx context.Context, config *Config) *ConfigChan {
+	return &ConfigChan{Ctx: ctx, Config: config, Source: APISource, Response: make(chan *SceneReloadResponse, 1)}
 }
 
 // SceneReloadResponse represents the response sent back from the daemon
@@ -142,7 +853,3 @@ type SceneReloadResponse struct {
 	Scene *Scene
 	Err   error
 }
-
-// SceneReloadResponseChanFactory represents a factory of all return channels
-// which the daemon picks with key ResponseChan field from ConfigChan struct
-type SceneReloadResponseChanFactory map[string]chan *SceneReloadResponse
@@ -1,14 +1,22 @@
 package backend_test
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 
 	"github.com/iliyanmotovski/raytracer/backend"
+	"github.com/iliyanmotovski/raytracer/backend/logging"
+	"github.com/iliyanmotovski/raytracer/backend/persistent"
 	"github.com/iliyanmotovski/raytracer/backend/vector"
 )
 
@@ -50,6 +58,8 @@ func TestSceneLoading(t *testing.T) {
 
 	persisted := backend.NewScene(config)
 	persisted.LitArea = 93.38
+	// the 8 base rays and 2 for each vertice
+	persisted.Rays = 14
 	json.Unmarshal([]byte(trianglesData), &persisted.Triangles)
 	json.Unmarshal([]byte(boundariesData), &persisted.Boundaries)
 
@@ -103,6 +113,8 @@ func TestSceneLoadingWithRepositoryFailure(t *testing.T) {
 
 	persisted := backend.NewScene(config)
 	persisted.LitArea = 93.38
+	// the 8 base rays and 2 for each vertice
+	persisted.Rays = 14
 	json.Unmarshal([]byte(trianglesData), &persisted.Triangles)
 	json.Unmarshal([]byte(boundariesData), &persisted.Boundaries)
 
@@ -155,6 +167,8 @@ func TestSceneReloadDaemon(t *testing.T) {
 
 	persisted := backend.NewScene(config)
 	persisted.LitArea = 93.38
+	// the 8 base rays and 2 for each vertice
+	persisted.Rays = 14
 	json.Unmarshal([]byte(trianglesData), &persisted.Triangles)
 	json.Unmarshal([]byte(boundariesData), &persisted.Boundaries)
 
@@ -162,23 +176,775 @@ func TestSceneReloadDaemon(t *testing.T) {
 	sceneRepo.On("Upsert", persisted).Return(persisted, nil)
 
 	cc := make(chan *backend.ConfigChan)
-	srrc := make(chan *backend.SceneReloadResponse)
 
-	srrcFactory := map[string]chan *backend.SceneReloadResponse{"test": srrc}
+	daemon := backend.NewSceneReloadDaemon(sceneRepo, cc)
+	daemon.Start(1)
+
+	loaded, err := backend.ReloadScene(context.Background(), cc, config)
+
+	assert.Nil(t, err)
+	assert.Equal(t, persisted, loaded)
+
+	sceneRepo.AssertExpectations(t)
+}
+
+func TestSceneReloadDaemonConcurrently(t *testing.T) {
+	cc := make(chan *backend.ConfigChan)
+
+	daemon := backend.NewSceneReloadDaemon(persistent.NewInMemorySceneRepository(), cc)
+	daemon.Start(8)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+
+		go func(x float64) {
+			defer wg.Done()
+
+			config := &backend.Config{
+				ID:    fmt.Sprintf("scene-%d", int(x)%5),
+				Light: &vector.Vector{X: x, Y: 300},
+				Scene: &vector.Vector{X: 800, Y: 500},
+			}
+
+			loaded, err := backend.ReloadScene(context.Background(), cc, config)
+			if err == backend.ErrSuperseded {
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, config.ID, loaded.ID)
+			assert.Equal(t, x, loaded.Light.X)
+		}(float64(i + 100))
+	}
+
+	wg.Wait()
+	close(cc)
+
+	metrics := daemon.Metrics()
+	assert.Equal(t, int64(200), metrics.Processed+metrics.Superseded)
+	assert.Equal(t, int64(0), metrics.Pending)
+}
+
+func TestSceneReloadDaemonCoalescing(t *testing.T) {
+	release := make(chan time.Time)
+
+	// the first config is blocked in Upsert until released, so the next ones stay pending
+	sceneRepo := new(backend.FakeSceneRepository)
+	sceneRepo.On("Upsert", mock.Anything).Return(&backend.Scene{}, nil).WaitUntil(release)
+
+	cc := make(chan *backend.ConfigChan)
+	daemon := backend.NewSceneReloadDaemon(sceneRepo, cc)
+	daemon.Start(2)
+
+	newConfig := func(x float64) *backend.Config {
+		return &backend.Config{
+			ID:    backend.DefaultSceneID,
+			Light: &vector.Vector{X: x, Y: 300},
+			Scene: &vector.Vector{X: 800, Y: 500},
+		}
+	}
+
+	errs := make([]chan error, 4)
+	for i := range errs {
+		errs[i] = make(chan error, 1)
+
+		go func(i int) {
+			_, err := backend.ReloadScene(context.Background(), cc, newConfig(float64(100+i)))
+			errs[i] <- err
+		}(i)
+
+		// the first job is being processed, each next one is pending
+		// until it is superseded by the one after it
+		want := backend.DaemonMetrics{Superseded: int64(i - 1), Pending: 1, Running: 1}
+		if i == 0 {
+			want = backend.DaemonMetrics{Running: 1}
+		}
+
+		assert.Eventually(t, func() bool { return daemon.Metrics() == want }, time.Second, time.Millisecond)
+	}
+
+	close(release)
+
+	assert.Nil(t, <-errs[0])
+	assert.Equal(t, backend.ErrSuperseded, <-errs[1])
+	assert.Equal(t, backend.ErrSuperseded, <-errs[2])
+	assert.Nil(t, <-errs[3])
+
+	metrics := daemon.Metrics()
+	assert.Equal(t, backend.DaemonMetrics{Processed: 2, Superseded: 2}, metrics)
+
+	sceneRepo.AssertNumberOfCalls(t, "Upsert", 2)
+	close(cc)
+}
+
+func TestSceneReloadDaemonRunsTasks(t *testing.T) {
+	release := make(chan struct{})
+
+	cc := make(chan *backend.ConfigChan)
+	daemon := backend.NewSceneReloadDaemon(new(backend.FakeSceneRepository), cc)
+	daemon.Start(3)
+
+	// the tasks are never superseded, so all of them are blocked on the workers at once
+	errs := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			errs <- backend.RunTask(context.Background(), cc, func(ctx context.Context) error {
+				<-release
+				return backend.ErrSceneNotFound
+			})
+		}()
+	}
+
+	assert.Eventually(t, func() bool { return daemon.Metrics() == backend.DaemonMetrics{Running: 3} }, time.Second, time.Millisecond)
+
+	close(release)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, backend.ErrSceneNotFound, <-errs)
+	}
+
+	assert.Equal(t, backend.DaemonMetrics{Tasks: 3}, daemon.Metrics())
+	close(cc)
+}
+
+func TestSceneReloadDaemonStalled(t *testing.T) {
+	release := make(chan struct{})
+
+	cc := make(chan *backend.ConfigChan)
+	daemon := backend.NewSceneReloadDaemon(new(backend.FakeSceneRepository), cc)
+
+	// a daemon without any worker never picks a job
+	assert.True(t, daemon.Stalled(time.Hour))
+	assert.True(t, daemon.Heartbeat().IsZero())
+
+	daemon.Start(1)
+	assert.False(t, daemon.Stalled(0))
+	assert.False(t, daemon.Heartbeat().IsZero())
+
+	task := func(ctx context.Context) error {
+		<-release
+		return nil
+	}
+
+	// the only worker is busy, but no job waits for it
+	go backend.RunTask(context.Background(), cc, task)
+	assert.Eventually(t, func() bool { return daemon.Metrics() == backend.DaemonMetrics{Running: 1} }, time.Second, time.Millisecond)
+	assert.False(t, daemon.Stalled(0))
+
+	// the next task waits for the worker, which does not pick or finish anything
+	go backend.RunTask(context.Background(), cc, task)
+	assert.Eventually(t, func() bool { return daemon.Metrics().Pending == 1 }, time.Second, time.Millisecond)
+	assert.False(t, daemon.Stalled(time.Hour))
+	assert.Eventually(t, func() bool { return daemon.Stalled(10 * time.Millisecond) }, time.Second, time.Millisecond)
+
+	close(release)
+	assert.Eventually(t, func() bool { return daemon.Metrics() == backend.DaemonMetrics{Tasks: 2} }, time.Second, time.Millisecond)
+	assert.False(t, daemon.Stalled(0))
+
+	// the workers stop once the config chan is closed
+	close(cc)
+	assert.Nil(t, daemon.Wait(context.Background()))
+	assert.True(t, daemon.Stalled(time.Hour))
+}
+
+func TestSceneReloadDaemonWaitDrainsJobs(t *testing.T) {
+	release := make(chan struct{})
+
+	cc := make(chan *backend.ConfigChan)
+	daemon := backend.NewSceneReloadDaemon(new(backend.FakeSceneRepository), cc)
+	daemon.Start(1)
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			errs <- backend.RunTask(context.Background(), cc, func(ctx context.Context) error {
+				<-release
+				return nil
+			})
+		}()
+	}
+
+	assert.Eventually(t, func() bool { return daemon.Metrics() == backend.DaemonMetrics{Pending: 1, Running: 1} }, time.Second, time.Millisecond)
+	close(cc)
+
+	waited := make(chan error)
+	go func() { waited <- daemon.Wait(context.Background()) }()
+
+	close(release)
+
+	// the pending task is still run after the config chan is closed
+	assert.Nil(t, <-waited)
+	assert.Nil(t, <-errs)
+	assert.Nil(t, <-errs)
+	assert.Equal(t, backend.DaemonMetrics{Tasks: 2}, daemon.Metrics())
+}
+
+func TestSceneReloadDaemonWaitAbortsJobs(t *testing.T) {
+	cc := make(chan *backend.ConfigChan)
+	daemon := backend.NewSceneReloadDaemon(new(backend.FakeSceneRepository), cc)
+	daemon.Start(1)
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			errs <- backend.RunTask(context.Background(), cc, func(ctx context.Context) error {
+				<-ctx.Done()
+				return backend.ContextError(ctx)
+			})
+		}()
+	}
+
+	assert.Eventually(t, func() bool { return daemon.Metrics() == backend.DaemonMetrics{Pending: 1, Running: 1} }, time.Second, time.Millisecond)
+	close(cc)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// the running task is cancelled and the pending one is dropped
+	assert.Equal(t, backend.ErrContextExpired, daemon.Wait(ctx))
+	assert.Equal(t, backend.ErrContextCancelled, <-errs)
+	assert.Equal(t, backend.ErrContextCancelled, <-errs)
+	assert.Equal(t, backend.DaemonMetrics{Tasks: 1, Cancelled: 1}, daemon.Metrics())
+}
+
+func TestSceneReloadDaemonObserver(t *testing.T) {
+	observed := make(chan *backend.JobStats, 4)
+
+	cc := make(chan *backend.ConfigChan)
+	daemon := backend.NewSceneReloadDaemon(persistent.NewInMemorySceneRepository(), cc)
+	daemon.SetObserver(func(stats *backend.JobStats) { observed <- stats })
+	daemon.Start(1)
+	defer close(cc)
+
+	config := &backend.Config{
+		ID:    backend.DefaultSceneID,
+		Light: &vector.Vector{X: 250, Y: 300},
+		Scene: &vector.Vector{X: 800, Y: 500},
+		Polygons: backend.Polygons{
+			{VerticesCount: 3, Loop: vector.Loop{{X: 600, Y: 200}, {X: 646, Y: 133}, {X: 646, Y: 261}}},
+		},
+	}
+
+	scene, err := backend.ReloadScene(context.Background(), cc, config)
+	assert.Nil(t, err)
+
+	stats := <-observed
+	assert.Equal(t, backend.ConfigJob, stats.Kind)
+	assert.Equal(t, backend.APISource, stats.Source)
+	assert.Equal(t, scene, stats.Scene)
+	assert.Equal(t, 14, stats.Scene.Rays)
+	assert.True(t, stats.Duration > 0)
+
+	_, err = backend.MoveSceneLight(context.Background(), cc, backend.DefaultSceneID, &vector.Vector{X: 100, Y: 100})
+	assert.Nil(t, err)
+	assert.Equal(t, backend.LightJob, (<-observed).Kind)
+
+	invalid := *config
+	invalid.Polygons = backend.Polygons{{VerticesCount: 3, Loop: vector.Loop{{X: 600, Y: 200}, {X: 646, Y: 133}, {X: 900, Y: 261}}}}
+
+	_, err = backend.ReloadScene(context.Background(), cc, &invalid)
+	stats = <-observed
+	assert.Equal(t, &backend.JobStats{Kind: backend.ConfigJob, Source: backend.APISource, Duration: stats.Duration, Err: err}, stats)
+	assert.Equal(t, "polygon_outside_scene", err.(*backend.ValidationError).Reason)
+
+	_, err = backend.ReloadSceneFrom(context.Background(), cc, config, backend.InitialSource)
+	assert.Nil(t, err)
+	assert.Equal(t, backend.InitialSource, (<-observed).Source)
+
+	assert.Nil(t, backend.RunTask(context.Background(), cc, func(ctx context.Context) error { return nil }))
+	assert.Equal(t, backend.TaskJob, (<-observed).Kind)
+}
+
+func TestSceneReloadDaemonLogsRequestID(t *testing.T) {
+	buf := &syncBuffer{}
+
+	defaultLogger := logging.Default()
+	logging.SetDefault(logging.New(buf, logging.LevelInfo, logging.TextFormat))
+	defer logging.SetDefault(defaultLogger)
+
+	cc := make(chan *backend.ConfigChan)
+	backend.NewSceneReloadDaemon(persistent.NewInMemorySceneRepository(), cc).Start(1)
+	defer close(cc)
+
+	ctx := logging.WithRequestID(context.Background(), "abc123")
+	_, err := backend.ReloadScene(ctx, cc, newLargeConfig(2))
+	assert.Nil(t, err)
+
+	// the line is logged once the response is sent
+	assert.Eventually(t, func() bool {
+		return strings.Contains(buf.String(), `msg="config processed" request_id=abc123 scene=default`)
+	}, time.Second, time.Millisecond)
+}
+
+func TestSceneReloadDaemonProcessesConfigsBeforeTasks(t *testing.T) {
+	release := make(chan struct{})
+
+	order := make(chan string, 2)
+
+	sceneRepo := new(backend.FakeSceneRepository)
+	sceneRepo.On("Upsert", mock.Anything).Return(&backend.Scene{}, nil).Run(func(mock.Arguments) { order <- "config" })
+
+	cc := make(chan *backend.ConfigChan)
+	daemon := backend.NewSceneReloadDaemon(sceneRepo, cc)
+	daemon.Start(1)
+
+	// the only worker is busy, so the next task and config are pending
+	go backend.RunTask(context.Background(), cc, func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+	assert.Eventually(t, func() bool { return daemon.Metrics().Running == 1 }, time.Second, time.Millisecond)
+
+	go func() {
+		backend.RunTask(context.Background(), cc, func(ctx context.Context) error {
+			order <- "task"
+			return nil
+		})
+	}()
+	assert.Eventually(t, func() bool { return daemon.Metrics().Pending == 1 }, time.Second, time.Millisecond)
+
+	go backend.ReloadScene(context.Background(), cc, &backend.Config{
+		ID:    backend.DefaultSceneID,
+		Light: &vector.Vector{X: 250, Y: 300},
+		Scene: &vector.Vector{X: 800, Y: 500},
+	})
+	assert.Eventually(t, func() bool { return daemon.Metrics().Pending == 2 }, time.Second, time.Millisecond)
+
+	close(release)
+	assert.Equal(t, "config", <-order)
+	assert.Equal(t, "task", <-order)
+	close(cc)
+}
+
+func TestSceneProcessIsIdempotent(t *testing.T) {
+	scene := backend.NewScene(newMixedConfig())
+
+	triangles, litArea, err := scene.Process(context.Background())
+	assert.Nil(t, err)
+
+	for i := 0; i < 3; i++ {
+		gotTriangles, gotLitArea, err := scene.Process(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, triangles, gotTriangles)
+		assert.Equal(t, litArea, gotLitArea)
+	}
+
+	// only the 4 scene edges, the processing never mutates the scene
+	assert.Equal(t, backend.NewScene(newMixedConfig()), scene)
+
+	sceneRepo := persistent.NewInMemorySceneRepository()
+
+	first, err := scene.Load(context.Background(), sceneRepo)
+	assert.Nil(t, err)
+
+	second, err := scene.Load(context.Background(), sceneRepo)
+	assert.Nil(t, err)
+	assert.Equal(t, first, second)
+
+	// loading the already loaded scene does not duplicate its boundaries
+	reloaded, err := first.Load(context.Background(), sceneRepo)
+	assert.Nil(t, err)
+	assert.Equal(t, first, reloaded)
+}
+
+func TestSceneProcessConcurrently(t *testing.T) {
+	scene := backend.NewScene(newMixedConfig())
+
+	want, wantLitArea, err := scene.Process(context.Background())
+	assert.Nil(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			got, litArea, err := scene.Process(context.Background())
+			assert.Nil(t, err)
+			assert.Equal(t, want, got)
+			assert.Equal(t, wantLitArea, litArea)
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestSceneMoveLight(t *testing.T) {
+	configs := map[string]*backend.Config{
+		"straight": {
+			ID:    backend.DefaultSceneID,
+			Light: &vector.Vector{X: 250, Y: 300},
+			Scene: &vector.Vector{X: 800, Y: 500},
+			Polygons: backend.Polygons{
+				{VerticesCount: 3, Loop: vector.Loop{{X: 600, Y: 200}, {X: 646, Y: 133}, {X: 646, Y: 261}}},
+			},
+			Walls: backend.Walls{
+				{Vertices: vector.Vectors{{X: 100, Y: 100}, {X: 300, Y: 100}}},
+			},
+		},
+		"curved": {
+			ID:    backend.DefaultSceneID,
+			Light: &vector.Vector{X: 250, Y: 300},
+			Scene: &vector.Vector{X: 800, Y: 500},
+			Polygons: backend.Polygons{
+				{
+					VerticesCount: 3,
+					Loop:          vector.Loop{{X: 600, Y: 200}, {X: 646, Y: 133}, {X: 646, Y: 261}},
+					Controls:      []vector.Vectors{{{X: 610, Y: 150}}, nil, nil},
+				},
+			},
+		},
+	}
+
+	for name, config := range configs {
+		t.Run(name, func(t *testing.T) {
+			sceneRepo := persistent.NewInMemorySceneRepository()
+
+			loaded, err := backend.NewScene(config).Load(context.Background(), sceneRepo)
+			assert.Nil(t, err)
+
+			light := &vector.Vector{X: 700, Y: 450}
+			moved, err := loaded.MoveLight(context.Background(), sceneRepo, light)
+			assert.Nil(t, err)
+
+			// moving the light gives the same result as loading the config with the new light
+			movedConfig := loaded.Config()
+			movedConfig.Light = light
+			want, err := backend.NewScene(movedConfig).Load(context.Background(), persistent.NewInMemorySceneRepository())
+			assert.Nil(t, err)
+
+			assert.Equal(t, want, moved)
+			assert.Equal(t, &vector.Vector{X: 250, Y: 300}, loaded.Light)
+
+			got, err := sceneRepo.Get(context.Background(), backend.DefaultSceneID)
+			assert.Nil(t, err)
+			assert.Equal(t, moved, got)
+		})
+	}
+}
+
+func TestSceneMoveLightOutsideScene(t *testing.T) {
+	sceneRepo := new(backend.FakeSceneRepository)
+
+	scene := backend.NewScene(&backend.Config{
+		ID:    backend.DefaultSceneID,
+		Light: &vector.Vector{X: 250, Y: 300},
+		Scene: &vector.Vector{X: 800, Y: 500},
+	})
+
+	_, err := scene.MoveLight(context.Background(), sceneRepo, &vector.Vector{X: 900, Y: 300})
+	assert.Equal(t, backend.ErrLightOutside, err)
+
+	sceneRepo.AssertNotCalled(t, "Upsert", mock.Anything)
+}
+
+func TestScenePreviewLight(t *testing.T) {
+	sceneRepo := persistent.NewInMemorySceneRepository()
+
+	loaded, err := backend.NewScene(&backend.Config{
+		ID:    backend.DefaultSceneID,
+		Light: &vector.Vector{X: 250, Y: 300},
+		Scene: &vector.Vector{X: 800, Y: 500},
+		Polygons: backend.Polygons{
+			{VerticesCount: 3, Loop: vector.Loop{{X: 600, Y: 200}, {X: 646, Y: 133}, {X: 646, Y: 261}}},
+		},
+	}).Load(context.Background(), sceneRepo)
+	assert.Nil(t, err)
+
+	light := &vector.Vector{X: 700, Y: 450}
+	previewed, err := loaded.PreviewLight(context.Background(), light)
+	assert.Nil(t, err)
 
-	daemon := backend.NewSceneReloadDaemon(sceneRepo, cc, srrcFactory)
+	moved, err := loaded.MoveLight(context.Background(), persistent.NewInMemorySceneRepository(), light)
+	assert.Nil(t, err)
+	assert.Equal(t, moved, previewed)
+
+	// the preview is not stored as a new version
+	versions, err := sceneRepo.ListVersions(context.Background(), backend.DefaultSceneID)
+	assert.Nil(t, err)
+	assert.Len(t, versions, 1)
+}
+
+func TestSceneReloadDaemonStoresPreviewOfPendingLightMove(t *testing.T) {
+	release := make(chan time.Time)
+
+	loaded, err := backend.NewScene(&backend.Config{
+		ID:    backend.DefaultSceneID,
+		Light: &vector.Vector{X: 250, Y: 300},
+		Scene: &vector.Vector{X: 800, Y: 500},
+	}).Load(context.Background(), persistent.NewInMemorySceneRepository())
+	assert.Nil(t, err)
+
+	// the first config is blocked in Upsert until released, so the next jobs stay pending
+	sceneRepo := new(backend.FakeSceneRepository)
+	sceneRepo.On("Upsert", mock.Anything).Return(&backend.Scene{}, nil).WaitUntil(release)
+	sceneRepo.On("Get", backend.DefaultSceneID).Return(loaded, nil)
+
+	cc := make(chan *backend.ConfigChan)
+	daemon := backend.NewSceneReloadDaemon(sceneRepo, cc)
+	daemon.Start(1)
+	defer close(cc)
+
+	errs := make(chan error, 3)
+	go func() {
+		_, err := backend.ReloadScene(context.Background(), cc, loaded.Config())
+		errs <- err
+	}()
+
+	assert.Eventually(t, func() bool { return daemon.Metrics().Running == 1 }, time.Second, time.Millisecond)
+
+	go func() {
+		_, err := backend.MoveSceneLight(context.Background(), cc, backend.DefaultSceneID, &vector.Vector{X: 30, Y: 40})
+		errs <- err
+	}()
+
+	assert.Eventually(t, func() bool { return daemon.Metrics().Pending == 1 }, time.Second, time.Millisecond)
+
+	go func() {
+		_, err := backend.PreviewSceneLight(context.Background(), cc, backend.DefaultSceneID, &vector.Vector{X: 50, Y: 60})
+		errs <- err
+	}()
+
+	assert.Eventually(t, func() bool { return daemon.Metrics().Superseded == 1 }, time.Second, time.Millisecond)
+	close(release)
+
+	for i := 0; i < 3; i++ {
+		if err := <-errs; err != nil {
+			assert.Equal(t, backend.ErrSuperseded, err)
+		}
+	}
+
+	// the preview which replaced the light move is stored instead of it
+	sceneRepo.AssertNumberOfCalls(t, "Upsert", 2)
+	upserted := sceneRepo.Calls[len(sceneRepo.Calls)-1].Arguments.Get(0).(*backend.Scene)
+	assert.Equal(t, &vector.Vector{X: 50, Y: 60}, upserted.Light)
+}
+
+func TestSceneReloadDaemonMovesLightOfPendingConfig(t *testing.T) {
+	release := make(chan time.Time)
+
+	// the first config is blocked in Upsert until released, so the next ones stay pending
+	sceneRepo := new(backend.FakeSceneRepository)
+	sceneRepo.On("Upsert", mock.Anything).Return(&backend.Scene{}, nil).WaitUntil(release)
+
+	cc := make(chan *backend.ConfigChan)
+	daemon := backend.NewSceneReloadDaemon(sceneRepo, cc)
 	daemon.Start(1)
+	defer close(cc)
+
+	first := newLargeConfig(1)
+	first.ID = backend.DefaultSceneID
+
+	pending := newLargeConfig(2)
+	pending.ID = backend.DefaultSceneID
+
+	errs := make(chan error, 3)
+	go func() {
+		_, err := backend.ReloadScene(context.Background(), cc, first)
+		errs <- err
+	}()
+
+	assert.Eventually(t, func() bool { return daemon.Metrics().Running == 1 }, time.Second, time.Millisecond)
+
+	go func() {
+		_, err := backend.ReloadScene(context.Background(), cc, pending)
+		errs <- err
+	}()
+
+	assert.Eventually(t, func() bool { return daemon.Metrics().Pending == 1 }, time.Second, time.Millisecond)
+
+	go func() {
+		_, err := backend.MoveSceneLight(context.Background(), cc, backend.DefaultSceneID, &vector.Vector{X: 30, Y: 40})
+		errs <- err
+	}()
 
-	cc <- &backend.ConfigChan{
-		Ctx:          context.Background(),
-		Config:       config,
-		ResponseChan: "test",
+	assert.Eventually(t, func() bool { return daemon.Metrics().Superseded == 1 }, time.Second, time.Millisecond)
+	close(release)
+
+	for i := 0; i < 3; i++ {
+		if err := <-errs; err != nil {
+			assert.Equal(t, backend.ErrSuperseded, err)
+		}
 	}
 
-	loaded := <-srrcFactory["test"]
+	// the light is moved in the pending config instead of the persisted scene
+	sceneRepo.AssertNumberOfCalls(t, "Upsert", 2)
+	upserted := sceneRepo.Calls[1].Arguments.Get(0).(*backend.Scene)
+	assert.Equal(t, &vector.Vector{X: 30, Y: 40}, upserted.Light)
+	assert.Equal(t, pending.Polygons, upserted.Polygons)
+}
+
+func TestReloadSceneCancelledWhileSending(t *testing.T) {
+	// nobody receives from the config chan, so the job is never sent
+	cc := make(chan *backend.ConfigChan)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := backend.ReloadScene(ctx, cc, &backend.Config{})
+
+	assert.Equal(t, backend.ErrContextExpired, err)
+}
+
+func TestReloadSceneCancelledWhileReceiving(t *testing.T) {
+	cc := make(chan *backend.ConfigChan)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	received := make(chan *backend.ConfigChan)
+	go func() {
+		// the job is taken but the response comes only after the caller gave up
+		job := <-cc
+		cancel()
+		received <- job
+	}()
+
+	_, err := backend.ReloadScene(ctx, cc, &backend.Config{})
+	assert.Equal(t, backend.ErrContextCancelled, err)
+
+	// the response chan is buffered, so a late response never blocks the worker
+	job := <-received
+	job.Response <- &backend.SceneReloadResponse{Scene: &backend.Scene{}}
+}
+
+func TestSceneReloadDaemonSkipsCancelledJobs(t *testing.T) {
+	sceneRepo := new(backend.FakeSceneRepository)
+
+	cc := make(chan *backend.ConfigChan)
+	backend.NewSceneReloadDaemon(sceneRepo, cc).Start(1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	job := backend.NewConfigChan(ctx, &backend.Config{})
+	cc <- job
+	resp := <-job.Response
+
+	assert.Equal(t, backend.ErrContextCancelled, resp.Err)
+
+	// Upsert is never called as the config is not processed
+	sceneRepo.AssertExpectations(t)
+}
+
+func TestSceneValidate(t *testing.T) {
+	assert.Nil(t, backend.NewScene(newMixedConfig()).Validate(context.Background()))
+
+	// the wall crosses the triangle
+	config := newMixedConfig()
+	config.Walls = backend.Walls{{Vertices: vector.Vectors{{X: 550, Y: 200}, {X: 700, Y: 200}}}}
+	assert.NotNil(t, backend.NewScene(config).Validate(context.Background()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, backend.ErrContextCancelled, backend.NewScene(newLargeConfig(150)).Validate(ctx))
+}
+
+func TestSceneProcessWithCancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, _, err := backend.NewScene(newLargeConfig(150)).Process(ctx)
+
+	assert.Equal(t, backend.ErrContextCancelled, err)
+}
+
+func TestSceneProcessWithExpiredContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, _, err := backend.NewScene(newLargeConfig(150)).Process(ctx)
+
+	assert.Equal(t, backend.ErrContextExpired, err)
+	assert.True(t, time.Since(start) < 2*time.Second, "processing did not stop in time")
+}
+
+func TestSceneReloadDaemonProcessingTimeout(t *testing.T) {
+	// Upsert is never called as the processing does not finish
+	sceneRepo := new(backend.FakeSceneRepository)
+
+	cc := make(chan *backend.ConfigChan)
+	daemon := backend.NewSceneReloadDaemon(sceneRepo, cc)
+	daemon.SetProcessingTimeout(50 * time.Millisecond)
+	daemon.Start(1)
 
-	assert.Nil(t, loaded.Err)
-	assert.Equal(t, persisted, loaded.Scene)
+	start := time.Now()
+	_, err := backend.ReloadScene(context.Background(), cc, newLargeConfig(150))
+
+	assert.Equal(t, backend.ErrContextExpired, err)
+	assert.True(t, time.Since(start) < 2*time.Second, "processing did not stop in time")
 
 	sceneRepo.AssertExpectations(t)
 }
+
+// newLargeConfig generates a config with a grid of n x n small squares,
+// which takes far too long to be processed without being stopped
+func newLargeConfig(n int) *backend.Config {
+	config := &backend.Config{
+		ID:       backend.DefaultSceneID,
+		Light:    &vector.Vector{X: 5, Y: 5},
+		Scene:    &vector.Vector{X: float64(n * 50), Y: float64(n * 50)},
+		Polygons: make(backend.Polygons, 0, n*n),
+	}
+
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			x, y := float64(i*50+10), float64(j*50+10)
+
+			config.Polygons = append(config.Polygons, &backend.Polygon{
+				VerticesCount: 4,
+				Loop: vector.Loop{
+					{X: x, Y: y},
+					{X: x + 20, Y: y},
+					{X: x + 20, Y: y + 20},
+					{X: x, Y: y + 20},
+				},
+			})
+		}
+	}
+
+	return config
+}
+
+// newMixedConfig returns a config with a straight and a curved polygon, a wall and an ellipse
+func newMixedConfig() *backend.Config {
+	return &backend.Config{
+		ID:    backend.DefaultSceneID,
+		Light: &vector.Vector{X: 250, Y: 300},
+		Scene: &vector.Vector{X: 800, Y: 500},
+		Polygons: backend.Polygons{
+			{VerticesCount: 3, Loop: vector.Loop{{X: 600, Y: 200}, {X: 646, Y: 133}, {X: 646, Y: 261}}},
+			{
+				VerticesCount: 3,
+				Loop:          vector.Loop{{X: 300, Y: 100}, {X: 346, Y: 33}, {X: 346, Y: 161}},
+				Controls:      []vector.Vectors{{{X: 310, Y: 50}}, nil, nil},
+			},
+		},
+		Walls: backend.Walls{
+			{Vertices: vector.Vectors{{X: 100, Y: 100}, {X: 200, Y: 100}}},
+		},
+		Ellipses: backend.Ellipses{
+			{Center: &vector.Vector{X: 400, Y: 400}, RadiusX: 50, RadiusY: 30},
+		},
+	}
+}
+
+// syncBuffer is a bytes.Buffer which is safe to be written and read concurrently
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
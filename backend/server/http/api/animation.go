@@ -0,0 +1,186 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+	"github.com/iliyanmotovski/raytracer/backend/render"
+	"github.com/iliyanmotovski/raytracer/backend/vector"
+)
+
+// CreateAnimation is an http handler which starts rendering an animation of the scene with the
+// ID from the {scene} path parameter, in which the light moves along the path from the body.
+// The frames are rendered in the background and the handler responds right away with the job,
+// whose progress is at the URL from the Location header. The query parameters set the size
+// and the colors of the frames the same way as for GetScenePNG
+func CreateAnimation(sceneRepo backend.SceneRepository, animations *render.Animations) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := sceneID(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(err)
+			return
+		}
+
+		opts, err := renderOptions(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(err.Error())
+			return
+		}
+
+		dto := new(animationDTO)
+
+		if err := json.NewDecoder(r.Body).Decode(dto); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(err)
+			return
+		}
+
+		scene, err := sceneRepo.Get(r.Context(), id)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(err)
+			return
+		}
+
+		if scene == nil {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(backend.ErrSceneNotFound)
+			return
+		}
+
+		job, err := animations.Start(scene, dto.adapt(opts))
+		if err != nil {
+			status := http.StatusBadRequest
+			if err == render.ErrTooManyAnimations || err == render.ErrAnimationsClosed {
+				status = http.StatusServiceUnavailable
+			}
+
+			w.WriteHeader(status)
+			json.NewEncoder(w).Encode(err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", "/api/v1/animations/"+job.ID)
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(newAnimationJobDTO(job))
+	}
+}
+
+// GetAnimation is an http handler which returns the progress of the
+// animation job with the ID from the {animation} path parameter
+func GetAnimation(animations *render.Animations) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		job := animations.Get(mux.Vars(r)["animation"])
+		if job == nil {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode("animation not found")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(newAnimationJobDTO(job))
+	}
+}
+
+// GetAnimationResult is an http handler which returns the rendered animation of the job with
+// the ID from the {animation} path parameter, an animated GIF or a ZIP archive of PNG frames.
+// It responds with 409 Conflict and the job while it is rendering or when it has failed
+func GetAnimationResult(animations *render.Animations) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		job := animations.Get(mux.Vars(r)["animation"])
+		if job == nil {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode("animation not found")
+			return
+		}
+
+		result := job.Result()
+		if result == nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(newAnimationJobDTO(job))
+			return
+		}
+
+		w.Header().Set("Content-Type", job.Format.ContentType())
+		if job.Format == render.PNGFormat {
+			w.Header().Set("Content-Disposition", `attachment; filename="`+job.SceneID+`-frames.zip"`)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write(result)
+	}
+}
+
+type animationDTO struct {
+	// Path is a polyline along which the light moves Step apart,
+	// or the keyframes of the light when Step is 0
+	Path []*xy
+	Step float64
+	// Format is gif or png, gif by default
+	Format string
+	// Delay of the GIF frames in 100ths of a second
+	Delay int
+}
+
+func (a *animationDTO) adapt(opts render.Options) *render.Animation {
+	points := make(vector.Vectors, len(a.Path))
+	for i, p := range a.Path {
+		if p == nil {
+			p = &xy{}
+		}
+
+		points[i] = &vector.Vector{X: p.X, Y: p.Y}
+	}
+
+	format := render.AnimationFormat(a.Format)
+	if format == "" {
+		format = render.GIFFormat
+	}
+
+	return &render.Animation{
+		Lights:  render.LightPath(points, a.Step),
+		Format:  format,
+		Delay:   a.Delay,
+		Options: opts,
+	}
+}
+
+type animationJobDTO struct {
+	ID, Scene, Format string
+	Frames, Rendered  int
+	Done              bool
+	Error             string
+	// Result is the URL of the rendered animation once it is done without an error
+	Result string
+}
+
+func newAnimationJobDTO(job *render.AnimationJob) *animationJobDTO {
+	status := job.Status()
+
+	dto := &animationJobDTO{
+		ID:       job.ID,
+		Scene:    job.SceneID,
+		Format:   string(job.Format),
+		Frames:   job.Frames,
+		Rendered: status.Rendered,
+		Done:     status.Done,
+	}
+
+	if status.Err != nil {
+		dto.Error = status.Err.Error()
+	}
+
+	if status.Done && status.Err == nil {
+		dto.Result = "/api/v1/animations/" + job.ID + "/result"
+	}
+
+	return dto
+}
@@ -0,0 +1,133 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"image/gif"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+	"github.com/iliyanmotovski/raytracer/backend/render"
+	"github.com/iliyanmotovski/raytracer/backend/server/http/api"
+)
+
+type animationJob struct {
+	ID, Scene, Format string
+	Frames, Rendered  int
+	Done              bool
+	Error             string
+	Result            string
+}
+
+func TestCreateAnimation(t *testing.T) {
+	scene := newVersionScene(60, 250, 300)
+	scene.ID = "second"
+
+	sceneRepo := new(backend.FakeSceneRepository)
+	sceneRepo.On("Get", "second").Return(scene, nil)
+
+	release := make(chan struct{})
+	run := func(ctx context.Context, task func(ctx context.Context) error) error {
+		<-release
+		return task(ctx)
+	}
+
+	router := newAnimationRouter(sceneRepo, render.NewAnimations(context.Background(), run, 4))
+
+	body := `{"Path": [{"X": 100, "Y": 100}, {"X": 500, "Y": 100}], "Step": 100, "Delay": 10}`
+	r, _ := http.NewRequest("POST", "/api/v1/scenes/second/animations?width=200", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	created := new(animationJob)
+	assert.Nil(t, json.NewDecoder(w.Body).Decode(created))
+	assert.Equal(t, &animationJob{ID: created.ID, Scene: "second", Format: "gif", Frames: 5}, created)
+	assert.Equal(t, "/api/v1/animations/"+created.ID, w.Header().Get("Location"))
+
+	// the result is not there while the frames are rendering
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, newGetRequest("/api/v1/animations/"+created.ID+"/result"))
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	close(release)
+
+	job := new(animationJob)
+	assert.Eventually(t, func() bool {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newGetRequest("/api/v1/animations/"+created.ID))
+		return json.NewDecoder(w.Body).Decode(job) == nil && job.Done
+	}, 5*time.Second, time.Millisecond)
+
+	assert.Equal(t, 5, job.Rendered)
+	assert.Equal(t, "", job.Error)
+	assert.Equal(t, "/api/v1/animations/"+created.ID+"/result", job.Result)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, newGetRequest(job.Result))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/gif", w.Header().Get("Content-Type"))
+
+	anim, err := gif.DecodeAll(w.Body)
+	assert.Nil(t, err)
+	assert.Len(t, anim.Image, 5)
+	assert.Equal(t, 200, anim.Config.Width)
+	assert.Equal(t, 10, anim.Delay[0])
+
+	sceneRepo.AssertExpectations(t)
+}
+
+func TestCreateAnimationInvalid(t *testing.T) {
+	sceneRepo := new(backend.FakeSceneRepository)
+	sceneRepo.On("Get", backend.DefaultSceneID).Return(newVersionScene(60, 250, 300), nil)
+
+	router := newAnimationRouter(sceneRepo, render.NewAnimations(context.Background(), nil, 4))
+
+	for _, body := range []string{
+		`{"Path": [{"X": 100, "Y": 100}], "Format": "mp4"}`,
+		`{"Path": []}`,
+		`{"Path": [{"X": 100, "Y": 100}, {"X": 900, "Y": 100}], "Step": 100}`,
+		`{"Path": `,
+	} {
+		r, _ := http.NewRequest("POST", "/api/v1/scene/animations", strings.NewReader(body))
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+}
+
+func TestGetAnimationNotFound(t *testing.T) {
+	router := newAnimationRouter(new(backend.FakeSceneRepository), render.NewAnimations(context.Background(), nil, 4))
+
+	for _, path := range []string{"/api/v1/animations/abc", "/api/v1/animations/abc/result"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newGetRequest(path))
+		assert.Equal(t, http.StatusNotFound, w.Code, path)
+	}
+}
+
+func newAnimationRouter(sceneRepo backend.SceneRepository, animations *render.Animations) *mux.Router {
+	router := mux.NewRouter()
+	router.Handle("/api/v1/scene/animations", api.CreateAnimation(sceneRepo, animations)).Methods("POST")
+	router.Handle("/api/v1/scenes/{scene}/animations", api.CreateAnimation(sceneRepo, animations)).Methods("POST")
+	router.Handle("/api/v1/animations/{animation}", api.GetAnimation(animations)).Methods("GET")
+	router.Handle("/api/v1/animations/{animation}/result", api.GetAnimationResult(animations)).Methods("GET")
+
+	return router
+}
+
+func newGetRequest(path string) *http.Request {
+	r, _ := http.NewRequest("GET", path, nil)
+	return r
+}
@@ -8,9 +8,17 @@ import (
 	"github.com/iliyanmotovski/raytracer/backend/vector"
 )
 
-// CreateConfiguration is an http handler used for creating a scene configuration
-func CreateConfiguration(cc chan *backend.ConfigChan, srrc backend.SceneReloadResponseChanFactory) http.HandlerFunc {
+// CreateConfiguration is an http handler used for creating a configuration of the scene with
+// the ID from the {scene} path parameter, the scene is created if it does not exist yet
+func CreateConfiguration(cc chan *backend.ConfigChan) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := sceneID(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(err)
+			return
+		}
+
 		dto := new(configDTO)
 
 		if err := json.NewDecoder(r.Body).Decode(dto); err != nil {
@@ -19,20 +27,24 @@ func CreateConfiguration(cc chan *backend.ConfigChan, srrc backend.SceneReloadRe
 			return
 		}
 
-		// send the config through the send chan to be processed and new scene generated
-		cc <- &backend.ConfigChan{Ctx: r.Context(), Config: dto.adapt(), ResponseChan: backend.CreateConfigHandler}
-		// receive the config processing response through the receive chan
-		created := <-srrc[backend.CreateConfigHandler]
-		if created.Err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(created.Err)
+		config := dto.adapt()
+		config.ID = id
+
+		// send the config to the daemon to be processed and new scene generated
+		created, err := backend.ReloadScene(r.Context(), cc, config)
+		if err != nil {
+			w.WriteHeader(reloadErrorStatus(err))
+			json.NewEncoder(w).Encode(err)
 			return
 		}
 
 		resp := &configDTO{
-			Light:    &xy{X: created.Scene.Light.X, Y: created.Scene.Light.Y},
-			Scene:    &xy{X: created.Scene.Width, Y: created.Scene.Height},
-			Polygons: created.Scene.Polygons,
+			Light:               &xy{X: created.Light.X, Y: created.Light.Y},
+			Scene:               &xy{X: created.Width, Y: created.Height},
+			Polygons:            created.Polygons,
+			Walls:               created.Walls,
+			Ellipses:            created.Ellipses,
+			FlatteningTolerance: created.FlatteningTolerance,
 		}
 
 		w.WriteHeader(http.StatusCreated)
@@ -41,36 +53,69 @@ func CreateConfiguration(cc chan *backend.ConfigChan, srrc backend.SceneReloadRe
 	}
 }
 
+// reloadErrorStatus returns the status code of a failed scene reload, the config
+// is invalid unless the request was given up or superseded before it was processed
+func reloadErrorStatus(err error) int {
+	switch err {
+	case backend.ErrContextCancelled, backend.ErrContextExpired:
+		return http.StatusServiceUnavailable
+	case backend.ErrSuperseded:
+		return http.StatusConflict
+	case backend.ErrSceneNotFound:
+		return http.StatusNotFound
+	default:
+		return http.StatusBadRequest
+	}
+}
+
 type configDTO struct {
-	Light, Scene *xy
-	Polygons     backend.Polygons
+	Light, Scene        *xy
+	Polygons            backend.Polygons
+	Walls               backend.Walls
+	Ellipses            backend.Ellipses
+	FlatteningTolerance float64
 }
 
 func (c *configDTO) adapt() *backend.Config {
 	return &backend.Config{
-		Light:    &vector.Vector{X: c.Light.X, Y: c.Light.Y},
-		Scene:    &vector.Vector{X: c.Scene.X, Y: c.Scene.Y},
-		Polygons: c.Polygons,
+		Light:               &vector.Vector{X: c.Light.X, Y: c.Light.Y},
+		Scene:               &vector.Vector{X: c.Scene.X, Y: c.Scene.Y},
+		Polygons:            c.Polygons,
+		Walls:               c.Walls,
+		Ellipses:            c.Ellipses,
+		FlatteningTolerance: c.FlatteningTolerance,
 	}
 }
 
 func (c *configDTO) MarshalJSON() ([]byte, error) {
 	dto := &struct {
-		Light, Scene *xy
-		Polygons     [][]*xy
+		Light, Scene        *xy
+		Polygons            [][]*vertexDTO
+		Walls               [][]*xy       `json:",omitempty"`
+		Ellipses            []*ellipseDTO `json:",omitempty"`
+		FlatteningTolerance float64       `json:",omitempty"`
 	}{}
 
 	dto.Light = c.Light
 	dto.Scene = c.Scene
-	dto.Polygons = make([][]*xy, len(c.Polygons))
+	dto.FlatteningTolerance = c.FlatteningTolerance
+	dto.Polygons = make([][]*vertexDTO, len(c.Polygons))
 
 	for i, polygon := range c.Polygons {
-		poly := make([]*xy, len(polygon.Loop))
-		for j, vertice := range polygon.Loop {
-			poly[j] = &xy{X: vertice.X, Y: vertice.Y}
+		dto.Polygons[i] = newPolygonDTO(polygon)
+	}
+
+	for _, wall := range c.Walls {
+		w := make([]*xy, len(wall.Vertices))
+		for j, vertice := range wall.Vertices {
+			w[j] = &xy{X: vertice.X, Y: vertice.Y}
 		}
 
-		dto.Polygons[i] = poly
+		dto.Walls = append(dto.Walls, w)
+	}
+
+	for _, ellipse := range c.Ellipses {
+		dto.Ellipses = append(dto.Ellipses, newEllipseDTO(ellipse))
 	}
 
 	return json.Marshal(dto)
@@ -78,8 +123,11 @@ func (c *configDTO) MarshalJSON() ([]byte, error) {
 
 func (c *configDTO) UnmarshalJSON(b []byte) error {
 	dto := &struct {
-		Light, Scene *xy
-		Polygons     [][]*xy
+		Light, Scene        *xy
+		Polygons            [][]*vertexDTO
+		Walls               [][]*xy
+		Ellipses            []*ellipseDTO
+		FlatteningTolerance float64
 	}{}
 
 	if err := json.Unmarshal(b, dto); err != nil {
@@ -88,23 +136,113 @@ func (c *configDTO) UnmarshalJSON(b []byte) error {
 
 	c.Light = dto.Light
 	c.Scene = dto.Scene
+	c.FlatteningTolerance = dto.FlatteningTolerance
 	c.Polygons = make(backend.Polygons, len(dto.Polygons))
 
 	for i, polygon := range dto.Polygons {
-		poly := &backend.Polygon{VerticesCount: len(polygon)}
-		for _, vertice := range polygon {
-			poly.Loop = append(poly.Loop, &vector.Vector{
+		c.Polygons[i] = adaptPolygonDTO(polygon)
+	}
+
+	for _, wall := range dto.Walls {
+		w := &backend.Wall{VerticesCount: len(wall)}
+		for _, vertice := range wall {
+			w.Vertices = append(w.Vertices, &vector.Vector{
 				X: vertice.X,
 				Y: vertice.Y,
 			})
 		}
 
-		c.Polygons[i] = poly
+		c.Walls = append(c.Walls, w)
+	}
+
+	for _, ellipse := range dto.Ellipses {
+		c.Ellipses = append(c.Ellipses, ellipse.adapt())
 	}
 
 	return nil
 }
 
+// vertexDTO is a polygon vertice, when Controls is set the side starting
+// at it is a quadratic (1 control point) or cubic (2) Bezier curve
+type vertexDTO struct {
+	X, Y     float64
+	Controls []*xy `json:",omitempty"`
+}
+
+func newPolygonDTO(polygon *backend.Polygon) []*vertexDTO {
+	result := make([]*vertexDTO, len(polygon.Loop))
+
+	for i, vertice := range polygon.Loop {
+		result[i] = &vertexDTO{X: vertice.X, Y: vertice.Y}
+
+		if i < len(polygon.Controls) {
+			for _, control := range polygon.Controls[i] {
+				result[i].Controls = append(result[i].Controls, &xy{X: control.X, Y: control.Y})
+			}
+		}
+	}
+
+	return result
+}
+
+func adaptPolygonDTO(vertices []*vertexDTO) *backend.Polygon {
+	result := &backend.Polygon{VerticesCount: len(vertices)}
+
+	for i, vertice := range vertices {
+		result.Loop = append(result.Loop, &vector.Vector{
+			X: vertice.X,
+			Y: vertice.Y,
+		})
+
+		if len(vertice.Controls) == 0 {
+			continue
+		}
+
+		if result.Controls == nil {
+			result.Controls = make([]vector.Vectors, len(vertices))
+		}
+
+		for _, control := range vertice.Controls {
+			result.Controls[i] = append(result.Controls[i], &vector.Vector{X: control.X, Y: control.Y})
+		}
+	}
+
+	return result
+}
+
+// ellipseDTO accepts either Radius for a circle or RadiusX and RadiusY for an
+// ellipse, Loop is an approximation of the ellipse used by the frontend for drawing
+type ellipseDTO struct {
+	Center           *xy
+	Radius           float64 `json:",omitempty"`
+	RadiusX, RadiusY float64
+	Loop             []*xy `json:",omitempty"`
+}
+
+func newEllipseDTO(e *backend.Ellipse) *ellipseDTO {
+	return &ellipseDTO{
+		Center:  &xy{X: e.Center.X, Y: e.Center.Y},
+		RadiusX: e.RadiusX,
+		RadiusY: e.RadiusY,
+	}
+}
+
+func (e *ellipseDTO) adapt() *backend.Ellipse {
+	if e.Center == nil {
+		e.Center = &xy{}
+	}
+
+	if e.RadiusX == 0 && e.RadiusY == 0 {
+		return backend.NewCircle(e.Center.X, e.Center.Y, e.Radius)
+	}
+
+	return &backend.Ellipse{
+		Center:  &vector.Vector{X: e.Center.X, Y: e.Center.Y},
+		RadiusX: e.RadiusX,
+		RadiusY: e.RadiusY,
+	}
+}
+
 type xy struct {
 	X, Y float64
 }
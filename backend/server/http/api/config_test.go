@@ -2,15 +2,21 @@ package api_test
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
 
+	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/iliyanmotovski/raytracer/backend"
+	"github.com/iliyanmotovski/raytracer/backend/persistent"
 	"github.com/iliyanmotovski/raytracer/backend/server/http/api"
 	"github.com/iliyanmotovski/raytracer/backend/vector"
 )
@@ -41,6 +47,7 @@ func TestCreateConfiguration(t *testing.T) {
 	}
 
 	config := &backend.Config{
+		ID:    backend.DefaultSceneID,
 		Light: &vector.Vector{X: 250, Y: 300},
 		Scene: &vector.Vector{X: 800, Y: 500},
 		Polygons: backend.Polygons{
@@ -56,48 +63,273 @@ func TestCreateConfiguration(t *testing.T) {
 	}
 
 	cc := make(chan *backend.ConfigChan)
-	srrc := make(chan *backend.SceneReloadResponse)
 
-	srrcFactory := map[string]chan *backend.SceneReloadResponse{backend.CreateConfigHandler: srrc}
+	body := bytes.NewReader([]byte(postData))
+	r, _ := http.NewRequest("POST", "/api/v1/scene/config", body)
+	w := httptest.NewRecorder()
+
+	go func() {
+		gotConfig := <-cc
+		assert.Equal(t, r.Context(), gotConfig.Ctx)
+		assert.Equal(t, config, gotConfig.Config)
+
+		gotConfig.Response <- &backend.SceneReloadResponse{Err: nil, Scene: scene}
+	}()
+
+	api.CreateConfiguration(cc).ServeHTTP(w, r)
+
+	wantResponse := `{"Light":{"X":250,"Y":300},"Scene":{"X":800,"Y":500},"Polygons":[[{"X":600,"Y":200},{"X":646,"Y":133},{"X":646,"Y":261}]]}`
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, wantResponse, strings.TrimSpace(w.Body.String()))
+}
+
+func TestCreateConfigurationForScene(t *testing.T) {
+	postData := `{
+	"scene": {"x": 800, "y": 500},
+	"light": {"x": 250, "y": 300},
+	"polygons": []
+}`
+
+	config := &backend.Config{
+		ID:       "second",
+		Light:    &vector.Vector{X: 250, Y: 300},
+		Scene:    &vector.Vector{X: 800, Y: 500},
+		Polygons: backend.Polygons{},
+	}
+
+	scene := backend.NewScene(config)
+
+	cc := make(chan *backend.ConfigChan)
+
+	body := bytes.NewReader([]byte(postData))
+	r, _ := http.NewRequest("POST", "/api/v1/scenes/second/config", body)
+	r = mux.SetURLVars(r, map[string]string{"scene": "second"})
+	w := httptest.NewRecorder()
+
+	go func() {
+		gotConfig := <-cc
+		assert.Equal(t, config, gotConfig.Config)
+
+		gotConfig.Response <- &backend.SceneReloadResponse{Err: nil, Scene: scene}
+	}()
+
+	api.CreateConfiguration(cc).ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+}
+
+func TestCreateConfigurationWithWalls(t *testing.T) {
+	postData := `{
+	"scene": {"x": 800, "y": 500},
+	"light": {"x": 250, "y": 300},
+	"polygons": [],
+	"walls": [
+		[{"x": 100, "y": 100}, {"x": 300, "y": 100}]
+	]
+}`
+
+	walls := backend.Walls{
+		{
+			VerticesCount: 2,
+			Vertices: vector.Vectors{
+				{X: 100, Y: 100},
+				{X: 300, Y: 100},
+			},
+		},
+	}
+
+	scene := &backend.Scene{
+		Width:    800,
+		Height:   500,
+		Light:    &vector.Vector{X: 250, Y: 300},
+		Polygons: backend.Polygons{},
+		Walls:    walls,
+	}
+
+	config := &backend.Config{
+		ID:       backend.DefaultSceneID,
+		Light:    &vector.Vector{X: 250, Y: 300},
+		Scene:    &vector.Vector{X: 800, Y: 500},
+		Polygons: backend.Polygons{},
+		Walls:    walls,
+	}
+
+	cc := make(chan *backend.ConfigChan)
 
 	body := bytes.NewReader([]byte(postData))
 	r, _ := http.NewRequest("POST", "/api/v1/scene/config", body)
 	w := httptest.NewRecorder()
 
 	go func() {
-		wantConfig := &backend.ConfigChan{
-			Ctx:          r.Context(),
-			Config:       config,
-			ResponseChan: backend.CreateConfigHandler,
-		}
+		gotConfig := <-cc
+		assert.Equal(t, config, gotConfig.Config)
+
+		gotConfig.Response <- &backend.SceneReloadResponse{Err: nil, Scene: scene}
+	}()
+
+	api.CreateConfiguration(cc).ServeHTTP(w, r)
+
+	wantResponse := `{"Light":{"X":250,"Y":300},"Scene":{"X":800,"Y":500},"Polygons":[],"Walls":[[{"X":100,"Y":100},{"X":300,"Y":100}]]}`
 
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, wantResponse, strings.TrimSpace(w.Body.String()))
+}
+
+func TestCreateConfigurationWithCurves(t *testing.T) {
+	postData := `{
+	"scene": {"x": 800, "y": 500},
+	"light": {"x": 250, "y": 300},
+	"flatteningTolerance": 0.1,
+	"polygons": [
+		[{"x": 600, "y": 200, "controls": [{"x": 620, "y": 150}]}, {"x": 646, "y": 133}, {"x": 646, "y": 261}]
+	]
+}`
+
+	polygons := backend.Polygons{
+		{
+			VerticesCount: 3,
+			Loop: vector.Loop{
+				{X: 600, Y: 200},
+				{X: 646, Y: 133},
+				{X: 646, Y: 261},
+			},
+			Controls: []vector.Vectors{{{X: 620, Y: 150}}, nil, nil},
+		},
+	}
+
+	scene := &backend.Scene{
+		Width:               800,
+		Height:              500,
+		FlatteningTolerance: 0.1,
+		Light:               &vector.Vector{X: 250, Y: 300},
+		Polygons:            polygons,
+	}
+
+	config := &backend.Config{
+		ID:                  backend.DefaultSceneID,
+		Light:               &vector.Vector{X: 250, Y: 300},
+		Scene:               &vector.Vector{X: 800, Y: 500},
+		Polygons:            polygons,
+		FlatteningTolerance: 0.1,
+	}
+
+	cc := make(chan *backend.ConfigChan)
+
+	body := bytes.NewReader([]byte(postData))
+	r, _ := http.NewRequest("POST", "/api/v1/scene/config", body)
+	w := httptest.NewRecorder()
+
+	go func() {
 		gotConfig := <-cc
-		assert.Equal(t, wantConfig, gotConfig)
+		assert.Equal(t, config, gotConfig.Config)
 
-		srrcFactory[backend.CreateConfigHandler] <- &backend.SceneReloadResponse{Err: nil, Scene: scene}
+		gotConfig.Response <- &backend.SceneReloadResponse{Err: nil, Scene: scene}
 	}()
 
-	api.CreateConfiguration(cc, srrcFactory).ServeHTTP(w, r)
+	api.CreateConfiguration(cc).ServeHTTP(w, r)
 
-	wantResponse := `{"Light":{"X":250,"Y":300},"Scene":{"X":800,"Y":500},"Polygons":[[{"X":600,"Y":200},{"X":646,"Y":133},{"X":646,"Y":261}]]}`
+	wantResponse := `{"Light":{"X":250,"Y":300},"Scene":{"X":800,"Y":500},"Polygons":[[{"X":600,"Y":200,"Controls":[{"X":620,"Y":150}]},` +
+		`{"X":646,"Y":133},{"X":646,"Y":261}]],"FlatteningTolerance":0.1}`
 
 	assert.Equal(t, http.StatusCreated, w.Code)
 	assert.Equal(t, wantResponse, strings.TrimSpace(w.Body.String()))
 }
 
+func TestCreateConfigurationConcurrently(t *testing.T) {
+	cc := make(chan *backend.ConfigChan)
+	backend.NewSceneReloadDaemon(persistent.NewInMemorySceneRepository(), cc).Start(4)
+
+	handler := api.CreateConfiguration(cc)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+
+		go func(x int) {
+			defer wg.Done()
+
+			postData := fmt.Sprintf(`{
+	"scene": {"x": 800, "y": 500},
+	"light": {"x": %d, "y": 300},
+	"polygons": [
+		[{"x": 600, "y": 200}, {"x": 646, "y": 133}, {"x": 646, "y": 261}]
+	]
+}`, x)
+
+			r, _ := http.NewRequest("POST", "/api/v1/scene/config", strings.NewReader(postData))
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, r)
+
+			// all requests are for the same scene, so most of them are superseded
+			if w.Code == http.StatusConflict {
+				return
+			}
+
+			got := &struct{ Light *vector.Vector }{}
+			assert.Equal(t, http.StatusCreated, w.Code)
+			assert.Nil(t, json.Unmarshal(w.Body.Bytes(), got))
+			assert.Equal(t, float64(x), got.Light.X)
+		}(i + 100)
+	}
+
+	wg.Wait()
+	close(cc)
+}
+
+func TestCreateConfigurationWithCancelledContext(t *testing.T) {
+	postData := `{
+	"scene": {"x": 800, "y": 500},
+	"light": {"x": 250, "y": 300},
+	"polygons": []
+}`
+
+	// nobody receives from the config chan, so the request waits for a free worker
+	cc := make(chan *backend.ConfigChan)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	r, _ := http.NewRequest("POST", "/api/v1/scene/config", strings.NewReader(postData))
+	r = r.WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	api.CreateConfiguration(cc).ServeHTTP(w, r)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestCreateConfigurationSuperseded(t *testing.T) {
+	postData := `{
+	"scene": {"x": 800, "y": 500},
+	"light": {"x": 250, "y": 300},
+	"polygons": []
+}`
+
+	cc := make(chan *backend.ConfigChan)
+
+	r, _ := http.NewRequest("POST", "/api/v1/scene/config", strings.NewReader(postData))
+	w := httptest.NewRecorder()
+
+	go func() {
+		gotConfig := <-cc
+		gotConfig.Response <- &backend.SceneReloadResponse{Err: backend.ErrSuperseded}
+	}()
+
+	api.CreateConfiguration(cc).ServeHTTP(w, r)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
 func TestCreateConfigurationFromBrokenJSON(t *testing.T) {
 	postData := `broken_json`
 
 	cc := make(chan *backend.ConfigChan)
-	srrc := make(chan *backend.SceneReloadResponse)
-
-	srrcFactory := map[string]chan *backend.SceneReloadResponse{backend.CreateConfigHandler: srrc}
 
 	body := bytes.NewReader([]byte(postData))
 	r, _ := http.NewRequest("POST", "/api/v1/scene/config", body)
 	w := httptest.NewRecorder()
 
-	api.CreateConfiguration(cc, srrcFactory).ServeHTTP(w, r)
+	api.CreateConfiguration(cc).ServeHTTP(w, r)
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
@@ -111,19 +343,16 @@ func TestCreateConfigurationWithSceneReloadResponseError(t *testing.T) {
 }`
 
 	cc := make(chan *backend.ConfigChan)
-	srrc := make(chan *backend.SceneReloadResponse)
-
-	srrcFactory := map[string]chan *backend.SceneReloadResponse{backend.CreateConfigHandler: srrc}
 
 	body := bytes.NewReader([]byte(postData))
 	r, _ := http.NewRequest("POST", "/api/v1/scene/config", body)
 	w := httptest.NewRecorder()
 
 	go func() {
-		<-cc
-		srrcFactory[backend.CreateConfigHandler] <- &backend.SceneReloadResponse{Err: errors.New("error"), Scene: &backend.Scene{}}
+		gotConfig := <-cc
+		gotConfig.Response <- &backend.SceneReloadResponse{Err: errors.New("error"), Scene: &backend.Scene{}}
 	}()
 
-	api.CreateConfiguration(cc, srrcFactory).ServeHTTP(w, r)
+	api.CreateConfiguration(cc).ServeHTTP(w, r)
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
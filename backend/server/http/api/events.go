@@ -0,0 +1,150 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+)
+
+// eventsKeepAlive is how often a comment is sent on an idle event stream,
+// so the proxies in between do not close it
+const eventsKeepAlive = 15 * time.Second
+
+// SceneEventStream is an http handler which streams the events of the scene with the ID
+// from the {scene} path parameter as Server-Sent Events, one for each stored version. The
+// id of every event is its version, a client which reconnects with the Last-Event-ID
+// header first gets an event for each version stored after it from the repository. A
+// Last-Event-ID above the latest version is stale, e.g. from before a restart of a server
+// with the memory storage which numbers the versions from 1 again, then the client gets
+// the latest version again and every version stored after it
+func SceneEventStream(sceneRepo backend.SceneRepository, events *backend.SceneEvents) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := sceneID(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(err)
+			return
+		}
+
+		lastVersion := 0
+		if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" {
+			lastVersion, err = strconv.Atoi(lastEventID)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(err)
+				return
+			}
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		// subscribes before reading the missed versions, so no version is lost in between
+		subscription, unsubscribe := events.Subscribe(id)
+		defer unsubscribe()
+
+		missed, lastVersion, err := missedSceneEvents(r, sceneRepo, id, lastVersion)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+
+		for _, event := range missed {
+			writeSceneEvent(w, event)
+			lastVersion = event.Version.ID
+		}
+		flusher.Flush()
+
+		keepAlive := time.NewTicker(eventsKeepAlive)
+		defer keepAlive.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-keepAlive.C:
+				fmt.Fprint(w, ": keep-alive\n\n")
+			case event, ok := <-subscription:
+				// the client is too slow, it reconnects and catches up with Last-Event-ID
+				if !ok {
+					return
+				}
+
+				// already sent as a missed version
+				if event.Version.ID <= lastVersion {
+					continue
+				}
+
+				writeSceneEvent(w, event)
+				lastVersion = event.Version.ID
+			}
+
+			flusher.Flush()
+		}
+	}
+}
+
+// missedSceneEvents returns the events of the versions of the scene stored after lastVersion
+// and the version the stream resumes after, which is lastVersion unless it is stale. There
+// are no events if lastVersion is 0 because the client has not received any event yet
+func missedSceneEvents(r *http.Request, sceneRepo backend.SceneRepository, id string, lastVersion int) ([]*backend.SceneEvent, int, error) {
+	if lastVersion == 0 {
+		return nil, 0, nil
+	}
+
+	latest, err := sceneRepo.LatestVersion(r.Context(), id)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if latest == nil {
+		return nil, 0, nil
+	}
+
+	// stale, the latest version is sent again
+	if lastVersion > latest.ID {
+		lastVersion = latest.ID - 1
+	}
+
+	if lastVersion == latest.ID {
+		return nil, lastVersion, nil
+	}
+
+	versions, err := sceneRepo.ListVersions(r.Context(), id)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	events := []*backend.SceneEvent{}
+	for _, version := range versions {
+		if version.ID <= lastVersion {
+			continue
+		}
+
+		scene, err := sceneRepo.GetVersion(r.Context(), id, version.ID)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		events = append(events, &backend.SceneEvent{SceneID: id, Version: version, Summary: backend.NewSceneSummary(scene)})
+	}
+
+	return events, lastVersion, nil
+}
+
+func writeSceneEvent(w http.ResponseWriter, event *backend.SceneEvent) {
+	data, _ := json.Marshal(event)
+	fmt.Fprintf(w, "id: %d\nevent: scene\ndata: %s\n\n", event.Version.ID, data)
+}
@@ -0,0 +1,149 @@
+package api_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+	"github.com/iliyanmotovski/raytracer/backend/persistent"
+	"github.com/iliyanmotovski/raytracer/backend/server/http/api"
+)
+
+func TestSceneEventStream(t *testing.T) {
+	events := backend.NewSceneEvents()
+	sceneRepo := backend.NewPublishingSceneRepository(persistent.NewInMemorySceneRepository(), events)
+
+	upsert := func(litArea float64) {
+		scene := newVersionScene(litArea, 250, 300)
+		scene.ID = backend.DefaultSceneID
+
+		_, err := sceneRepo.Upsert(context.Background(), scene)
+		assert.Nil(t, err)
+	}
+
+	upsert(10)
+	upsert(20)
+
+	server := httptest.NewServer(api.SceneEventStream(sceneRepo, events))
+	defer server.Close()
+
+	// resumes after the first version, so the second one is sent from the repository
+	r, _ := http.NewRequest("GET", server.URL, nil)
+	r.Header.Set("Last-Event-ID", "1")
+
+	resp, err := http.DefaultClient.Do(r)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	stream := bufio.NewReader(resp.Body)
+
+	id, event := readSceneEvent(t, stream)
+	assert.Equal(t, "2", id)
+	assert.Equal(t, 2, event.Version.ID)
+	assert.Equal(t, float64(20), event.Summary.LitArea)
+
+	upsert(30)
+
+	id, event = readSceneEvent(t, stream)
+	assert.Equal(t, "3", id)
+	assert.Equal(t, backend.DefaultSceneID, event.SceneID)
+	assert.Equal(t, float64(30), event.Summary.LitArea)
+	assert.Equal(t, 1, event.Summary.Polygons)
+}
+
+func TestSceneEventStreamWithStaleLastEventID(t *testing.T) {
+	events := backend.NewSceneEvents()
+	sceneRepo := backend.NewPublishingSceneRepository(persistent.NewInMemorySceneRepository(), events)
+
+	upsert := func(litArea float64) {
+		scene := newVersionScene(litArea, 250, 300)
+		scene.ID = backend.DefaultSceneID
+
+		_, err := sceneRepo.Upsert(context.Background(), scene)
+		assert.Nil(t, err)
+	}
+
+	server := httptest.NewServer(api.SceneEventStream(sceneRepo, events))
+	defer server.Close()
+
+	// the client reconnects with an id from before a restart which has lost the versions
+	connect := func() (*http.Response, *bufio.Reader) {
+		r, _ := http.NewRequest("GET", server.URL, nil)
+		r.Header.Set("Last-Event-ID", "57")
+
+		resp, err := http.DefaultClient.Do(r)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		return resp, bufio.NewReader(resp.Body)
+	}
+
+	// there is no version yet, so the first stored one is sent
+	resp, stream := connect()
+	defer resp.Body.Close()
+
+	upsert(10)
+
+	id, event := readSceneEvent(t, stream)
+	assert.Equal(t, "1", id)
+	assert.Equal(t, float64(10), event.Summary.LitArea)
+
+	upsert(20)
+	readSceneEvent(t, stream)
+
+	// the latest version is sent again, followed by the newer ones
+	resp, stream = connect()
+	defer resp.Body.Close()
+
+	id, event = readSceneEvent(t, stream)
+	assert.Equal(t, "2", id)
+	assert.Equal(t, float64(20), event.Summary.LitArea)
+
+	upsert(30)
+
+	id, event = readSceneEvent(t, stream)
+	assert.Equal(t, "3", id)
+	assert.Equal(t, float64(30), event.Summary.LitArea)
+}
+
+func TestSceneEventStreamWithInvalidLastEventID(t *testing.T) {
+	r, _ := http.NewRequest("GET", "/api/v1/scene/events", nil)
+	r.Header.Set("Last-Event-ID", "abc")
+	w := httptest.NewRecorder()
+
+	api.SceneEventStream(new(backend.FakeSceneRepository), backend.NewSceneEvents()).ServeHTTP(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// readSceneEvent reads the next event from the stream and returns its id and data
+func readSceneEvent(t *testing.T, stream *bufio.Reader) (string, *backend.SceneEvent) {
+	var id string
+	event := &backend.SceneEvent{}
+
+	for {
+		line, err := stream.ReadString('\n')
+		if !assert.Nil(t, err) {
+			return id, event
+		}
+
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			return id, event
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "data: "):
+			assert.Nil(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), event))
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+	"github.com/iliyanmotovski/raytracer/backend/render"
+)
+
+// GetScenePNG is an http handler which renders the scene with the ID from the {scene} path
+// parameter as a PNG image. The optional width and height query parameters set the size of
+// the image and the parameters with the names of render.ColorNames set its colors, e.g.
+// ?width=400&background=202020
+func GetScenePNG(sceneRepo backend.SceneRepository) http.HandlerFunc {
+	return renderScene(sceneRepo, "image/png", render.PNG)
+}
+
+// GetSceneSVG is an http handler which renders the scene with the ID from the {scene} path
+// parameter as an SVG image, it takes the same query parameters as GetScenePNG
+func GetSceneSVG(sceneRepo backend.SceneRepository) http.HandlerFunc {
+	return renderScene(sceneRepo, "image/svg+xml", render.SVG)
+}
+
+// renderScene is an http handler which writes the scene encoded by encode with the content type
+func renderScene(sceneRepo backend.SceneRepository, contentType string, encode func(io.Writer, *backend.Scene, render.Options) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := sceneID(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(err)
+			return
+		}
+
+		opts, err := renderOptions(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(err.Error())
+			return
+		}
+
+		scene, err := sceneRepo.Get(r.Context(), id)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(err)
+			return
+		}
+
+		if scene == nil {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(backend.ErrSceneNotFound)
+			return
+		}
+
+		buf := &bytes.Buffer{}
+		if err := encode(buf, scene, opts); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(http.StatusOK)
+		buf.WriteTo(w)
+	}
+}
+
+// renderOptions returns the default render options with the size and the colors from the query
+func renderOptions(r *http.Request) (render.Options, error) {
+	opts := render.DefaultOptions()
+	query := r.URL.Query()
+
+	for name, size := range map[string]*int{"width": &opts.Width, "height": &opts.Height} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > render.MaxSize {
+			return opts, fmt.Errorf("%s must be between 1 and %d", name, render.MaxSize)
+		}
+
+		*size = n
+	}
+
+	for _, name := range render.ColorNames {
+		if value := query.Get(name); value != "" {
+			if err := opts.SetColor(name, value); err != nil {
+				return opts, err
+			}
+		}
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,79 @@
+package api_test
+
+import (
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+	"github.com/iliyanmotovski/raytracer/backend/server/http/api"
+)
+
+func TestGetScenePNG(t *testing.T) {
+	sceneRepo := new(backend.FakeSceneRepository)
+	sceneRepo.On("Get", backend.DefaultSceneID).Return(newVersionScene(60, 250, 300), nil)
+
+	r, _ := http.NewRequest("GET", "/api/v1/scene.png?width=400&background=102030", nil)
+	w := httptest.NewRecorder()
+
+	api.GetScenePNG(sceneRepo).ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+
+	img, err := png.Decode(w.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, 400, img.Bounds().Dx())
+	assert.Equal(t, 250, img.Bounds().Dy())
+
+	// the scene has no lit triangles, so the corner is the background
+	assert.Equal(t, color.RGBA{R: 0x10, G: 0x20, B: 0x30, A: 255}, color.RGBAModel.Convert(img.At(399, 249)))
+
+	sceneRepo.AssertExpectations(t)
+}
+
+func TestGetSceneSVG(t *testing.T) {
+	sceneRepo := new(backend.FakeSceneRepository)
+	sceneRepo.On("Get", "second").Return(newVersionScene(60, 250, 300), nil)
+
+	r, _ := http.NewRequest("GET", "/api/v1/scenes/second.svg?width=400&background=102030", nil)
+	r = mux.SetURLVars(r, map[string]string{"scene": "second"})
+	w := httptest.NewRecorder()
+
+	api.GetSceneSVG(sceneRepo).ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/svg+xml", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), `width="400" height="250" viewBox="0 0 800 500"`)
+	assert.Contains(t, w.Body.String(), `<rect id="bounds" class="bounds" x="0" y="0" width="800" height="500" fill="#102030"/>`)
+
+	sceneRepo.AssertExpectations(t)
+}
+
+func TestGetScenePNGWithInvalidOptions(t *testing.T) {
+	for _, query := range []string{"width=0", "height=abc", "width=100000", "lit=xyz"} {
+		r, _ := http.NewRequest("GET", "/api/v1/scene.png?"+query, nil)
+		w := httptest.NewRecorder()
+
+		api.GetScenePNG(new(backend.FakeSceneRepository)).ServeHTTP(w, r)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestGetScenePNGNotFound(t *testing.T) {
+	sceneRepo := new(backend.FakeSceneRepository)
+	sceneRepo.On("Get", backend.DefaultSceneID).Return((*backend.Scene)(nil), nil)
+
+	r, _ := http.NewRequest("GET", "/api/v1/scene.png", nil)
+	w := httptest.NewRecorder()
+
+	api.GetScenePNG(sceneRepo).ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	sceneRepo.AssertExpectations(t)
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+	"github.com/iliyanmotovski/raytracer/backend/vector"
+)
+
+// MoveLight is an http handler which moves the light of the scene with the ID from the
+// {scene} path parameter. It takes only the new light position and reuses the already
+// validated polygons of the persisted scene, so it is much faster than creating a
+// new configuration while the light is dragged around. It returns the updated scene
+func MoveLight(cc chan *backend.ConfigChan) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := sceneID(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(err)
+			return
+		}
+
+		light := new(xy)
+
+		if err := json.NewDecoder(r.Body).Decode(light); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(err)
+			return
+		}
+
+		// send the light to the daemon to be moved in the persisted scene
+		moved, err := backend.MoveSceneLight(r.Context(), cc, id, &vector.Vector{X: light.X, Y: light.Y})
+		if err != nil {
+			w.WriteHeader(reloadErrorStatus(err))
+			json.NewEncoder(w).Encode(err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(newSceneDTO(moved))
+	}
+}
@@ -0,0 +1,104 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+	"github.com/iliyanmotovski/raytracer/backend/persistent"
+	"github.com/iliyanmotovski/raytracer/backend/server/http/api"
+	"github.com/iliyanmotovski/raytracer/backend/vector"
+)
+
+func TestMoveLight(t *testing.T) {
+	cc := make(chan *backend.ConfigChan)
+
+	r, _ := http.NewRequest("PATCH", "/api/v1/scenes/second/light", strings.NewReader(`{"x": 200, "y": 320}`))
+	r = mux.SetURLVars(r, map[string]string{"scene": "second"})
+	w := httptest.NewRecorder()
+
+	moved := newVersionScene(55.5, 200, 320)
+	moved.ID = "second"
+
+	go func() {
+		gotConfig := <-cc
+		assert.Equal(t, r.Context(), gotConfig.Ctx)
+		assert.True(t, gotConfig.LightOnly)
+		assert.Equal(t, &backend.Config{ID: "second", Light: &vector.Vector{X: 200, Y: 320}}, gotConfig.Config)
+
+		gotConfig.Response <- &backend.SceneReloadResponse{Err: nil, Scene: moved}
+	}()
+
+	api.MoveLight(cc).ServeHTTP(w, r)
+
+	wantResponse := `{"ID":"second","Width":800,"Height":500,"LitArea":55.5,"Light":{"X":200,"Y":320},` +
+		`"Polygons":[[{"X":600,"Y":200},{"X":646,"Y":133},{"X":646,"Y":261}]],"Triangles":[]}`
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, wantResponse, strings.TrimSpace(w.Body.String()))
+}
+
+func TestMoveLightOfLoadedScene(t *testing.T) {
+	sceneRepo := persistent.NewInMemorySceneRepository()
+
+	cc := make(chan *backend.ConfigChan)
+	backend.NewSceneReloadDaemon(sceneRepo, cc).Start(1)
+	defer close(cc)
+
+	config := newVersionScene(0, 250, 300).Config()
+	config.ID = backend.DefaultSceneID
+
+	loaded, err := backend.ReloadScene(context.Background(), cc, config)
+	assert.Nil(t, err)
+
+	r, _ := http.NewRequest("PATCH", "/api/v1/scene/light", strings.NewReader(`{"x": 700, "y": 100}`))
+	w := httptest.NewRecorder()
+
+	api.MoveLight(cc).ServeHTTP(w, r)
+
+	got := &struct {
+		Light     *vector.Vector
+		LitArea   float64
+		Triangles []interface{}
+	}{}
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), got))
+	assert.Equal(t, &vector.Vector{X: 700, Y: 100}, got.Light)
+	assert.NotEqual(t, loaded.LitArea, got.LitArea)
+	assert.NotEmpty(t, got.Triangles)
+
+	versions, err := sceneRepo.ListVersions(context.Background(), backend.DefaultSceneID)
+	assert.Nil(t, err)
+	assert.Len(t, versions, 2)
+}
+
+func TestMoveLightOfMissingScene(t *testing.T) {
+	cc := make(chan *backend.ConfigChan)
+	backend.NewSceneReloadDaemon(persistent.NewInMemorySceneRepository(), cc).Start(1)
+	defer close(cc)
+
+	r, _ := http.NewRequest("PATCH", "/api/v1/scenes/missing/light", strings.NewReader(`{"x": 200, "y": 320}`))
+	r = mux.SetURLVars(r, map[string]string{"scene": "missing"})
+	w := httptest.NewRecorder()
+
+	api.MoveLight(cc).ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestMoveLightFromBrokenJSON(t *testing.T) {
+	cc := make(chan *backend.ConfigChan)
+
+	r, _ := http.NewRequest("PATCH", "/api/v1/scene/light", strings.NewReader(`broken_json`))
+	w := httptest.NewRecorder()
+
+	api.MoveLight(cc).ServeHTTP(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/iliyanmotovski/raytracer/backend/logging"
+)
+
+// RequestIDHeader is the header with the ID of the request, which is returned in the response
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID matches the request IDs which are taken from the requests, e.g. set by a proxy
+var validRequestID = regexp.MustCompile(`^[a-zA-Z0-9._-]{1,64}$`)
+
+// RequestLogging is a mux middleware which puts the request ID into the context of the request,
+// so every line logged for it carries the ID, including the lines of the daemon which processes
+// its config. The ID is taken from the X-Request-ID header or a new one is assigned. Every
+// handled request is logged with its status and duration
+func RequestLogging(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = logging.NewRequestID()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		r = r.WithContext(logging.WithRequestID(r.Context(), id))
+
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+
+		logging.FromContext(r.Context()).Info("request handled", "method", r.Method, "path", r.URL.Path,
+			"status", recorder.status(), "duration", time.Since(start))
+	})
+}
@@ -0,0 +1,39 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iliyanmotovski/raytracer/backend/logging"
+	"github.com/iliyanmotovski/raytracer/backend/server/http/api"
+)
+
+func TestRequestLogging(t *testing.T) {
+	var id string
+	handler := api.RequestLogging(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id = logging.RequestID(r.Context())
+	}))
+
+	// the ID of the request is kept
+	r := newGetRequest("/api/v1/scene")
+	r.Header.Set(api.RequestIDHeader, "proxy-1.2")
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, "proxy-1.2", id)
+	assert.Equal(t, "proxy-1.2", w.Header().Get(api.RequestIDHeader))
+
+	// a missing or an invalid ID is replaced with a new one
+	for _, header := range []string{"", "with spaces", string(make([]byte, 65))} {
+		r := newGetRequest("/api/v1/scene")
+		r.Header.Set(api.RequestIDHeader, header)
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, r)
+		assert.Len(t, id, 16)
+		assert.Equal(t, id, w.Header().Get(api.RequestIDHeader))
+	}
+}
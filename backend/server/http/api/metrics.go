@@ -0,0 +1,108 @@
+package api
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/iliyanmotovski/raytracer/backend/metrics"
+)
+
+// RouteMetrics returns a mux middleware which counts the requests by their method, route and
+// response status and measures their durations. The route is the path template of the matched
+// route without the patterns of its variables, e.g. /api/v1/scenes/{scene}/versions/{version},
+// so the number of the series does not grow with the scenes and their versions
+func RouteMetrics(registry *metrics.Registry) mux.MiddlewareFunc {
+	requests := registry.NewCounterVec("raytracer_http_requests_total",
+		"Number of the handled HTTP requests by method, route and status.", "method", "route", "status")
+	durations := registry.NewHistogramVec("raytracer_http_request_duration_seconds",
+		"Duration of the HTTP requests by method and route in seconds.", metrics.DefaultBuckets, "method", "route")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			recorder := &statusRecorder{ResponseWriter: w}
+
+			next.ServeHTTP(recorder, r)
+
+			route := routeTemplate(r)
+			requests.Inc(r.Method, route, strconv.Itoa(recorder.status()))
+			durations.Observe(time.Since(start).Seconds(), r.Method, route)
+		})
+	}
+}
+
+var routeVariablePattern = regexp.MustCompile(`\{(\w+):[^}]*\}`)
+
+// routeTemplate returns the path template of the matched route without the patterns of its variables
+func routeTemplate(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return "unknown"
+	}
+
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return "unknown"
+	}
+
+	return routeVariablePattern.ReplaceAllString(template, "{$1}")
+}
+
+// statusRecorder records the status of the response, it keeps the streaming of the event
+// streams and the hijacking of the websockets working through the wrapped ResponseWriter
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if s.code == 0 {
+		s.code = code
+	}
+
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.code == 0 {
+		s.code = http.StatusOK
+	}
+
+	return s.ResponseWriter.Write(b)
+}
+
+func (s *statusRecorder) Flush() {
+	if flusher, ok := s.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (s *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := s.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response does not support hijacking")
+	}
+
+	conn, rw, err := hijacker.Hijack()
+	if err == nil && s.code == 0 {
+		s.code = http.StatusSwitchingProtocols
+	}
+
+	return conn, rw, err
+}
+
+// status returns the status of the response, which is 200 when the handler has not written anything
+func (s *statusRecorder) status() int {
+	if s.code == 0 {
+		return http.StatusOK
+	}
+
+	return s.code
+}
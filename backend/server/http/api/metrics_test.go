@@ -0,0 +1,48 @@
+package api_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iliyanmotovski/raytracer/backend/metrics"
+	"github.com/iliyanmotovski/raytracer/backend/server/http/api"
+)
+
+func TestRouteMetrics(t *testing.T) {
+	registry := metrics.NewRegistry()
+
+	router := mux.NewRouter()
+	router.Use(api.RouteMetrics(registry))
+	router.HandleFunc("/api/v1/scenes/{scene:[a-z]+}", func(w http.ResponseWriter, r *http.Request) {
+		if mux.Vars(r)["scene"] == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Write([]byte("{}"))
+	})
+	router.HandleFunc("/api/v1/stream", func(w http.ResponseWriter, r *http.Request) {
+		// the recorder keeps the response streaming
+		_, ok := w.(http.Flusher)
+		assert.True(t, ok)
+	})
+
+	for _, path := range []string{"/api/v1/scenes/first", "/api/v1/scenes/second", "/api/v1/scenes/missing", "/api/v1/stream"} {
+		router.ServeHTTP(httptest.NewRecorder(), newGetRequest(path))
+	}
+
+	buf := &bytes.Buffer{}
+	assert.Nil(t, registry.Write(buf))
+
+	lines := strings.Split(buf.String(), "\n")
+	assert.Contains(t, lines, `raytracer_http_requests_total{method="GET",route="/api/v1/scenes/{scene}",status="200"} 2`)
+	assert.Contains(t, lines, `raytracer_http_requests_total{method="GET",route="/api/v1/scenes/{scene}",status="404"} 1`)
+	assert.Contains(t, lines, `raytracer_http_requests_total{method="GET",route="/api/v1/stream",status="200"} 1`)
+	assert.Contains(t, lines, `raytracer_http_request_duration_seconds_count{method="GET",route="/api/v1/scenes/{scene}"} 3`)
+}
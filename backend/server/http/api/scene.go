@@ -4,74 +4,165 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/gorilla/mux"
+
 	"github.com/iliyanmotovski/raytracer/backend"
 )
 
-// GetScene is an http handler which gets the scene from the persistence
-// and returns it to the caller
+// GetScene is an http handler which gets the scene with the ID from the
+// {scene} path parameter from the persistence and returns it to the caller
 func GetScene(sceneRepo backend.SceneRepository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		scene, err := sceneRepo.Get(r.Context())
+		id, err := sceneID(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(err)
+			return
+		}
+
+		scene, err := sceneRepo.Get(r.Context(), id)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(err)
 			return
 		}
 
-		resp := &sceneDTO{
-			Light:     &xy{X: scene.Light.X, Y: scene.Light.Y},
-			Width:     scene.Width,
-			Height:    scene.Height,
-			LitArea:   scene.LitArea,
-			Polygons:  scene.Polygons,
-			Triangles: scene.Triangles,
+		if scene == nil {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(backend.ErrSceneNotFound)
+			return
 		}
 
 		w.WriteHeader(http.StatusOK)
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(resp)
+		json.NewEncoder(w).Encode(newSceneDTO(scene))
+	}
+}
+
+// sceneID returns the ID of the scene from the {scene} path parameter,
+// the routes without it are aliases to the default scene
+func sceneID(r *http.Request) (string, error) {
+	id, ok := mux.Vars(r)["scene"]
+	if !ok {
+		return backend.DefaultSceneID, nil
 	}
+
+	return id, backend.ValidateSceneID(id)
 }
 
+// ellipseRenderSegments is the number of vertices of the
+// ellipses approximation sent to the frontend for drawing
+const ellipseRenderSegments = 64
+
 type sceneDTO struct {
+	ID                     string
 	Width, Height, LitArea float64
 	Light                  *xy
 	Polygons               backend.Polygons
+	Walls                  backend.Walls
+	Ellipses               backend.Ellipses
 	Triangles              backend.Triangles
+	FlatteningTolerance    float64
+}
+
+func newSceneDTO(scene *backend.Scene) *sceneDTO {
+	return &sceneDTO{
+		ID:                  scene.ID,
+		Light:               &xy{X: scene.Light.X, Y: scene.Light.Y},
+		Width:               scene.Width,
+		Height:              scene.Height,
+		LitArea:             scene.LitArea,
+		Polygons:            scene.Polygons,
+		Walls:               scene.Walls,
+		Ellipses:            scene.Ellipses,
+		Triangles:           scene.Triangles,
+		FlatteningTolerance: scene.FlatteningTolerance,
+	}
 }
 
 func (c *sceneDTO) MarshalJSON() ([]byte, error) {
 	dto := &struct {
+		ID                     string `json:",omitempty"`
 		Width, Height, LitArea float64
 		Light                  *xy
-		Polygons               [][]*xy
+		Polygons               [][]*vertexDTO
+		Walls                  [][]*xy       `json:",omitempty"`
+		Ellipses               []*ellipseDTO `json:",omitempty"`
 		Triangles              [][]*xy
+		Arcs                   []*arcDTO `json:",omitempty"`
+		FlatteningTolerance    float64   `json:",omitempty"`
 	}{}
 
+	dto.ID = c.ID
 	dto.Width = c.Width
 	dto.Height = c.Height
 	dto.LitArea = c.LitArea
 	dto.Light = c.Light
-	dto.Polygons = make([][]*xy, len(c.Polygons))
-	dto.Triangles = make([][]*xy, len(c.Triangles))
+	dto.FlatteningTolerance = c.FlatteningTolerance
+	dto.Polygons = make([][]*vertexDTO, len(c.Polygons))
 
 	for i, polygon := range c.Polygons {
-		poly := make([]*xy, len(polygon.Loop))
-		for j, vertice := range polygon.Loop {
-			poly[j] = &xy{X: vertice.X, Y: vertice.Y}
+		dto.Polygons[i] = newPolygonDTO(polygon)
+	}
+
+	for _, wall := range c.Walls {
+		w := make([]*xy, len(wall.Vertices))
+		for j, vertice := range wall.Vertices {
+			w[j] = &xy{X: vertice.X, Y: vertice.Y}
+		}
+
+		dto.Walls = append(dto.Walls, w)
+	}
+
+	for _, ellipse := range c.Ellipses {
+		e := newEllipseDTO(ellipse)
+		for _, vertice := range ellipse.Polygonize(ellipseRenderSegments).Loop {
+			e.Loop = append(e.Loop, &xy{X: vertice.X, Y: vertice.Y})
 		}
 
-		dto.Polygons[i] = poly
+		dto.Ellipses = append(dto.Ellipses, e)
 	}
 
-	for i, triangle := range c.Triangles {
+	dto.Triangles, dto.Arcs = newTrianglesDTO(c.Triangles)
+
+	return json.Marshal(dto)
+}
+
+// newTrianglesDTO returns the vertices of the triangles
+// and the arcs of the ones which have a curved far side
+func newTrianglesDTO(triangles backend.Triangles) ([][]*xy, []*arcDTO) {
+	result := make([][]*xy, len(triangles))
+	var arcs []*arcDTO
+
+	for i, triangle := range triangles {
 		tri := make([]*xy, len(triangle.Loop))
 		for j, vertice := range triangle.Loop {
 			tri[j] = &xy{X: vertice.X, Y: vertice.Y}
 		}
 
-		dto.Triangles[i] = tri
+		result[i] = tri
+
+		if triangle.Arc != nil {
+			arcs = append(arcs, &arcDTO{
+				Triangle: i,
+				Center:   &xy{X: triangle.Arc.Ellipse.Center.X, Y: triangle.Arc.Ellipse.Center.Y},
+				RadiusX:  triangle.Arc.Ellipse.RadiusX,
+				RadiusY:  triangle.Arc.Ellipse.RadiusY,
+				From:     triangle.Arc.From,
+				To:       triangle.Arc.To,
+			})
+		}
 	}
 
-	return json.Marshal(dto)
+	return result, arcs
+}
+
+// arcDTO marks that the far side of the triangle with index
+// Triangle is an arc of the ellipse between the parametric
+// angles From and To, instead of a straight line
+type arcDTO struct {
+	Triangle         int
+	Center           *xy
+	RadiusX, RadiusY float64
+	From, To         float64
 }
@@ -1,12 +1,14 @@
 package api_test
 
 import (
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
+	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/iliyanmotovski/raytracer/backend"
@@ -45,7 +47,7 @@ func TestGetScene(t *testing.T) {
 	}
 
 	sceneRepo := new(backend.FakeSceneRepository)
-	sceneRepo.On("Get").Return(scene, nil)
+	sceneRepo.On("Get", backend.DefaultSceneID).Return(scene, nil)
 
 	r, _ := http.NewRequest("GET", "/api/v1/scene", nil)
 	w := httptest.NewRecorder()
@@ -60,9 +62,122 @@ func TestGetScene(t *testing.T) {
 	sceneRepo.AssertExpectations(t)
 }
 
+func TestGetSceneWithEllipses(t *testing.T) {
+	circle := backend.NewCircle(400, 400, 50)
+
+	scene := &backend.Scene{
+		Width:    800,
+		Height:   500,
+		LitArea:  60,
+		Light:    &vector.Vector{X: 400, Y: 100},
+		Ellipses: backend.Ellipses{circle},
+		Triangles: backend.Triangles{
+			{
+				Polygon: backend.Polygon{
+					VerticesCount: 3,
+					Loop: vector.Loop{
+						{X: 400, Y: 100},
+						{X: 350, Y: 400},
+						{X: 450, Y: 400},
+					},
+				},
+				Arc: &backend.Arc{Ellipse: circle, From: 3, To: 0},
+			},
+		},
+	}
+
+	sceneRepo := new(backend.FakeSceneRepository)
+	sceneRepo.On("Get", backend.DefaultSceneID).Return(scene, nil)
+
+	r, _ := http.NewRequest("GET", "/api/v1/scene", nil)
+	w := httptest.NewRecorder()
+
+	api.GetScene(sceneRepo).ServeHTTP(w, r)
+
+	got := &struct {
+		Ellipses []*struct {
+			Center           *vector.Vector
+			RadiusX, RadiusY float64
+			Loop             []*vector.Vector
+		}
+		Arcs []*struct {
+			Triangle int
+			From, To float64
+		}
+	}{}
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), got))
+
+	assert.Len(t, got.Ellipses, 1)
+	assert.Equal(t, &vector.Vector{X: 400, Y: 400}, got.Ellipses[0].Center)
+	assert.Equal(t, float64(50), got.Ellipses[0].RadiusX)
+	assert.Equal(t, float64(50), got.Ellipses[0].RadiusY)
+	assert.Len(t, got.Ellipses[0].Loop, 64)
+
+	assert.Len(t, got.Arcs, 1)
+	assert.Equal(t, 0, got.Arcs[0].Triangle)
+	assert.Equal(t, float64(3), got.Arcs[0].From)
+	assert.Equal(t, float64(0), got.Arcs[0].To)
+
+	sceneRepo.AssertExpectations(t)
+}
+
+func TestGetSceneByID(t *testing.T) {
+	scene := &backend.Scene{
+		ID:     "second",
+		Width:  800,
+		Height: 500,
+		Light:  &vector.Vector{X: 250, Y: 300},
+	}
+
+	sceneRepo := new(backend.FakeSceneRepository)
+	sceneRepo.On("Get", "second").Return(scene, nil)
+
+	r, _ := http.NewRequest("GET", "/api/v1/scenes/second", nil)
+	r = mux.SetURLVars(r, map[string]string{"scene": "second"})
+	w := httptest.NewRecorder()
+
+	api.GetScene(sceneRepo).ServeHTTP(w, r)
+
+	wantResponse := `{"ID":"second","Width":800,"Height":500,"LitArea":0,"Light":{"X":250,"Y":300},"Polygons":[],"Triangles":[]}`
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, wantResponse, strings.TrimSpace(w.Body.String()))
+
+	sceneRepo.AssertExpectations(t)
+}
+
+func TestGetSceneNotFound(t *testing.T) {
+	sceneRepo := new(backend.FakeSceneRepository)
+	sceneRepo.On("Get", "missing").Return((*backend.Scene)(nil), nil)
+
+	r, _ := http.NewRequest("GET", "/api/v1/scenes/missing", nil)
+	r = mux.SetURLVars(r, map[string]string{"scene": "missing"})
+	w := httptest.NewRecorder()
+
+	api.GetScene(sceneRepo).ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	sceneRepo.AssertExpectations(t)
+}
+
+func TestGetSceneWithInvalidID(t *testing.T) {
+	sceneRepo := new(backend.FakeSceneRepository)
+
+	r, _ := http.NewRequest("GET", "/api/v1/scenes/in.valid", nil)
+	r = mux.SetURLVars(r, map[string]string{"scene": "in.valid"})
+	w := httptest.NewRecorder()
+
+	api.GetScene(sceneRepo).ServeHTTP(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	sceneRepo.AssertExpectations(t)
+}
+
 func TestGetSceneWithRepositoryFailure(t *testing.T) {
 	sceneRepo := new(backend.FakeSceneRepository)
-	sceneRepo.On("Get").Return(&backend.Scene{}, errors.New("error"))
+	sceneRepo.On("Get", backend.DefaultSceneID).Return(&backend.Scene{}, errors.New("error"))
 
 	r, _ := http.NewRequest("GET", "/api/v1/scene", nil)
 	w := httptest.NewRecorder()
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+	"github.com/iliyanmotovski/raytracer/backend/logging"
+	"github.com/iliyanmotovski/raytracer/backend/tracing"
+	"github.com/iliyanmotovski/raytracer/backend/vector"
+)
+
+var upgrader = websocket.Upgrader{}
+
+// SceneSocket is a websocket handler used for live dragging of the light of the scene with
+// the ID from the {scene} path parameter. The client streams the light positions as
+// {"x": 200, "y": 320} messages and the server streams back the lit triangles and the lit
+// area of each of them. Only the latest position is kept while the previous one is being
+// processed and sent, so a slow client or scene never makes the positions pile up.
+// The positions are not stored, except the one sent with "release": true at the end of
+// the drag, which is stored as a new version of the scene
+func SceneSocket(cc chan *backend.ConfigChan) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := sceneID(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			// the upgrader has already responded with the error
+			return
+		}
+		defer conn.Close()
+
+		// the request context is not cancelled when a hijacked connection is closed
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		latest := make(chan *socketLightDTO, 1)
+		go readLightPositions(cancel, conn, latest)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case position := <-latest:
+				moveCtx, span := tracing.Start(ctx, "websocket.move_light", "scene", id,
+					"request_id", logging.RequestID(ctx), "release", position.Release)
+				move := backend.PreviewSceneLight
+				if position.Release {
+					move = backend.MoveSceneLight
+				}
+				moved, err := move(moveCtx, cc, id, &vector.Vector{X: position.X, Y: position.Y})
+				span.SetError(err)
+				span.End()
+
+				switch err {
+				case nil:
+					err = conn.WriteJSON(newLightDTO(moved))
+				case backend.ErrContextCancelled, backend.ErrContextExpired:
+					return
+				case backend.ErrSuperseded:
+					// another client has moved the light meanwhile
+					continue
+				default:
+					err = conn.WriteJSON(&socketErrorDTO{Error: err.Error()})
+				}
+
+				if err != nil {
+					logging.FromContext(ctx).Warn("websocket closed", "error", err)
+					return
+				}
+			}
+		}
+	}
+}
+
+// readLightPositions reads the light positions from the connection until it is closed,
+// replacing the position which is not picked yet with the newer one, which is stored
+// instead of the replaced one if that was the released one
+func readLightPositions(cancel context.CancelFunc, conn *websocket.Conn, latest chan *socketLightDTO) {
+	defer cancel()
+
+	for {
+		position := new(socketLightDTO)
+		if err := conn.ReadJSON(position); err != nil {
+			return
+		}
+
+		select {
+		case replaced := <-latest:
+			position.Release = position.Release || replaced.Release
+		default:
+		}
+
+		latest <- position
+	}
+}
+
+// socketLightDTO is a light position streamed by the client,
+// Release marks the last position of the drag
+type socketLightDTO struct {
+	X, Y    float64
+	Release bool
+}
+
+// lightDTO is the part of the scene which changes when the light is moved
+type lightDTO struct {
+	Light     *xy
+	LitArea   float64
+	Triangles [][]*xy
+	Arcs      []*arcDTO `json:",omitempty"`
+}
+
+func newLightDTO(scene *backend.Scene) *lightDTO {
+	triangles, arcs := newTrianglesDTO(scene.Triangles)
+
+	return &lightDTO{
+		Light:     &xy{X: scene.Light.X, Y: scene.Light.Y},
+		LitArea:   scene.LitArea,
+		Triangles: triangles,
+		Arcs:      arcs,
+	}
+}
+
+type socketErrorDTO struct {
+	Error string
+}
@@ -0,0 +1,162 @@
+package api_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+	"github.com/iliyanmotovski/raytracer/backend/persistent"
+	"github.com/iliyanmotovski/raytracer/backend/server/http/api"
+	"github.com/iliyanmotovski/raytracer/backend/vector"
+)
+
+func TestSceneSocket(t *testing.T) {
+	cc := make(chan *backend.ConfigChan)
+	release := make(chan struct{})
+	lights := make(chan *vector.Vector, 10)
+
+	// the first light move is held until released, the positions
+	// sent meanwhile must be dropped in favour of the latest one
+	go func() {
+		for job := range cc {
+			assert.True(t, job.LightOnly)
+			assert.True(t, job.Preview)
+			assert.Equal(t, "second", job.Config.ID)
+
+			if len(lights) == 0 {
+				<-release
+			}
+
+			lights <- job.Config.Light
+
+			scene := newVersionScene(float64(len(lights)), job.Config.Light.X, job.Config.Light.Y)
+			job.Response <- &backend.SceneReloadResponse{Scene: scene}
+		}
+	}()
+	defer close(cc)
+
+	router := mux.NewRouter()
+	router.Handle("/api/v1/scenes/{scene}/ws", api.SceneSocket(cc))
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/api/v1/scenes/second/ws", nil)
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	for x := 100; x <= 400; x += 100 {
+		assert.Nil(t, conn.WriteJSON(map[string]int{"x": x, "y": 300}))
+	}
+
+	// gives the server time to read all positions before the first one is processed
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+
+	got := &struct {
+		Light     *vector.Vector
+		LitArea   float64
+		Triangles [][]*vector.Vector
+	}{}
+
+	assert.Nil(t, conn.ReadJSON(got))
+	assert.Equal(t, &vector.Vector{X: 100, Y: 300}, got.Light)
+	assert.Equal(t, float64(1), got.LitArea)
+
+	assert.Nil(t, conn.ReadJSON(got))
+	assert.Equal(t, &vector.Vector{X: 400, Y: 300}, got.Light)
+	assert.Equal(t, float64(2), got.LitArea)
+	assert.Empty(t, got.Triangles)
+
+	assert.Len(t, lights, 2)
+}
+
+func TestSceneSocketStoresTheReleasedPosition(t *testing.T) {
+	sceneRepo := persistent.NewInMemorySceneRepository()
+
+	cc := make(chan *backend.ConfigChan)
+	backend.NewSceneReloadDaemon(sceneRepo, cc).Start(1)
+	defer close(cc)
+
+	config := newVersionScene(0, 250, 300).Config()
+	config.ID = "second"
+	config.Ellipses = backend.Ellipses{backend.NewCircle(400, 300, 50)}
+
+	_, err := backend.ReloadScene(context.Background(), cc, config)
+	assert.Nil(t, err)
+
+	router := mux.NewRouter()
+	router.Handle("/api/v1/scenes/{scene}/ws", api.SceneSocket(cc))
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/api/v1/scenes/second/ws", nil)
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	got := &struct {
+		Light *vector.Vector
+		Arcs  []*struct {
+			Triangle         int
+			Center           *vector.Vector
+			RadiusX, RadiusY float64
+		}
+	}{}
+
+	// the light is dragged past the circle, which casts its shadow with an arc
+	assert.Nil(t, conn.WriteJSON(map[string]int{"x": 200, "y": 300}))
+	assert.Nil(t, conn.ReadJSON(got))
+	assert.Equal(t, &vector.Vector{X: 200, Y: 300}, got.Light)
+	if assert.NotEmpty(t, got.Arcs) {
+		assert.Equal(t, &vector.Vector{X: 400, Y: 300}, got.Arcs[0].Center)
+		assert.Equal(t, float64(50), got.Arcs[0].RadiusX)
+	}
+
+	versions, err := sceneRepo.ListVersions(context.Background(), "second")
+	assert.Nil(t, err)
+	assert.Len(t, versions, 1)
+
+	assert.Nil(t, conn.WriteJSON(map[string]interface{}{"x": 150, "y": 300, "release": true}))
+	assert.Nil(t, conn.ReadJSON(got))
+	assert.Equal(t, &vector.Vector{X: 150, Y: 300}, got.Light)
+
+	versions, err = sceneRepo.ListVersions(context.Background(), "second")
+	assert.Nil(t, err)
+	assert.Len(t, versions, 2)
+
+	stored, err := sceneRepo.Get(context.Background(), "second")
+	assert.Nil(t, err)
+	assert.Equal(t, &vector.Vector{X: 150, Y: 300}, stored.Light)
+}
+
+func TestSceneSocketWithError(t *testing.T) {
+	cc := make(chan *backend.ConfigChan)
+
+	go func() {
+		for job := range cc {
+			job.Response <- &backend.SceneReloadResponse{Scene: &backend.Scene{}, Err: backend.ErrLightOutside}
+		}
+	}()
+	defer close(cc)
+
+	server := httptest.NewServer(api.SceneSocket(cc))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	assert.Nil(t, conn.WriteJSON(map[string]int{"x": 900, "y": 300}))
+
+	got := &struct{ Error string }{}
+	assert.Nil(t, conn.ReadJSON(got))
+	assert.Equal(t, backend.ErrLightOutside.Error(), got.Error)
+}
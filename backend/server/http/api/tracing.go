@@ -0,0 +1,50 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/iliyanmotovski/raytracer/backend/logging"
+	"github.com/iliyanmotovski/raytracer/backend/tracing"
+)
+
+// TraceIDHeader is the header with the ID of the trace of the request, which is returned in the response
+const TraceIDHeader = "X-Trace-ID"
+
+// RequestTracing is a mux middleware which starts the root span of the trace of every request,
+// named after its method and route, so the spans of processing its config, e.g. the ones of the
+// daemon, are its children. It must be used after RequestLogging to record the request ID. The
+// websockets live as long as their clients, so instead of one trace for the whole connection
+// every light move gets its own trace
+func RequestTracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, span := tracing.Start(r.Context(), r.Method+" "+routeTemplate(r), "http.method", r.Method,
+			"http.target", r.URL.Path, "request_id", logging.RequestID(r.Context()))
+		defer span.End()
+
+		if span != nil {
+			w.Header().Set(TraceIDHeader, span.TraceID())
+		}
+
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		status := recorder.status()
+		span.SetAttributes("http.status_code", status)
+		if status >= http.StatusInternalServerError {
+			span.SetError(errorStatus(status))
+		}
+	})
+}
+
+// errorStatus is the error of a span of a request which has failed with the status
+type errorStatus int
+
+func (e errorStatus) Error() string {
+	return http.StatusText(int(e))
+}
@@ -0,0 +1,63 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iliyanmotovski/raytracer/backend/server/http/api"
+	"github.com/iliyanmotovski/raytracer/backend/tracing"
+)
+
+func TestRequestTracing(t *testing.T) {
+	recorder := tracing.NewRecorder(10)
+	tracing.SetDefault(tracing.NewTracer(nil, recorder))
+	defer tracing.SetDefault(nil)
+
+	router := mux.NewRouter()
+	router.Use(api.RequestLogging, api.RequestTracing)
+	router.HandleFunc("/api/v1/scenes/{scene:[a-z]+}", func(w http.ResponseWriter, r *http.Request) {
+		_, span := tracing.Start(r.Context(), "scene_repository.get")
+		span.End()
+
+		if mux.Vars(r)["scene"] == "broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+
+	r := newGetRequest("/api/v1/scenes/default")
+	r.Header.Set(api.RequestIDHeader, "abc123")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	traces := recorder.Traces()
+	if assert.Len(t, traces, 1) && assert.Len(t, traces[0].Spans, 2) {
+		root := traces[0].Root()
+		assert.Equal(t, traces[0].ID, w.Header().Get(api.TraceIDHeader))
+		assert.Equal(t, "GET /api/v1/scenes/{scene}", root.Name)
+		assert.Equal(t, []tracing.Attribute{
+			{Key: "http.method", Value: "GET"},
+			{Key: "http.target", Value: "/api/v1/scenes/default"},
+			{Key: "request_id", Value: "abc123"},
+			{Key: "http.status_code", Value: http.StatusOK},
+		}, root.Attributes)
+		assert.Equal(t, "", root.Error)
+		assert.Equal(t, root.SpanID, traces[0].Spans[1].ParentID)
+	}
+
+	// the failed requests are marked as failed
+	router.ServeHTTP(httptest.NewRecorder(), newGetRequest("/api/v1/scenes/broken"))
+	assert.Equal(t, "Internal Server Error", recorder.Traces()[0].Root().Error)
+
+	// a websocket is not traced as a whole, so the trace of its child span is on its own
+	r = newGetRequest("/api/v1/scenes/default")
+	r.Header.Set("Upgrade", "websocket")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	assert.Equal(t, "", w.Header().Get(api.TraceIDHeader))
+	assert.Equal(t, "scene_repository.get", recorder.Traces()[0].Root().Name)
+}
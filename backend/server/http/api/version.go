@@ -0,0 +1,218 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+)
+
+// ListSceneVersions is an http handler which returns all versions of the
+// scene with the ID from the {scene} path parameter stored in the persistence
+func ListSceneVersions(sceneRepo backend.SceneRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := sceneID(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(err)
+			return
+		}
+
+		versions, err := sceneRepo.ListVersions(r.Context(), id)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(err)
+			return
+		}
+
+		resp := make([]*versionDTO, len(versions))
+		for i, version := range versions {
+			resp[i] = &versionDTO{ID: version.ID, CreatedAt: version.CreatedAt}
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// GetSceneVersion is an http handler which returns the
+// scene stored with the version from the {version} path parameter
+func GetSceneVersion(sceneRepo backend.SceneRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := sceneID(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(err)
+			return
+		}
+
+		scene, ok := getSceneVersion(w, r, sceneRepo, id, mux.Vars(r)["version"])
+		if !ok {
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(newSceneDTO(scene))
+	}
+}
+
+// RestoreSceneVersion is an http handler which sends the config of the scene stored with
+// the version from the {version} path parameter to be processed again, so it becomes the latest
+func RestoreSceneVersion(sceneRepo backend.SceneRepository, cc chan *backend.ConfigChan) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := sceneID(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(err)
+			return
+		}
+
+		scene, ok := getSceneVersion(w, r, sceneRepo, id, mux.Vars(r)["version"])
+		if !ok {
+			return
+		}
+
+		config := scene.Config()
+		config.ID = id
+
+		// send the config to the daemon to be processed and new scene generated
+		restored, err := backend.ReloadScene(r.Context(), cc, config)
+		if err != nil {
+			w.WriteHeader(reloadErrorStatus(err))
+			json.NewEncoder(w).Encode(err)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(newSceneDTO(restored))
+	}
+}
+
+// DiffSceneVersions is an http handler which compares the scene stored with the version
+// from the {version} path parameter with the one from the "to" query parameter, or with the
+// latest scene if it is not provided
+func DiffSceneVersions(sceneRepo backend.SceneRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := sceneID(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(err)
+			return
+		}
+
+		from, ok := getSceneVersion(w, r, sceneRepo, id, mux.Vars(r)["version"])
+		if !ok {
+			return
+		}
+
+		to := from
+		if version := r.URL.Query().Get("to"); version != "" {
+			if to, ok = getSceneVersion(w, r, sceneRepo, id, version); !ok {
+				return
+			}
+		} else {
+			latest, err := sceneRepo.Get(r.Context(), id)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(err)
+				return
+			}
+			to = latest
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(newSceneDiffDTO(from.Diff(to)))
+	}
+}
+
+// getSceneVersion gets the given version of the scene from the persistence and
+// writes the error response if the version is invalid or there is no such
+func getSceneVersion(w http.ResponseWriter, r *http.Request, sceneRepo backend.SceneRepository, id, version string) (*backend.Scene, bool) {
+	v, err := strconv.Atoi(version)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err)
+		return nil, false
+	}
+
+	scene, err := sceneRepo.GetVersion(r.Context(), id, v)
+	if err == backend.ErrVersionNotFound {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(err)
+		return nil, false
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(err)
+		return nil, false
+	}
+
+	return scene, true
+}
+
+type versionDTO struct {
+	ID        int
+	CreatedAt time.Time
+}
+
+type sceneDiffDTO struct {
+	Added, Removed [][]*vertexDTO
+	Moved          []*polygonMoveDTO
+	Light          *lightDiffDTO
+	LitArea        *litAreaDiffDTO
+}
+
+type polygonMoveDTO struct {
+	From, To []*vertexDTO
+	Offset   *xy
+}
+
+type lightDiffDTO struct {
+	From, To, Offset *xy
+}
+
+type litAreaDiffDTO struct {
+	From, To, Change float64
+}
+
+func newSceneDiffDTO(diff *backend.SceneDiff) *sceneDiffDTO {
+	offset := diff.LightOffset()
+
+	result := &sceneDiffDTO{
+		Added:   make([][]*vertexDTO, len(diff.Added)),
+		Removed: make([][]*vertexDTO, len(diff.Removed)),
+		Moved:   make([]*polygonMoveDTO, len(diff.Moved)),
+		Light: &lightDiffDTO{
+			From:   &xy{X: diff.LightFrom.X, Y: diff.LightFrom.Y},
+			To:     &xy{X: diff.LightTo.X, Y: diff.LightTo.Y},
+			Offset: &xy{X: offset.X, Y: offset.Y},
+		},
+		LitArea: &litAreaDiffDTO{From: diff.LitAreaFrom, To: diff.LitAreaTo, Change: diff.LitAreaChange()},
+	}
+
+	for i, polygon := range diff.Added {
+		result.Added[i] = newPolygonDTO(polygon)
+	}
+
+	for i, polygon := range diff.Removed {
+		result.Removed[i] = newPolygonDTO(polygon)
+	}
+
+	for i, move := range diff.Moved {
+		result.Moved[i] = &polygonMoveDTO{
+			From:   newPolygonDTO(move.From),
+			To:     newPolygonDTO(move.To),
+			Offset: &xy{X: move.Offset.X, Y: move.Offset.Y},
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,171 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+	"github.com/iliyanmotovski/raytracer/backend/server/http/api"
+	"github.com/iliyanmotovski/raytracer/backend/vector"
+)
+
+func TestListSceneVersions(t *testing.T) {
+	createdAt := time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	sceneRepo := new(backend.FakeSceneRepository)
+	sceneRepo.On("ListVersions", backend.DefaultSceneID).Return(backend.Versions{
+		{ID: 1, CreatedAt: createdAt},
+		{ID: 2, CreatedAt: createdAt.Add(time.Minute)},
+	}, nil)
+
+	r, _ := http.NewRequest("GET", "/api/v1/scene/versions", nil)
+	w := httptest.NewRecorder()
+
+	api.ListSceneVersions(sceneRepo).ServeHTTP(w, r)
+
+	wantResponse := `[{"ID":1,"CreatedAt":"2020-05-01T10:00:00Z"},{"ID":2,"CreatedAt":"2020-05-01T10:01:00Z"}]`
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, wantResponse, strings.TrimSpace(w.Body.String()))
+
+	sceneRepo.AssertExpectations(t)
+}
+
+func TestGetSceneVersion(t *testing.T) {
+	sceneRepo := new(backend.FakeSceneRepository)
+	sceneRepo.On("GetVersion", backend.DefaultSceneID, 1).Return(newVersionScene(60, 250, 300), nil)
+
+	r, _ := http.NewRequest("GET", "/api/v1/scene/versions/1", nil)
+	r = mux.SetURLVars(r, map[string]string{"version": "1"})
+	w := httptest.NewRecorder()
+
+	api.GetSceneVersion(sceneRepo).ServeHTTP(w, r)
+
+	wantResponse := `{"Width":800,"Height":500,"LitArea":60,"Light":{"X":250,"Y":300},"Polygons":[[{"X":600,"Y":200},{"X":646,"Y":133},{"X":646,"Y":261}]],"Triangles":[]}`
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, wantResponse, strings.TrimSpace(w.Body.String()))
+
+	sceneRepo.AssertExpectations(t)
+}
+
+func TestGetSceneVersionNotFound(t *testing.T) {
+	sceneRepo := new(backend.FakeSceneRepository)
+	sceneRepo.On("GetVersion", backend.DefaultSceneID, 5).Return((*backend.Scene)(nil), backend.ErrVersionNotFound)
+
+	r, _ := http.NewRequest("GET", "/api/v1/scene/versions/5", nil)
+	r = mux.SetURLVars(r, map[string]string{"version": "5"})
+	w := httptest.NewRecorder()
+
+	api.GetSceneVersion(sceneRepo).ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	sceneRepo.AssertExpectations(t)
+}
+
+func TestRestoreSceneVersion(t *testing.T) {
+	scene := newVersionScene(60, 250, 300)
+
+	sceneRepo := new(backend.FakeSceneRepository)
+	sceneRepo.On("GetVersion", "second", 1).Return(scene, nil)
+
+	cc := make(chan *backend.ConfigChan)
+
+	r, _ := http.NewRequest("POST", "/api/v1/scenes/second/versions/1/restore", nil)
+	r = mux.SetURLVars(r, map[string]string{"scene": "second", "version": "1"})
+	w := httptest.NewRecorder()
+
+	go func() {
+		config := scene.Config()
+		config.ID = "second"
+
+		gotConfig := <-cc
+		assert.Equal(t, r.Context(), gotConfig.Ctx)
+		assert.Equal(t, config, gotConfig.Config)
+
+		gotConfig.Response <- &backend.SceneReloadResponse{Err: nil, Scene: scene}
+	}()
+
+	api.RestoreSceneVersion(sceneRepo, cc).ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	sceneRepo.AssertExpectations(t)
+}
+
+func TestDiffSceneVersions(t *testing.T) {
+	from := newVersionScene(60, 250, 300)
+	to := newVersionScene(55.5, 200, 300)
+	to.Polygons = backend.Polygons{
+		{VerticesCount: 3, Loop: vector.Loop{{X: 610, Y: 200}, {X: 656, Y: 133}, {X: 656, Y: 261}}},
+	}
+
+	sceneRepo := new(backend.FakeSceneRepository)
+	sceneRepo.On("GetVersion", backend.DefaultSceneID, 1).Return(from, nil)
+	sceneRepo.On("Get", backend.DefaultSceneID).Return(to, nil)
+
+	r, _ := http.NewRequest("GET", "/api/v1/scene/versions/1/diff", nil)
+	r = mux.SetURLVars(r, map[string]string{"version": "1"})
+	w := httptest.NewRecorder()
+
+	api.DiffSceneVersions(sceneRepo).ServeHTTP(w, r)
+
+	got := &struct {
+		Added, Removed []interface{}
+		Moved          []*struct{ Offset *vector.Vector }
+		Light          *struct{ From, To, Offset *vector.Vector }
+		LitArea        *struct{ From, To, Change float64 }
+	}{}
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), got))
+
+	assert.Empty(t, got.Added)
+	assert.Empty(t, got.Removed)
+	assert.Len(t, got.Moved, 1)
+	assert.Equal(t, &vector.Vector{X: 10, Y: 0}, got.Moved[0].Offset)
+	assert.Equal(t, &vector.Vector{X: -50, Y: 0}, got.Light.Offset)
+	assert.Equal(t, -4.5, got.LitArea.Change)
+
+	sceneRepo.AssertExpectations(t)
+}
+
+func TestDiffSceneVersionsWithInvalidVersion(t *testing.T) {
+	sceneRepo := new(backend.FakeSceneRepository)
+
+	r, _ := http.NewRequest("GET", "/api/v1/scene/versions/1/diff?to=abc", nil)
+	r = mux.SetURLVars(r, map[string]string{"version": "1"})
+	w := httptest.NewRecorder()
+
+	sceneRepo.On("GetVersion", backend.DefaultSceneID, 1).Return(newVersionScene(60, 250, 300), nil)
+
+	api.DiffSceneVersions(sceneRepo).ServeHTTP(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	sceneRepo.AssertExpectations(t)
+}
+
+func newVersionScene(litArea, lightX, lightY float64) *backend.Scene {
+	return &backend.Scene{
+		Width:   800,
+		Height:  500,
+		LitArea: litArea,
+		Light:   &vector.Vector{X: lightX, Y: lightY},
+		Polygons: backend.Polygons{
+			{
+				VerticesCount: 3,
+				Loop: vector.Loop{
+					{X: 600, Y: 200},
+					{X: 646, Y: 133},
+					{X: 646, Y: 261},
+				},
+			},
+		},
+	}
+}
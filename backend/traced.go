@@ -0,0 +1,128 @@
+package backend
+
+import (
+	"context"
+
+	"github.com/iliyanmotovski/raytracer/backend/tracing"
+)
+
+// tracedSceneRepository is a SceneRepository which records a span for every call of the wrapped one
+type tracedSceneRepository struct {
+	repo SceneRepository
+}
+
+// NewTracedSceneRepository wraps the repository, so the time spent persisting
+// and reading the scenes is part of the traces of the calls
+func NewTracedSceneRepository(repo SceneRepository) SceneRepository {
+	return &tracedSceneRepository{repo: repo}
+}
+
+// Get gets the scene from the wrapped repository
+func (r *tracedSceneRepository) Get(ctx context.Context, sceneID string) (*Scene, error) {
+	ctx, span := tracing.Start(ctx, "scene_repository.get", "scene", sceneID)
+	defer span.End()
+
+	scene, err := r.repo.Get(ctx, sceneID)
+	span.SetError(err)
+
+	return scene, err
+}
+
+// Upsert stores the scene in the wrapped repository
+func (r *tracedSceneRepository) Upsert(ctx context.Context, scene *Scene) (*Scene, error) {
+	ctx, span := tracing.Start(ctx, "scene_repository.upsert", "scene", scene.ID, "triangles", len(scene.Triangles))
+	defer span.End()
+
+	persisted, err := r.repo.Upsert(ctx, scene)
+	span.SetError(err)
+
+	return persisted, err
+}
+
+// ListVersions lists the versions of the scene in the wrapped repository
+func (r *tracedSceneRepository) ListVersions(ctx context.Context, sceneID string) (Versions, error) {
+	ctx, span := tracing.Start(ctx, "scene_repository.list_versions", "scene", sceneID)
+	defer span.End()
+
+	versions, err := r.repo.ListVersions(ctx, sceneID)
+	span.SetError(err)
+
+	return versions, err
+}
+
+// LatestVersion gets the version of the latest scene from the wrapped repository
+func (r *tracedSceneRepository) LatestVersion(ctx context.Context, sceneID string) (*Version, error) {
+	ctx, span := tracing.Start(ctx, "scene_repository.latest_version", "scene", sceneID)
+	defer span.End()
+
+	version, err := r.repo.LatestVersion(ctx, sceneID)
+	span.SetError(err)
+
+	return version, err
+}
+
+// GetVersion gets the version of the scene from the wrapped repository
+func (r *tracedSceneRepository) GetVersion(ctx context.Context, sceneID string, version int) (*Scene, error) {
+	ctx, span := tracing.Start(ctx, "scene_repository.get_version", "scene", sceneID, "version", version)
+	defer span.End()
+
+	scene, err := r.repo.GetVersion(ctx, sceneID, version)
+	span.SetError(err)
+
+	return scene, err
+}
+
+// tracedConfigRepository is a ConfigRepository which records a span for every call of the wrapped one
+type tracedConfigRepository struct {
+	repo ConfigRepository
+}
+
+// NewTracedConfigRepository wraps the repository, so the time spent persisting
+// and reading the configs is part of the traces of the calls
+func NewTracedConfigRepository(repo ConfigRepository) ConfigRepository {
+	return &tracedConfigRepository{repo: repo}
+}
+
+// Get gets the config from the wrapped repository
+func (r *tracedConfigRepository) Get(ctx context.Context, sceneID string) (*Config, error) {
+	ctx, span := tracing.Start(ctx, "config_repository.get", "scene", sceneID)
+	defer span.End()
+
+	config, err := r.repo.Get(ctx, sceneID)
+	span.SetError(err)
+
+	return config, err
+}
+
+// Upsert stores the config in the wrapped repository
+func (r *tracedConfigRepository) Upsert(ctx context.Context, config *Config) (*Config, error) {
+	ctx, span := tracing.Start(ctx, "config_repository.upsert", "scene", config.ID)
+	defer span.End()
+
+	persisted, err := r.repo.Upsert(ctx, config)
+	span.SetError(err)
+
+	return persisted, err
+}
+
+// ListVersions lists the versions of the config in the wrapped repository
+func (r *tracedConfigRepository) ListVersions(ctx context.Context, sceneID string) (Versions, error) {
+	ctx, span := tracing.Start(ctx, "config_repository.list_versions", "scene", sceneID)
+	defer span.End()
+
+	versions, err := r.repo.ListVersions(ctx, sceneID)
+	span.SetError(err)
+
+	return versions, err
+}
+
+// GetVersion gets the version of the config from the wrapped repository
+func (r *tracedConfigRepository) GetVersion(ctx context.Context, sceneID string, version int) (*Config, error) {
+	ctx, span := tracing.Start(ctx, "config_repository.get_version", "scene", sceneID, "version", version)
+	defer span.End()
+
+	config, err := r.repo.GetVersion(ctx, sceneID, version)
+	span.SetError(err)
+
+	return config, err
+}
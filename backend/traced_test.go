@@ -0,0 +1,84 @@
+package backend_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+	"github.com/iliyanmotovski/raytracer/backend/persistent"
+	"github.com/iliyanmotovski/raytracer/backend/tracing"
+)
+
+func TestSceneReloadDaemonTracesJob(t *testing.T) {
+	recorder := tracing.NewRecorder(1)
+	tracing.SetDefault(tracing.NewTracer(nil, recorder))
+	defer tracing.SetDefault(nil)
+
+	cc := make(chan *backend.ConfigChan)
+	sceneRepo := backend.NewTracedSceneRepository(persistent.NewInMemorySceneRepository())
+	backend.NewSceneReloadDaemon(sceneRepo, cc).Start(1)
+	defer close(cc)
+
+	ctx, span := tracing.Start(context.Background(), "POST /api/v1/scene/config")
+	_, err := backend.ReloadScene(ctx, cc, newLargeConfig(2))
+	assert.Nil(t, err)
+	span.End()
+
+	traces := recorder.Traces()
+	if !assert.Len(t, traces, 1) {
+		return
+	}
+
+	names := map[string]*tracing.SpanData{}
+	for _, span := range traces[0].Spans {
+		names[span.Name] = span
+	}
+
+	// every step of the pipeline is a child of the previous one or of the scene processing
+	parents := map[string]string{
+		"daemon.job":              "POST /api/v1/scene/config",
+		"scene.load":              "daemon.job",
+		"scene.process":           "scene.load",
+		"scene.boundaries":        "scene.process",
+		"scene.validate":          "scene.process",
+		"rays.generate":           "scene.process",
+		"rays.sort":               "scene.process",
+		"rays.cast":               "scene.process",
+		"triangles.fan":           "scene.process",
+		"scene_repository.upsert": "scene.load",
+	}
+	assert.Len(t, traces[0].Spans, len(parents)+1)
+
+	for name, parent := range parents {
+		if assert.Contains(t, names, name) && assert.Contains(t, names, parent) {
+			assert.Equal(t, names[parent].SpanID, names[name].ParentID, name)
+		}
+	}
+
+	assert.Equal(t, []tracing.Attribute{
+		{Key: "kind", Value: backend.ConfigJob}, {Key: "source", Value: backend.APISource}, {Key: "scene", Value: backend.DefaultSceneID},
+	}, names["daemon.job"].Attributes)
+}
+
+func TestTracedConfigRepository(t *testing.T) {
+	recorder := tracing.NewRecorder(1)
+	tracing.SetDefault(tracing.NewTracer(nil, recorder))
+	defer tracing.SetDefault(nil)
+
+	configRepo := backend.NewTracedConfigRepository(persistent.NewInMemoryConfigRepository())
+
+	ctx, span := tracing.Start(context.Background(), "config.reload")
+	_, err := configRepo.GetVersion(ctx, backend.DefaultSceneID, 1)
+	assert.Equal(t, backend.ErrVersionNotFound, err)
+	span.End()
+
+	traces := recorder.Traces()
+	if assert.Len(t, traces, 1) && assert.Len(t, traces[0].Spans, 2) {
+		get := traces[0].Spans[1]
+		assert.Equal(t, "config_repository.get_version", get.Name)
+		assert.Equal(t, []tracing.Attribute{{Key: "scene", Value: backend.DefaultSceneID}, {Key: "version", Value: 1}}, get.Attributes)
+		assert.Equal(t, backend.ErrVersionNotFound.Error(), get.Error)
+	}
+}
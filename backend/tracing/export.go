@@ -0,0 +1,326 @@
+package tracing
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JSONExporter writes every trace as a single line of JSON, e.g. to stdout
+type JSONExporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONExporter creates a JSONExporter which writes to w
+func NewJSONExporter(w io.Writer) *JSONExporter {
+	return &JSONExporter{w: w}
+}
+
+// Export writes the trace
+func (e *JSONExporter) Export(trace *Trace) error {
+	b, err := json.Marshal(newTraceDTO(trace))
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	_, err = e.w.Write(append(b, '\n'))
+	return err
+}
+
+type traceDTO struct {
+	ID    string
+	Spans []*spanDTO
+}
+
+type spanDTO struct {
+	SpanID, ParentID, Name string
+	Start                  time.Time
+	Duration               string
+	Attributes             map[string]interface{} `json:",omitempty"`
+	Error                  string                 `json:",omitempty"`
+}
+
+func newTraceDTO(trace *Trace) *traceDTO {
+	dto := &traceDTO{ID: trace.ID, Spans: make([]*spanDTO, len(trace.Spans))}
+
+	for i, span := range trace.Spans {
+		dto.Spans[i] = &spanDTO{
+			SpanID:   span.SpanID,
+			ParentID: span.ParentID,
+			Name:     span.Name,
+			Start:    span.Start,
+			Duration: span.Duration().String(),
+			Error:    span.Error,
+		}
+
+		if len(span.Attributes) > 0 {
+			dto.Spans[i].Attributes = map[string]interface{}{}
+			for _, attribute := range span.Attributes {
+				dto.Spans[i].Attributes[attribute.Key] = attribute.Value
+			}
+		}
+	}
+
+	return dto
+}
+
+// OTLPFileExporter appends the traces to a file in the OTLP JSON format, every line is an
+// ExportTraceServiceRequest, the same as written by the file exporter of the OpenTelemetry
+// collector, so the file can be replayed into a collector or loaded by the tools which read it
+type OTLPFileExporter struct {
+	service string
+
+	mu   sync.Mutex
+	file *os.File
+	w    *bufio.Writer
+}
+
+// NewOTLPFileExporter creates an OTLPFileExporter which appends to the file at the path,
+// the spans are reported as coming from the service, it must be closed to flush the file
+func NewOTLPFileExporter(path, service string) (*OTLPFileExporter, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OTLPFileExporter{service: service, file: file, w: bufio.NewWriter(file)}, nil
+}
+
+// Export appends the trace to the file
+func (e *OTLPFileExporter) Export(trace *Trace) error {
+	b, err := json.Marshal(newOTLPRequest(e.service, trace))
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, err := e.w.Write(append(b, '\n')); err != nil {
+		return err
+	}
+
+	return e.w.Flush()
+}
+
+// Close flushes and closes the file
+func (e *OTLPFileExporter) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.w.Flush(); err != nil {
+		e.file.Close()
+		return err
+	}
+
+	return e.file.Close()
+}
+
+// the OTLP JSON messages use lower camel case field names and the 64-bit integers are strings
+
+type otlpRequest struct {
+	ResourceSpans []*otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource      `json:"resource"`
+	ScopeSpans []*otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []*otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope   `json:"scope"`
+	Spans []*otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string           `json:"traceId"`
+	SpanID            string           `json:"spanId"`
+	ParentSpanID      string           `json:"parentSpanId,omitempty"`
+	Name              string           `json:"name"`
+	Kind              int              `json:"kind"`
+	StartTimeUnixNano string           `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string           `json:"endTimeUnixNano"`
+	Attributes        []*otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus       `json:"status"`
+}
+
+type otlpAttribute struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+const (
+	// otlpKindInternal is SPAN_KIND_INTERNAL, all spans are reported as internal ones
+	otlpKindInternal = 1
+	// otlpStatusError is STATUS_CODE_ERROR
+	otlpStatusError = 2
+)
+
+func newOTLPRequest(service string, trace *Trace) *otlpRequest {
+	spans := make([]*otlpSpan, len(trace.Spans))
+	for i, span := range trace.Spans {
+		spans[i] = &otlpSpan{
+			TraceID:           span.TraceID,
+			SpanID:            span.SpanID,
+			ParentSpanID:      span.ParentID,
+			Name:              span.Name,
+			Kind:              otlpKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+		}
+
+		for _, attribute := range span.Attributes {
+			spans[i].Attributes = append(spans[i].Attributes, newOTLPAttribute(attribute.Key, attribute.Value))
+		}
+
+		if span.Error != "" {
+			spans[i].Status = otlpStatus{Code: otlpStatusError, Message: span.Error}
+		}
+	}
+
+	return &otlpRequest{ResourceSpans: []*otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: []*otlpAttribute{newOTLPAttribute("service.name", service)}},
+		ScopeSpans: []*otlpScopeSpans{{Scope: otlpScope{Name: service}, Spans: spans}},
+	}}}
+}
+
+func newOTLPAttribute(key string, value interface{}) *otlpAttribute {
+	var v map[string]interface{}
+
+	switch value := value.(type) {
+	case string:
+		v = map[string]interface{}{"stringValue": value}
+	case bool:
+		v = map[string]interface{}{"boolValue": value}
+	case int:
+		v = map[string]interface{}{"intValue": strconv.Itoa(value)}
+	case int64:
+		v = map[string]interface{}{"intValue": strconv.FormatInt(value, 10)}
+	case float64:
+		v = map[string]interface{}{"doubleValue": value}
+	default:
+		v = map[string]interface{}{"stringValue": fmt.Sprint(value)}
+	}
+
+	return &otlpAttribute{Key: key, Value: v}
+}
+
+// Recorder keeps the latest traces in memory and serves them, e.g. on a debug endpoint
+type Recorder struct {
+	limit int
+
+	mu sync.Mutex
+	// traces holds the kept traces from the oldest one
+	traces []*Trace
+}
+
+// NewRecorder creates a Recorder which keeps the last limit traces
+func NewRecorder(limit int) *Recorder {
+	return &Recorder{limit: limit}
+}
+
+// Export keeps the trace, dropping the oldest one when there are already as many as the limit
+func (r *Recorder) Export(trace *Trace) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.traces = append(r.traces, trace)
+	if len(r.traces) > r.limit {
+		r.traces = append([]*Trace(nil), r.traces[len(r.traces)-r.limit:]...)
+	}
+
+	return nil
+}
+
+// Traces returns the kept traces from the latest one
+func (r *Recorder) Traces() []*Trace {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	traces := make([]*Trace, len(r.traces))
+	for i, trace := range r.traces {
+		traces[len(traces)-1-i] = trace
+	}
+
+	return traces
+}
+
+// ServeHTTP serves the kept traces from the latest one as JSON, or as
+// indented trees of their spans with the format=text query parameter
+func (r *Recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	traces := r.Traces()
+
+	if req.URL.Query().Get("format") == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		for _, trace := range traces {
+			writeTree(w, trace)
+		}
+		return
+	}
+
+	dtos := make([]*traceDTO, len(traces))
+	for i, trace := range traces {
+		dtos[i] = newTraceDTO(trace)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dtos)
+}
+
+// writeTree writes the spans of the trace indented under their parents
+func writeTree(w io.Writer, trace *Trace) {
+	children := map[string][]*SpanData{}
+	for _, span := range trace.Spans[1:] {
+		children[span.ParentID] = append(children[span.ParentID], span)
+	}
+
+	fmt.Fprintf(w, "trace %s at %s\n", trace.ID, trace.Root().Start.UTC().Format(time.RFC3339Nano))
+
+	var write func(span *SpanData, depth int)
+	write = func(span *SpanData, depth int) {
+		line := []string{strings.Repeat("  ", depth) + span.Name, span.Duration().String()}
+		for _, attribute := range span.Attributes {
+			line = append(line, fmt.Sprintf("%s=%v", attribute.Key, attribute.Value))
+		}
+
+		if span.Error != "" {
+			line = append(line, strconv.Quote("error: "+span.Error))
+		}
+
+		fmt.Fprintln(w, strings.Join(line, " "))
+
+		spans := children[span.SpanID]
+		sort.SliceStable(spans, func(i, j int) bool { return spans[i].Start.Before(spans[j].Start) })
+		for _, child := range spans {
+			write(child, depth+1)
+		}
+	}
+
+	write(trace.Root(), 1)
+	fmt.Fprintln(w)
+}
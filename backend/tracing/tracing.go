@@ -0,0 +1,265 @@
+// Package tracing records how long the steps of processing a scene take as spans carried in
+// the context. The spans of one trace are exported together once its root span ends. Tracing
+// is disabled until a Tracer is set with SetDefault, then Start returns a nil *Span, whose
+// methods do nothing, so the instrumented code does not need to check for it
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Attribute is a key value pair describing a span, e.g. the number of the rays it has cast
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// SpanData is a finished span
+type SpanData struct {
+	TraceID  string
+	SpanID   string
+	ParentID string
+	Name     string
+	Start    time.Time
+	End      time.Time
+	// Attributes are in the order they were set
+	Attributes []Attribute
+	// Error is the message of the error of the span or "" when it has succeeded
+	Error string
+}
+
+// Duration returns how long the span took
+func (s *SpanData) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// Trace holds the finished spans of a trace sorted by their start, the root span is the first one.
+// The spans which end after the root span are not part of it
+type Trace struct {
+	ID    string
+	Spans []*SpanData
+}
+
+// Root returns the root span of the trace
+func (t *Trace) Root() *SpanData {
+	return t.Spans[0]
+}
+
+// Exporter exports the finished traces, e.g. writes them to a file. Export
+// is called concurrently with the traces as soon as their root spans end
+type Exporter interface {
+	Export(trace *Trace) error
+}
+
+// Tracer exports the finished traces to all of its exporters
+type Tracer struct {
+	exporters []Exporter
+	// onError is called with the errors of the exporters
+	onError func(err error)
+}
+
+// NewTracer creates a Tracer which exports the traces to the exporters
+// and passes their errors to onError, which may be nil to ignore them
+func NewTracer(onError func(err error), exporters ...Exporter) *Tracer {
+	return &Tracer{exporters: exporters, onError: onError}
+}
+
+// Close closes the exporters which are io.Closers, e.g. flushes the files they write
+func (t *Tracer) Close() error {
+	var first error
+	for _, exporter := range t.exporters {
+		if closer, ok := exporter.(io.Closer); ok {
+			if err := closer.Close(); err != nil && first == nil {
+				first = err
+			}
+		}
+	}
+
+	return first
+}
+
+func (t *Tracer) export(trace *Trace) {
+	for _, exporter := range t.exporters {
+		if err := exporter.Export(trace); err != nil && t.onError != nil {
+			t.onError(err)
+		}
+	}
+}
+
+var defaultTracer atomic.Value
+
+// SetDefault sets the Tracer which the spans are exported with, nil disables tracing
+func SetDefault(t *Tracer) {
+	defaultTracer.Store(&t)
+}
+
+// Default returns the Tracer set with SetDefault or nil when tracing is disabled
+func Default() *Tracer {
+	t, _ := defaultTracer.Load().(**Tracer)
+	if t == nil {
+		return nil
+	}
+
+	return *t
+}
+
+// trace collects the finished spans until its root span ends
+type trace struct {
+	id     string
+	tracer *Tracer
+
+	mu       sync.Mutex
+	spans    []*SpanData
+	exported bool
+}
+
+// Span is a step of a trace, it must be ended with End. A nil *Span is a valid span which records nothing
+type Span struct {
+	trace *trace
+	data  *SpanData
+	root  bool
+
+	mu    sync.Mutex
+	ended bool
+}
+
+type spanKey struct{}
+
+// Start starts a span which is a child of the span of the context, or the root span of a new trace
+// when the context has none. It returns a copy of the context with the new span and the span, which
+// is nil when tracing is disabled. The attributes are given as alternating keys and values
+func Start(ctx context.Context, name string, attributes ...interface{}) (context.Context, *Span) {
+	parent, _ := ctx.Value(spanKey{}).(*Span)
+
+	var span *Span
+	if parent != nil {
+		span = &Span{trace: parent.trace, data: &SpanData{TraceID: parent.trace.id, ParentID: parent.data.SpanID}}
+	} else {
+		tracer := Default()
+		if tracer == nil {
+			return ctx, nil
+		}
+
+		id := newID(16)
+		span = &Span{trace: &trace{id: id, tracer: tracer}, data: &SpanData{TraceID: id}, root: true}
+	}
+
+	span.data.SpanID = newID(8)
+	span.data.Name = name
+	span.data.Start = time.Now()
+	span.SetAttributes(attributes...)
+
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// FromContext returns the span of the context or nil when there is none
+func FromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// TraceID returns the ID of the trace of the span
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+
+	return s.data.TraceID
+}
+
+// SetAttributes adds the alternating keys and values to the attributes of the span until it ends
+func (s *Span) SetAttributes(attributes ...interface{}) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ended {
+		return
+	}
+
+	for i := 0; i+1 < len(attributes); i += 2 {
+		key, ok := attributes[i].(string)
+		if !ok {
+			continue
+		}
+
+		s.data.Attributes = append(s.data.Attributes, Attribute{Key: key, Value: attributes[i+1]})
+	}
+}
+
+// SetError marks the span as failed with the error, a nil error is ignored
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.ended {
+		s.data.Error = err.Error()
+	}
+}
+
+// End ends the span, the trace is exported when its root span ends. Only the first call has an effect
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+
+	s.ended = true
+	s.data.End = time.Now()
+	s.mu.Unlock()
+
+	t := s.trace
+	t.mu.Lock()
+	if t.exported {
+		t.mu.Unlock()
+		return
+	}
+
+	t.spans = append(t.spans, s.data)
+	if !s.root {
+		t.mu.Unlock()
+		return
+	}
+
+	t.exported = true
+	spans := t.spans
+	t.mu.Unlock()
+
+	// the root span goes first even when a child has started at the same time
+	sort.SliceStable(spans, func(i, j int) bool {
+		if spans[i] == s.data || spans[j] == s.data {
+			return spans[i] == s.data && spans[j] != s.data
+		}
+
+		return spans[i].Start.Before(spans[j].Start)
+	})
+
+	t.tracer.export(&Trace{ID: t.id, Spans: spans})
+}
+
+// newID returns a random hex ID of n bytes, 16 for the trace IDs and 8 for the span IDs as in OpenTelemetry
+func newID(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
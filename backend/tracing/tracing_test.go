@@ -0,0 +1,212 @@
+package tracing_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iliyanmotovski/raytracer/backend/tracing"
+)
+
+// withRecorder sets a default Tracer which keeps the traces in the returned Recorder until the test ends
+func withRecorder(t *testing.T, limit int, exporters ...tracing.Exporter) *tracing.Recorder {
+	recorder := tracing.NewRecorder(limit)
+	tracing.SetDefault(tracing.NewTracer(nil, append(exporters, recorder)...))
+	t.Cleanup(func() { tracing.SetDefault(nil) })
+
+	return recorder
+}
+
+func TestDisabled(t *testing.T) {
+	ctx, span := tracing.Start(context.Background(), "scene.process")
+	assert.Nil(t, span)
+	assert.Nil(t, tracing.FromContext(ctx))
+
+	// the methods of a nil span do nothing
+	span.SetAttributes("rays", 8)
+	span.SetError(errors.New("failed"))
+	span.End()
+	assert.Equal(t, "", span.TraceID())
+}
+
+func TestTrace(t *testing.T) {
+	recorder := withRecorder(t, 10)
+
+	ctx, root := tracing.Start(context.Background(), "POST /api/v1/scene/config", "request_id", "abc")
+	assert.Equal(t, root, tracing.FromContext(ctx))
+
+	jobCtx, job := tracing.Start(ctx, "daemon.job", "kind", "config")
+	_, cast := tracing.Start(jobCtx, "rays.cast")
+	cast.SetAttributes("rays", 14, "ignored")
+	cast.End()
+	job.SetError(errors.New("polygon is not convex"))
+	job.End()
+	job.SetAttributes("late", true)
+
+	// a span which ends after its root is not part of the trace
+	_, late := tracing.Start(ctx, "scene_repository.upsert")
+
+	assert.Empty(t, recorder.Traces())
+	root.End()
+	root.End()
+	late.End()
+
+	traces := recorder.Traces()
+	if assert.Len(t, traces, 1) {
+		trace := traces[0]
+		assert.Equal(t, root.TraceID(), trace.ID)
+		assert.Len(t, trace.ID, 32)
+
+		names := []string{}
+		for _, span := range trace.Spans {
+			assert.Equal(t, trace.ID, span.TraceID)
+			assert.Len(t, span.SpanID, 16)
+			assert.False(t, span.End.Before(span.Start))
+			names = append(names, span.Name)
+		}
+		assert.Equal(t, []string{"POST /api/v1/scene/config", "daemon.job", "rays.cast"}, names)
+
+		assert.Equal(t, trace.Root(), trace.Spans[0])
+		assert.Equal(t, "", trace.Spans[0].ParentID)
+		assert.Equal(t, trace.Spans[0].SpanID, trace.Spans[1].ParentID)
+		assert.Equal(t, trace.Spans[1].SpanID, trace.Spans[2].ParentID)
+
+		assert.Equal(t, []tracing.Attribute{{Key: "kind", Value: "config"}}, trace.Spans[1].Attributes)
+		assert.Equal(t, "polygon is not convex", trace.Spans[1].Error)
+		assert.Equal(t, []tracing.Attribute{{Key: "rays", Value: 14}}, trace.Spans[2].Attributes)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	recorder := withRecorder(t, 2)
+
+	for _, name := range []string{"first", "second", "third"} {
+		ctx, span := tracing.Start(context.Background(), name, "scene", "default")
+		_, child := tracing.Start(ctx, "scene.process")
+		child.SetError(errors.New("context expired"))
+		child.End()
+		span.End()
+	}
+
+	traces := recorder.Traces()
+	if assert.Len(t, traces, 2) {
+		assert.Equal(t, "third", traces[0].Root().Name)
+		assert.Equal(t, "second", traces[1].Root().Name)
+	}
+
+	w := httptest.NewRecorder()
+	recorder.ServeHTTP(w, httptest.NewRequest("GET", "/debug/traces", nil))
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var dtos []struct {
+		ID    string
+		Spans []map[string]interface{}
+	}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &dtos))
+	if assert.Len(t, dtos, 2) {
+		assert.Equal(t, traces[0].ID, dtos[0].ID)
+		assert.Equal(t, "third", dtos[0].Spans[0]["Name"])
+		assert.Equal(t, map[string]interface{}{"scene": "default"}, dtos[0].Spans[0]["Attributes"])
+		assert.Equal(t, "context expired", dtos[0].Spans[1]["Error"])
+	}
+
+	w = httptest.NewRecorder()
+	recorder.ServeHTTP(w, httptest.NewRequest("GET", "/debug/traces?format=text", nil))
+
+	lines := strings.Split(w.Body.String(), "\n")
+	assert.Equal(t, "trace "+traces[0].ID+" at "+traces[0].Root().Start.UTC().Format("2006-01-02T15:04:05.999999999Z07:00"), lines[0])
+	assert.Regexp(t, `^  third \S+ scene=default$`, lines[1])
+	assert.Regexp(t, `^    scene\.process \S+ "error: context expired"$`, lines[2])
+	assert.Equal(t, "", lines[3])
+	assert.Equal(t, "trace "+traces[1].ID, strings.Split(lines[4], " at ")[0])
+}
+
+func TestJSONExporter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	withRecorder(t, 1, tracing.NewJSONExporter(buf))
+
+	for i := 0; i < 2; i++ {
+		_, span := tracing.Start(context.Background(), "config.reload", "path", "config.txt")
+		span.End()
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	assert.Len(t, lines, 2)
+
+	trace := map[string]interface{}{}
+	assert.Nil(t, json.Unmarshal([]byte(lines[0]), &trace))
+	assert.Len(t, trace["Spans"], 1)
+}
+
+func TestOTLPFileExporter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tracing")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "traces.json")
+	exporter, err := tracing.NewOTLPFileExporter(path, "raytracer")
+	assert.Nil(t, err)
+
+	tracer := tracing.NewTracer(nil, exporter)
+	tracing.SetDefault(tracer)
+	defer tracing.SetDefault(nil)
+
+	ctx, root := tracing.Start(context.Background(), "scene.load", "scene", "default", "cached", true)
+	_, child := tracing.Start(ctx, "rays.cast", "rays", 14, "lit_area", 93.38)
+	child.SetError(errors.New("context cancelled"))
+	child.End()
+	root.End()
+
+	assert.Nil(t, tracer.Close())
+
+	b, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+
+	var request struct {
+		ResourceSpans []struct {
+			Resource struct {
+				Attributes []map[string]interface{}
+			}
+			ScopeSpans []struct {
+				Scope map[string]interface{}
+				Spans []map[string]interface{}
+			}
+		}
+	}
+	assert.Nil(t, json.Unmarshal(b, &request))
+
+	if assert.Len(t, request.ResourceSpans, 1) {
+		resource := request.ResourceSpans[0]
+		assert.Equal(t, []map[string]interface{}{{"key": "service.name", "value": map[string]interface{}{"stringValue": "raytracer"}}}, resource.Resource.Attributes)
+
+		spans := resource.ScopeSpans[0].Spans
+		if assert.Len(t, spans, 2) {
+			assert.Equal(t, root.TraceID(), spans[0]["traceId"])
+			assert.Equal(t, "scene.load", spans[0]["name"])
+			assert.Nil(t, spans[0]["parentSpanId"])
+			assert.Equal(t, float64(1), spans[0]["kind"])
+			assert.IsType(t, "", spans[0]["startTimeUnixNano"])
+			assert.Equal(t, []interface{}{
+				map[string]interface{}{"key": "scene", "value": map[string]interface{}{"stringValue": "default"}},
+				map[string]interface{}{"key": "cached", "value": map[string]interface{}{"boolValue": true}},
+			}, spans[0]["attributes"])
+			assert.Equal(t, map[string]interface{}{}, spans[0]["status"])
+
+			assert.Equal(t, spans[0]["spanId"], spans[1]["parentSpanId"])
+			assert.Equal(t, []interface{}{
+				map[string]interface{}{"key": "rays", "value": map[string]interface{}{"intValue": "14"}},
+				map[string]interface{}{"key": "lit_area", "value": map[string]interface{}{"doubleValue": 93.38}},
+			}, spans[1]["attributes"])
+			assert.Equal(t, map[string]interface{}{"code": float64(2), "message": "context cancelled"}, spans[1]["status"])
+		}
+	}
+}
@@ -34,3 +34,22 @@ func (e1 *Edge) Intersection(e2 *Edge) (*Vector, bool) {
 
 	return nil, false
 }
+
+// Crosses checks whether two edges, treated as finite segments, properly
+// cross each other. Edges which only touch at their end points are not
+// considered crossing
+func (e1 *Edge) Crosses(e2 *Edge) bool {
+	d1 := Orientation(e2.A, e2.B, e1.A)
+	d2 := Orientation(e2.A, e2.B, e1.B)
+	d3 := Orientation(e1.A, e1.B, e2.A)
+	d4 := Orientation(e1.A, e1.B, e2.B)
+
+	return ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) &&
+		((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0))
+}
+
+// Orientation returns the z component of the cross product of (b - a) and (c - a),
+// positive when c lies to the left of a->b, negative when to the right, zero if collinear
+func Orientation(a, b, c *Vector) float64 {
+	return (b.X-a.X)*(c.Y-a.Y) - (b.Y-a.Y)*(c.X-a.X)
+}
@@ -41,6 +41,15 @@ func (v Vector) MultiplyByScalar(s float64) Vector {
 	}
 }
 
+// Rotate returns the vector rotated counterclockwise by the given angle in radians
+func (v Vector) Rotate(angle float64) Vector {
+	sin, cos := math.Sincos(angle)
+	return Vector{
+		X: v.X*cos - v.Y*sin,
+		Y: v.X*sin + v.Y*cos,
+	}
+}
+
 // Length returns the length of the vector
 func (v Vector) Length() float64 {
 	return math.Sqrt(v.Dot(v))
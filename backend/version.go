@@ -0,0 +1,12 @@
+package backend
+
+import "time"
+
+// Version describes a single persisted revision of a config or a scene,
+// IDs start from 1 and grow with every Upsert
+type Version struct {
+	ID        int
+	CreatedAt time.Time
+}
+
+type Versions []*Version
@@ -0,0 +1,158 @@
+package backend
+
+import (
+	"context"
+	"math"
+	"sort"
+
+	"github.com/iliyanmotovski/raytracer/backend/vector"
+)
+
+// Wall represents an open polyline, a thin "solid" obstacle
+// which unlike the Polygon is not closed, i.e. a fence
+type Wall struct {
+	Vertices      vector.Vectors
+	VerticesCount int
+}
+
+// GetBoundaries returns all boundaries (segments) of the wall
+func (w *Wall) GetBoundaries() Boundaries {
+	result := Boundaries{}
+
+	for i := 0; i < len(w.Vertices)-1; i++ {
+		result = append(result, &Boundary{vector.Edge{A: w.Vertices[i], B: w.Vertices[i+1]}})
+	}
+
+	return result
+}
+
+type Walls []*Wall
+
+// Validate checks all walls and returns whether some has a point
+// which is outside of the scene or inside some polygon, or some of
+// its segments crosses a polygon side or passes through the inside
+// of a polygon, e.g. from one of its vertices to another. The walls
+// may cross each other, they cast their shadows the same way as
+// the separate ones
+func (ws Walls) Validate(ctx context.Context, width, height float64, polygons Polygons) error {
+	scene := &Polygon{Loop: vector.Loop{
+		{X: 0, Y: 0},
+		{X: width, Y: 0},
+		{X: width, Y: height},
+		{X: 0, Y: height},
+	}}
+
+	for _, wall := range ws {
+		if err := ContextError(ctx); err != nil {
+			return err
+		}
+
+		if len(wall.Vertices) < 2 {
+			return invalid("wall_too_short", "wall must have at least 2 vertices")
+		}
+
+		for _, vertice := range wall.Vertices {
+			if !scene.IsPointContainedInPolygon(vertice) {
+				return invalid("wall_outside_scene", "point X: %v , Y: %v is outside the scene", vertice.X, vertice.Y)
+			}
+
+			for _, polygon := range polygons {
+				if polygon.IsPointContainedInPolygon(vertice) && !polygon.ContainsVertice(vertice) {
+					return invalid("wall_inside_polygon", "point X: %v , Y: %v is inside a polygon", vertice.X, vertice.Y)
+				}
+			}
+		}
+
+		for _, segment := range wall.GetBoundaries() {
+			for _, polygon := range polygons {
+				for _, side := range polygon.GetBoundaries() {
+					if segment.Crosses(&side.Edge) {
+						return invalid("wall_crosses_polygon", "wall segment X: %v , Y: %v - X: %v , Y: %v crosses a polygon",
+							segment.A.X, segment.A.Y, segment.B.X, segment.B.Y)
+					}
+				}
+
+				if passesThrough(segment, polygon) {
+					return invalid("wall_crosses_polygon", "wall segment X: %v , Y: %v - X: %v , Y: %v crosses a polygon",
+						segment.A.X, segment.A.Y, segment.B.X, segment.B.Y)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// onSegmentTolerance is the distance from a segment within which a point is on it
+const onSegmentTolerance = 1e-9
+
+// passesThrough returns whether a part of the segment, which does not cross the sides
+// of the polygon, is inside it. Such a segment can only touch the sides at its end points
+// or at the vertices of the polygon, so it is split at the vertices which are on it and
+// the segment is inside when the middle of some of the parts is inside and not on a side
+func passesThrough(segment *Boundary, polygon *Polygon) bool {
+	splits := []float64{0, 1}
+	for _, vertice := range polygon.Loop {
+		if t, ok := onSegment(vertice, &segment.Edge); ok {
+			splits = append(splits, t)
+		}
+	}
+	sort.Float64s(splits)
+
+	for i := 0; i+1 < len(splits); i++ {
+		if splits[i+1]-splits[i] < onSegmentTolerance {
+			continue
+		}
+
+		t := (splits[i] + splits[i+1]) / 2
+		middle := &vector.Vector{
+			X: segment.A.X + t*(segment.B.X-segment.A.X),
+			Y: segment.A.Y + t*(segment.B.Y-segment.A.Y),
+		}
+
+		if !polygon.IsPointContainedInPolygon(middle) {
+			continue
+		}
+
+		// a part which runs along a side is on its boundary and not inside
+		onSide := false
+		for _, side := range polygon.GetBoundaries() {
+			if _, ok := onSegment(middle, &side.Edge); ok {
+				onSide = true
+				break
+			}
+		}
+
+		if !onSide {
+			return true
+		}
+	}
+
+	return false
+}
+
+// onSegment returns whether the point is on the segment and how far along it, from 0 at A to 1 at B
+func onSegment(point *vector.Vector, segment *vector.Edge) (float64, bool) {
+	length := segment.A.Distance(*segment.B)
+	if length == 0 {
+		return 0, false
+	}
+
+	if math.Abs(vector.Orientation(segment.A, segment.B, point))/length > onSegmentTolerance {
+		return 0, false
+	}
+
+	t := point.Sub(*segment.A).Dot(segment.B.Sub(*segment.A)) / (length * length)
+	return t, t >= 0 && t <= 1
+}
+
+// getAllVertices returns all vertices of all walls in an array
+func (ws Walls) getAllVertices() vector.Vectors {
+	result := vector.Vectors{}
+
+	for _, wall := range ws {
+		result = append(result, wall.Vertices...)
+	}
+
+	return result
+}
@@ -0,0 +1,167 @@
+package backend_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+	"github.com/iliyanmotovski/raytracer/backend/vector"
+)
+
+func TestGetWallBoundaries(t *testing.T) {
+	wall := &backend.Wall{
+		VerticesCount: 3,
+		Vertices: vector.Vectors{
+			{X: 100, Y: 100},
+			{X: 200, Y: 100},
+			{X: 200, Y: 200},
+		},
+	}
+
+	got := wall.GetBoundaries()
+
+	want := backend.Boundaries{
+		{Edge: vector.Edge{A: &vector.Vector{X: 100, Y: 100}, B: &vector.Vector{X: 200, Y: 100}}},
+		{Edge: vector.Edge{A: &vector.Vector{X: 200, Y: 100}, B: &vector.Vector{X: 200, Y: 200}}},
+	}
+
+	assert.Equal(t, want, got)
+}
+
+func TestValidateWalls(t *testing.T) {
+	polygons := backend.Polygons{
+		{
+			VerticesCount: 3,
+			Loop: vector.Loop{
+				{X: 600, Y: 200},
+				{X: 646, Y: 133},
+				{X: 646, Y: 261},
+			},
+		},
+	}
+
+	walls := backend.Walls{
+		{
+			VerticesCount: 2,
+			Vertices: vector.Vectors{
+				{X: 100, Y: 100},
+				{X: 300, Y: 100},
+			},
+		},
+		{
+			VerticesCount: 2,
+			Vertices: vector.Vectors{
+				{X: 646, Y: 261},
+				{X: 700, Y: 400},
+			},
+		},
+	}
+
+	got := walls.Validate(context.Background(), 800, 500, polygons)
+	assert.Nil(t, got)
+}
+
+func TestValidateWallsWithErrors(t *testing.T) {
+	polygons := backend.Polygons{
+		{
+			VerticesCount: 3,
+			Loop: vector.Loop{
+				{X: 600, Y: 200},
+				{X: 646, Y: 133},
+				{X: 646, Y: 261},
+			},
+		},
+	}
+
+	cases := []*struct {
+		wall *backend.Wall
+		want error
+	}{
+		{
+			&backend.Wall{VerticesCount: 1, Vertices: vector.Vectors{{X: 100, Y: 100}}},
+			&backend.ValidationError{Reason: "wall_too_short", Message: "wall must have at least 2 vertices"},
+		},
+		{
+			&backend.Wall{VerticesCount: 2, Vertices: vector.Vectors{{X: 100, Y: 100}, {X: 850, Y: 100}}},
+			&backend.ValidationError{Reason: "wall_outside_scene", Message: "point X: 850 , Y: 100 is outside the scene"},
+		},
+		{
+			&backend.Wall{VerticesCount: 2, Vertices: vector.Vectors{{X: 100, Y: 100}, {X: 640, Y: 200}}},
+			&backend.ValidationError{Reason: "wall_inside_polygon", Message: "point X: 640 , Y: 200 is inside a polygon"},
+		},
+		{
+			&backend.Wall{VerticesCount: 2, Vertices: vector.Vectors{{X: 500, Y: 200}, {X: 700, Y: 200}}},
+			&backend.ValidationError{Reason: "wall_crosses_polygon", Message: "wall segment X: 500 , Y: 200 - X: 700 , Y: 200 crosses a polygon"},
+		},
+		{
+			// from one vertice to another through the inside of the polygon
+			&backend.Wall{VerticesCount: 2, Vertices: vector.Vectors{{X: 600, Y: 200}, {X: 646, Y: 200}}},
+			&backend.ValidationError{Reason: "wall_crosses_polygon", Message: "wall segment X: 600 , Y: 200 - X: 646 , Y: 200 crosses a polygon"},
+		},
+		{
+			// into the polygon through a vertice up to a side
+			&backend.Wall{VerticesCount: 2, Vertices: vector.Vectors{{X: 500, Y: 200}, {X: 646, Y: 200}}},
+			&backend.ValidationError{Reason: "wall_crosses_polygon", Message: "wall segment X: 500 , Y: 200 - X: 646 , Y: 200 crosses a polygon"},
+		},
+	}
+
+	for i, c := range cases {
+		got := backend.Walls{c.wall}.Validate(context.Background(), 800, 500, polygons)
+		assert.Equal(t, c.want, got, "case failed: %v", i)
+	}
+}
+
+func TestValidateWallsAlongPolygon(t *testing.T) {
+	square := backend.Polygons{
+		{
+			VerticesCount: 4,
+			Loop: vector.Loop{
+				{X: 100, Y: 100},
+				{X: 200, Y: 100},
+				{X: 200, Y: 200},
+				{X: 100, Y: 200},
+			},
+		},
+	}
+
+	// the diagonal goes through the inside of the square
+	diagonal := backend.Walls{{VerticesCount: 2, Vertices: vector.Vectors{{X: 100, Y: 100}, {X: 200, Y: 200}}}}
+	assert.Equal(t, &backend.ValidationError{Reason: "wall_crosses_polygon", Message: "wall segment X: 100 , Y: 100 - X: 200 , Y: 200 crosses a polygon"},
+		diagonal.Validate(context.Background(), 800, 500, square))
+
+	// the walls may run along the sides, touch the vertices from outside and cross each other
+	walls := backend.Walls{
+		{VerticesCount: 3, Vertices: vector.Vectors{{X: 50, Y: 100}, {X: 200, Y: 100}, {X: 200, Y: 300}}},
+		{VerticesCount: 2, Vertices: vector.Vectors{{X: 50, Y: 250}, {X: 250, Y: 250}}},
+		{VerticesCount: 2, Vertices: vector.Vectors{{X: 300, Y: 100}, {X: 200, Y: 200}}},
+	}
+	assert.Nil(t, walls.Validate(context.Background(), 800, 500, square))
+}
+
+func TestSceneWallCastsShadow(t *testing.T) {
+	config := &backend.Config{
+		Light: &vector.Vector{X: 400, Y: 100},
+		Scene: &vector.Vector{X: 800, Y: 500},
+	}
+
+	_, withoutWall, err := backend.NewScene(config).Process(context.Background())
+	assert.Nil(t, err)
+
+	config.Walls = backend.Walls{
+		{
+			VerticesCount: 2,
+			Vertices: vector.Vectors{
+				{X: 300, Y: 200},
+				{X: 500, Y: 200},
+			},
+		},
+	}
+
+	_, withWall, err := backend.NewScene(config).Process(context.Background())
+	assert.Nil(t, err)
+
+	assert.Equal(t, float64(100), withoutWall)
+	assert.True(t, withWall < withoutWall)
+}
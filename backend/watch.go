@@ -0,0 +1,70 @@
+package backend
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// fileState is what is compared to tell whether a watched file has changed
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// WatchFile polls the file every interval and sends on the returned chan once it
+// has changed and then stayed unchanged for the debounce period, so an editor which
+// saves the file in a few writes causes a single change. The changes which are not
+// received yet are merged into one. A missing file is not a change, as editors often
+// replace the file, but it is one once it appears again. The chan is closed when the
+// context is done
+func WatchFile(ctx context.Context, path string, interval, debounce time.Duration) <-chan struct{} {
+	changes := make(chan struct{}, 1)
+
+	go func() {
+		defer close(changes)
+
+		last, _ := statFile(path)
+		var changedAt time.Time
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				current, ok := statFile(path)
+				if ok && current != last {
+					last = current
+					changedAt = now
+					continue
+				}
+
+				if changedAt.IsZero() || now.Sub(changedAt) < debounce {
+					continue
+				}
+
+				changedAt = time.Time{}
+
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+
+	return changes
+}
+
+// statFile returns the state of the file and false if it can not be read
+func statFile(path string) (fileState, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileState{}, false
+	}
+
+	return fileState{modTime: info.ModTime(), size: info.Size()}, true
+}
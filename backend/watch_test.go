@@ -0,0 +1,60 @@
+package backend_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+)
+
+func TestWatchFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watch")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.txt")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("800 500"), 0644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := backend.WatchFile(ctx, path, 5*time.Millisecond, 50*time.Millisecond)
+
+	// a few quick writes are a single change
+	for _, content := range []string{"800 500\n", "800 500\n250", "800 500\n250 300"} {
+		assert.Nil(t, ioutil.WriteFile(path, []byte(content), 0644))
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	select {
+	case <-changes:
+	case <-time.After(time.Second):
+		t.Fatal("change not detected")
+	}
+
+	select {
+	case <-changes:
+		t.Fatal("writes not debounced")
+	case <-time.After(150 * time.Millisecond):
+	}
+
+	// replacing the file is a change once the new one is there
+	assert.Nil(t, os.Remove(path))
+	time.Sleep(20 * time.Millisecond)
+	assert.Nil(t, ioutil.WriteFile(path, []byte("800 500\n250 300\n0"), 0644))
+
+	select {
+	case <-changes:
+	case <-time.After(time.Second):
+		t.Fatal("replaced file not detected")
+	}
+
+	cancel()
+
+	_, ok := <-changes
+	assert.False(t, ok)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+	"github.com/iliyanmotovski/raytracer/backend/logging"
+	"github.com/iliyanmotovski/raytracer/backend/persistent"
+	renderer "github.com/iliyanmotovski/raytracer/backend/render"
+	"github.com/iliyanmotovski/raytracer/backend/vector"
+)
+
+// animate is the animate subcommand, it renders the scene of the config file with the light moving
+// along a path as an animated GIF or as numbered PNG frames in a directory. The frames are rendered
+// in parallel on the workers of a scene reload daemon, the same way as the server renders them
+func animate(args []string) error {
+	flags := flag.NewFlagSet("animate", flag.ExitOnError)
+	path := flags.String("path", "", `light path as space separated points, e.g. "100,100 700,100 700,400"`)
+	step := flags.Float64("step", 0, "distance between the lights of the frames along the path, every point is a frame when 0")
+	format := flags.String("format", string(renderer.GIFFormat), "gif or png")
+	out := flags.String("o", "", "path to the GIF or to the directory of the PNG frames, animation.gif or frames by default")
+	delay := flags.Int("delay", renderer.DefaultDelay, "time of each GIF frame in 100ths of a second")
+	workers := flags.Int("workers", runtime.NumCPU(), "number of workers rendering the frames")
+	options := renderOptionFlags(flags)
+
+	configPath, err := configArg("animate", parseArgs(flags, args))
+	if err != nil {
+		return err
+	}
+
+	opts, err := options()
+	if err != nil {
+		return usageError(err.Error())
+	}
+
+	points, err := parsePath(*path)
+	if err != nil {
+		return usageError(err.Error())
+	}
+
+	ctx := context.Background()
+
+	scene, err := loadScene(ctx, configPath)
+	if err != nil {
+		return err
+	}
+
+	cc := make(chan *backend.ConfigChan)
+	defer close(cc)
+
+	backend.NewSceneReloadDaemon(persistent.NewInMemorySceneRepository(), cc).Start(*workers)
+
+	animation := &renderer.Animation{
+		Lights:  renderer.LightPath(points, *step),
+		Format:  renderer.AnimationFormat(*format),
+		Delay:   *delay,
+		Options: opts,
+	}
+
+	if err := animation.Validate(scene); err != nil {
+		return err
+	}
+
+	if animation.Format == renderer.PNGFormat {
+		return writeFrames(ctx, scene, animation, daemonRunner(cc), *out)
+	}
+
+	if *out == "" {
+		*out = "animation.gif"
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+
+	if err := animation.Render(ctx, f, scene, daemonRunner(cc)); err != nil {
+		f.Close()
+		return err
+	}
+
+	logging.Default().Info("animation written", "frames", len(animation.Lights), "path", *out)
+	return f.Close()
+}
+
+// writeFrames writes each frame of the animation as a PNG image named by renderer.FrameName to the directory
+func writeFrames(ctx context.Context, scene *backend.Scene, animation *renderer.Animation, run renderer.Runner, dir string) error {
+	if dir == "" {
+		dir = "frames"
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	err := renderer.Frames(ctx, scene, animation.Lights, animation.Options, run, func(i int, img *image.RGBA) error {
+		f, err := os.Create(filepath.Join(dir, renderer.FrameName(i)))
+		if err != nil {
+			return err
+		}
+
+		if err := png.Encode(f, img); err != nil {
+			f.Close()
+			return err
+		}
+
+		return f.Close()
+	})
+	if err != nil {
+		return err
+	}
+
+	logging.Default().Info("frames written", "frames", len(animation.Lights), "dir", dir)
+	return nil
+}
+
+// parsePath parses the space separated x,y points of the path
+func parsePath(path string) (vector.Vectors, error) {
+	points := vector.Vectors{}
+
+	for _, field := range strings.Fields(path) {
+		xy := strings.Split(field, ",")
+		if len(xy) != 2 {
+			return nil, fmt.Errorf("invalid path point %q, it must be x,y", field)
+		}
+
+		x, errX := strconv.ParseFloat(xy[0], 64)
+		y, errY := strconv.ParseFloat(xy[1], 64)
+		if errX != nil || errY != nil {
+			return nil, fmt.Errorf("invalid path point %q, it must be x,y", field)
+		}
+
+		points = append(points, &vector.Vector{X: x, Y: y})
+	}
+
+	return points, nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+	"github.com/iliyanmotovski/raytracer/backend/vector"
+)
+
+// bench is the bench subcommand, it generates a scene with a grid of squares
+// and prints how long it takes to validate it and to process it
+func bench(args []string) error {
+	flags := flag.NewFlagSet("bench", flag.ExitOnError)
+	grid := flags.Int("grid", 20, "the scene is a grid of grid x grid squares")
+	curved := flags.Bool("curved", false, "make a side of every other square curved")
+	runs := flags.Int("runs", 10, "number of times the scene is processed")
+
+	if len(parseArgs(flags, args)) > 0 {
+		return usageError("bench takes no arguments")
+	}
+
+	if *grid < 1 || *runs < 1 {
+		return usageError("grid and runs must be positive")
+	}
+
+	config := benchConfig(*grid, *curved)
+	scene := backend.NewScene(config)
+	fmt.Printf("scene: %d polygons in %vx%v\n", len(config.Polygons), config.Scene.X, config.Scene.Y)
+
+	ctx := context.Background()
+
+	var litArea float64
+	var triangles backend.Triangles
+	durations := make([]time.Duration, *runs)
+
+	for i := range durations {
+		start := time.Now()
+
+		var err error
+		triangles, litArea, err = scene.Process(ctx)
+		if err != nil {
+			return err
+		}
+
+		durations[i] = time.Since(start)
+	}
+
+	fmt.Printf("lit area: %v%% in %d triangles\n", litArea, len(triangles))
+	printDurations("process", durations)
+
+	for i := range durations {
+		start := time.Now()
+		if err := scene.Validate(ctx); err != nil {
+			return err
+		}
+
+		durations[i] = time.Since(start)
+	}
+
+	printDurations("validate", durations)
+	return nil
+}
+
+// benchConfig generates a config with a grid of n x n squares of size 30 with gaps of 20
+// between them and the light in the gap in the middle, every other square has a curved
+// side when curved is set
+func benchConfig(n int, curved bool) *backend.Config {
+	center := float64(n / 2 * 50)
+
+	config := &backend.Config{
+		ID:       backend.DefaultSceneID,
+		Light:    &vector.Vector{X: center, Y: center},
+		Scene:    &vector.Vector{X: float64(n * 50), Y: float64(n * 50)},
+		Polygons: make(backend.Polygons, 0, n*n),
+	}
+
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			x, y := float64(i*50+10), float64(j*50+10)
+
+			polygon := &backend.Polygon{
+				VerticesCount: 4,
+				Loop:          vector.Loop{{X: x, Y: y}, {X: x + 30, Y: y}, {X: x + 30, Y: y + 30}, {X: x, Y: y + 30}},
+			}
+
+			if curved && (i+j)%2 == 1 {
+				polygon.Controls = []vector.Vectors{{{X: x + 15, Y: y - 8}}, nil, nil, nil}
+			}
+
+			config.Polygons = append(config.Polygons, polygon)
+		}
+	}
+
+	return config
+}
+
+// printDurations prints the minimum, the mean and the maximum of the durations
+func printDurations(name string, durations []time.Duration) {
+	min, max, total := durations[0], durations[0], time.Duration(0)
+	for _, d := range durations {
+		if d < min {
+			min = d
+		}
+
+		if d > max {
+			max = d
+		}
+
+		total += d
+	}
+
+	fmt.Printf("%s: min %v, mean %v, max %v in %d runs\n", name, min, total/time.Duration(len(durations)), max, len(durations))
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+	"github.com/iliyanmotovski/raytracer/backend/persistent"
+)
+
+// compute is the compute subcommand, it processes the scene of the config file
+// and prints its lit area and its lit triangles as JSON to the standard output
+func compute(args []string) error {
+	flags := flag.NewFlagSet("compute", flag.ExitOnError)
+	indent := flags.Bool("indent", false, "indent the JSON output")
+
+	configPath, err := configArg("compute", parseArgs(flags, args))
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	config, err := backend.NewTextFileConfigurator(configPath).Parse(ctx, persistent.NewInMemoryConfigRepository())
+	if err != nil {
+		return err
+	}
+
+	triangles, litArea, err := backend.NewScene(config).Process(ctx)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	if *indent {
+		encoder.SetIndent("", "  ")
+	}
+
+	return encoder.Encode(newComputedDTO(triangles, litArea))
+}
+
+type computedDTO struct {
+	LitArea   float64
+	Triangles []*computedTriangleDTO
+}
+
+type computedTriangleDTO struct {
+	Vertices []*pointDTO
+	// Arc is the arc of the ellipse which is the far side of the triangle instead of a straight line
+	Arc *computedArcDTO `json:",omitempty"`
+}
+
+type computedArcDTO struct {
+	Center           *pointDTO
+	RadiusX, RadiusY float64
+	From, To         float64
+}
+
+type pointDTO struct {
+	X, Y float64
+}
+
+func newComputedDTO(triangles backend.Triangles, litArea float64) *computedDTO {
+	dto := &computedDTO{LitArea: litArea, Triangles: make([]*computedTriangleDTO, len(triangles))}
+
+	for i, triangle := range triangles {
+		tri := &computedTriangleDTO{Vertices: make([]*pointDTO, len(triangle.Loop))}
+		for j, vertex := range triangle.Loop {
+			tri.Vertices[j] = &pointDTO{X: vertex.X, Y: vertex.Y}
+		}
+
+		if arc := triangle.Arc; arc != nil {
+			tri.Arc = &computedArcDTO{
+				Center:  &pointDTO{X: arc.Ellipse.Center.X, Y: arc.Ellipse.Center.Y},
+				RadiusX: arc.Ellipse.RadiusX,
+				RadiusY: arc.Ellipse.RadiusY,
+				From:    arc.From,
+				To:      arc.To,
+			}
+		}
+
+		dto.Triangles[i] = tri
+	}
+
+	return dto
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+	"github.com/iliyanmotovski/raytracer/backend/health"
+)
+
+// version, commit and buildTime describe the build served on /version, they are set when building, e.g.
+// go build -ldflags "-X main.version=v1.2.0 -X main.commit=$(git rev-parse HEAD) -X main.buildTime=$(date -u +%FT%TZ)"
+var version, commit, buildTime string
+
+// readinessTimeout limits how long the readiness checks may take
+const readinessTimeout = 2 * time.Second
+
+// errSceneNotLoaded is the error of the initial scene check until the initial scene is processed
+var errSceneNotLoaded = errors.New("the initial scene is not processed yet")
+
+// serverReadiness is ready once the initial scene is processed, the repositories respond
+// and the daemon is not stalled, it is never ready again once the server is shutting down
+type serverReadiness struct {
+	*health.Readiness
+
+	mu sync.Mutex
+	// sceneErr is the error of processing the initial scene, nil once it is processed
+	sceneErr error
+}
+
+// newReadiness creates the readiness which checks the initial scene and the repositories
+func newReadiness(sceneRepo backend.SceneRepository, configRepo backend.ConfigRepository) *serverReadiness {
+	r := &serverReadiness{Readiness: health.NewReadiness(readinessTimeout), sceneErr: errSceneNotLoaded}
+
+	r.Add("initial_scene", func(context.Context) error {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		return r.sceneErr
+	})
+
+	r.Add("repositories", func(ctx context.Context) error {
+		if _, err := sceneRepo.Get(ctx, backend.DefaultSceneID); err != nil {
+			return fmt.Errorf("scene repository: %v", err)
+		}
+
+		if _, err := configRepo.Get(ctx, backend.DefaultSceneID); err != nil {
+			return fmt.Errorf("config repository: %v", err)
+		}
+
+		return nil
+	})
+
+	return r
+}
+
+// loaded records the result of processing the initial scene, a restored scene is processed already
+func (r *serverReadiness) loaded(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sceneErr = nil
+	if err != nil {
+		r.sceneErr = fmt.Errorf("the initial scene is not processed: %v", err)
+	}
+}
+
+// reloaded records the result of reloading the config file, a successful reload
+// makes up for the initial scene which has failed, a failed one changes nothing
+func (r *serverReadiness) reloaded(err error) {
+	if err == nil {
+		r.loaded(nil)
+	}
+}
@@ -3,116 +3,123 @@ package main
 import (
 	"context"
 	"flag"
-	"html/template"
-	"log"
-	"net"
-	"net/http"
+	"fmt"
 	"os"
-	"os/signal"
-	"syscall"
-
-	"github.com/gorilla/mux"
+	"strings"
 
 	"github.com/iliyanmotovski/raytracer/backend"
+	"github.com/iliyanmotovski/raytracer/backend/logging"
 	"github.com/iliyanmotovski/raytracer/backend/persistent"
-	"github.com/iliyanmotovski/raytracer/backend/server/http/api"
+	renderer "github.com/iliyanmotovski/raytracer/backend/render"
 )
 
-var (
-	httpPort   = flag.String("port", "8008", "http listen address")
-	configPath = flag.String("config", "config.txt", "path to config file")
+const (
+	// exitFailure is the exit code of a failed command, e.g. of an invalid config
+	exitFailure = 1
+	// exitUsage is the exit code of a command with invalid arguments, the same as of the flag package
+	exitUsage = 2
 )
 
-func main() {
-	flag.Parse()
+// commands are the subcommands of the binary, serve is run when none is given,
+// so the server is started with its flags the same way as before the subcommands
+var commands = map[string]func(args []string) error{
+	"serve":    serve,
+	"validate": validate,
+	"compute":  compute,
+	"render":   render,
+	"animate":  animate,
+	"bench":    bench,
+}
 
-	sceneRepo := persistent.NewInMemorySceneRepository()
-	configRepo := persistent.NewInMemoryConfigRepository()
+const usage = `Usage: raytracer <command> [arguments]
 
-	ctx := context.Background()
-	configurator := backend.NewTextFileConfigurator(*configPath)
+Commands:
+  serve                      start the HTTP server, the default command
+  validate [config]          check the config and exit with 1 when it is invalid
+  compute [config]           print the lit area and the lit triangles of the scene as JSON
+  render [config] -o out     draw the scene as a PNG or an SVG image
+  animate [config] -path p   draw the scene with the light moving along the path
+  bench                      process a generated scene and print the timings
 
-	c, err := configurator.Parse(ctx, configRepo)
-	if err != nil {
-		log.Println(err)
-		os.Exit(1)
-	}
-
-	cc := make(chan *backend.ConfigChan)
-	initialSrrc := make(chan *backend.SceneReloadResponse)
-	hotReloadSrrc := make(chan *backend.SceneReloadResponse)
-	createConfigHandlerSrrc := make(chan *backend.SceneReloadResponse)
+The config defaults to config.txt, run raytracer <command> -h for the flags of the command
+`
 
-	srrcFactory := backend.SceneReloadResponseChanFactory{
-		backend.Initial:             initialSrrc,
-		backend.HotReload:           hotReloadSrrc,
-		backend.CreateConfigHandler: createConfigHandlerSrrc,
-	}
+// usageError is returned by the commands which are given invalid arguments
+type usageError string
 
-	sceneReloadDaemon := backend.NewSceneReloadDaemon(sceneRepo, cc, srrcFactory)
-	sceneReloadDaemon.Start(1)
+func (e usageError) Error() string {
+	return string(e)
+}
 
-	cc <- &backend.ConfigChan{Ctx: ctx, Config: c, ResponseChan: backend.Initial}
-	resp := <-srrcFactory[backend.Initial]
-	if resp.Err != nil {
-		log.Println(resp.Err)
+func main() {
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
 	}
 
-	apiRoot := mux.NewRouter().PathPrefix("/api/v1").Subrouter()
-	apiRoot.Handle("/scene", api.GetScene(sceneRepo)).Methods("GET")
-	apiRoot.Handle("/scene/config", api.CreateConfiguration(cc, srrcFactory)).Methods("POST")
+	if name == "help" {
+		fmt.Print(usage)
+		return
+	}
 
-	fileServer := http.FileServer(http.Dir("../frontend"))
+	command, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", name, usage)
+		os.Exit(exitUsage)
+	}
 
-	http.HandleFunc("/{path:.+\\.[a-z0-9]+$}", handler)
-	http.Handle("/", http.StripPrefix("/", fileServer))
-	http.Handle("/api/v1/", apiRoot)
+	if err := command(args); err != nil {
+		logging.Default().Error(err.Error())
 
-	errChan := make(chan error)
-	go func() {
-		l, err := net.Listen("tcp", ":"+*httpPort)
-		if err != nil {
-			errChan <- err
+		if _, ok := err.(usageError); ok {
+			os.Exit(exitUsage)
 		}
 
-		if err := http.Serve(l, nil); err != nil {
-			errChan <- err
-		}
-	}()
+		os.Exit(exitFailure)
+	}
+}
+
+// parseArgs parses the flags, which may also follow the positional arguments, and returns the positional ones
+func parseArgs(flags *flag.FlagSet, args []string) []string {
+	var positional []string
 
-	// configuration hot reload
-	sigchan := make(chan os.Signal)
-	signal.Notify(sigchan, syscall.SIGHUP, syscall.SIGTERM)
 	for {
-		select {
-		case s := <-sigchan:
-			switch s {
-			case syscall.SIGHUP:
-				log.Println("reloading configuration")
-				c, err = configurator.Parse(ctx, configRepo)
-				if err != nil {
-					log.Println(err)
-					continue
-				}
-
-				cc <- &backend.ConfigChan{Ctx: ctx, Config: c, ResponseChan: backend.HotReload}
-				resp := <-srrcFactory[backend.HotReload]
-				if resp.Err != nil {
-					log.Println(resp.Err)
-				}
-
-				continue
-			case syscall.SIGTERM:
-				os.Exit(0)
-			}
-		case httpErr := <-errChan:
-			log.Println(httpErr)
-			os.Exit(1)
+		flags.Parse(args)
+
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional
 		}
+
+		positional, args = append(positional, args[0]), args[1:]
+	}
+}
+
+// configArg returns the path to the config file from the positional arguments of the command
+func configArg(command string, args []string) (string, error) {
+	switch len(args) {
+	case 0:
+		return "config.txt", nil
+	case 1:
+		return args[0], nil
+	default:
+		return "", usageError(fmt.Sprintf("%s takes a single config file", command))
 	}
 }
 
-func handler(w http.ResponseWriter, r *http.Request) {
-	t, _ := template.ParseFiles("../frontend/index.html")
-	t.Execute(w, "")
+// loadScene parses the config file and processes its scene with in-memory repositories
+func loadScene(ctx context.Context, configPath string) (*backend.Scene, error) {
+	config, err := backend.NewTextFileConfigurator(configPath).Parse(ctx, persistent.NewInMemoryConfigRepository())
+	if err != nil {
+		return nil, err
+	}
+
+	return backend.NewScene(config).Load(ctx, persistent.NewInMemorySceneRepository())
+}
+
+// daemonRunner runs the tasks on the workers of the daemon listening on cc
+func daemonRunner(cc chan *backend.ConfigChan) renderer.Runner {
+	return func(ctx context.Context, task func(ctx context.Context) error) error {
+		return backend.RunTask(ctx, cc, task)
+	}
 }
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+	"github.com/iliyanmotovski/raytracer/backend/metrics"
+)
+
+// serverMetrics are the metrics of the processed scenes and of the daemon served on /metrics
+type serverMetrics struct {
+	*metrics.Registry
+
+	durations          *metrics.HistogramVec
+	validationFailures *metrics.CounterVec
+	rays               *metrics.GaugeVec
+	boundaries         *metrics.GaugeVec
+	polygons           *metrics.GaugeVec
+	litArea            *metrics.GaugeVec
+}
+
+// newServerMetrics creates the metrics and observes the jobs processed by the daemon,
+// it must be called before the daemon is started
+func newServerMetrics(daemon *backend.SceneReloadDaemon) *serverMetrics {
+	registry := metrics.NewRegistry()
+
+	m := &serverMetrics{
+		Registry: registry,
+		durations: registry.NewHistogramVec("raytracer_job_duration_seconds",
+			"Duration of the jobs processed by the daemon by kind - config, light or task and by source - initial, hotreload or api in seconds.",
+			metrics.DefaultBuckets, "kind", "source"),
+		validationFailures: registry.NewCounterVec("raytracer_validation_failures_total",
+			"Number of the configs and light moves rejected as invalid by reason.", "reason"),
+		rays: registry.NewGaugeVec("raytracer_scene_rays",
+			"Number of the rays cast from the light of the scene.", "scene"),
+		boundaries: registry.NewGaugeVec("raytracer_scene_boundaries",
+			"Number of the boundaries the rays of the scene are cast against.", "scene"),
+		polygons: registry.NewGaugeVec("raytracer_scene_polygons",
+			"Number of the polygons of the scene.", "scene"),
+		litArea: registry.NewGaugeVec("raytracer_scene_lit_area_percent",
+			"Lit area of the scene in percent of the whole scene.", "scene"),
+	}
+
+	registry.NewGaugeFunc("raytracer_daemon_queue_depth", "Number of the jobs waiting for a worker.", func() float64 {
+		return float64(daemon.Metrics().Pending)
+	})
+	registry.NewGaugeFunc("raytracer_daemon_running_jobs", "Number of the jobs being processed.", func() float64 {
+		return float64(daemon.Metrics().Running)
+	})
+	registry.NewGaugeFunc("raytracer_daemon_heartbeat_timestamp_seconds", "Unix time at which a worker has last picked or finished a job.", func() float64 {
+		return float64(daemon.Heartbeat().UnixNano()) / 1e9
+	})
+	registry.NewCounterFunc("raytracer_daemon_processed_total", "Number of the processed configs and light moves.", func() float64 {
+		return float64(daemon.Metrics().Processed)
+	})
+	registry.NewCounterFunc("raytracer_daemon_superseded_total", "Number of the jobs dropped for a newer config of the same scene.", func() float64 {
+		return float64(daemon.Metrics().Superseded)
+	})
+	registry.NewCounterFunc("raytracer_daemon_cancelled_total", "Number of the jobs dropped because their request was given up.", func() float64 {
+		return float64(daemon.Metrics().Cancelled)
+	})
+	registry.NewCounterFunc("raytracer_daemon_tasks_total", "Number of the run tasks, e.g. the rendered animation frames.", func() float64 {
+		return float64(daemon.Metrics().Tasks)
+	})
+
+	daemon.SetObserver(m.observe)
+
+	return m
+}
+
+// observe records the processed job
+func (m *serverMetrics) observe(stats *backend.JobStats) {
+	m.durations.Observe(stats.Duration.Seconds(), string(stats.Kind), string(stats.Source))
+
+	if reason := validationReason(stats.Err); reason != "" {
+		m.validationFailures.Inc(reason)
+	}
+
+	if stats.Scene != nil {
+		m.scene(stats.Scene)
+	}
+}
+
+// scene sets the gauges of the scene
+func (m *serverMetrics) scene(scene *backend.Scene) {
+	m.rays.Set(float64(scene.Rays), scene.ID)
+	m.boundaries.Set(float64(len(scene.Boundaries)), scene.ID)
+	m.polygons.Set(float64(len(scene.Polygons)), scene.ID)
+	m.litArea.Set(scene.LitArea, scene.ID)
+}
+
+// validationReason returns the reason of the validation error or "" when err is not one
+func validationReason(err error) string {
+	if err == backend.ErrLightOutside {
+		return "light_outside"
+	}
+
+	var invalid *backend.ValidationError
+	if errors.As(err, &invalid) {
+		return invalid.Reason
+	}
+
+	return ""
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+	"github.com/iliyanmotovski/raytracer/backend/persistent"
+)
+
+func TestServerMetricsJobDurationLabels(t *testing.T) {
+	m := newServerMetrics(backend.NewSceneReloadDaemon(persistent.NewInMemorySceneRepository(), make(chan *backend.ConfigChan)))
+	m.observe(&backend.JobStats{Kind: backend.ConfigJob, Source: backend.HotReloadSource, Duration: time.Millisecond})
+
+	buf := &bytes.Buffer{}
+	assert.Nil(t, m.Write(buf))
+	assert.Contains(t, buf.String(), `raytracer_job_duration_seconds_count{kind="config",source="hotreload"} 1`)
+}
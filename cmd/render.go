@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	renderer "github.com/iliyanmotovski/raytracer/backend/render"
+)
+
+// render is the render subcommand, it processes the config file and writes the scene
+// as a PNG image, or as an SVG image when the out file ends with .svg, without starting the server
+func render(args []string) error {
+	flags := flag.NewFlagSet("render", flag.ExitOnError)
+	out := flags.String("o", "scene.png", "path to the rendered PNG or SVG image")
+	options := renderOptionFlags(flags)
+
+	configPath, err := configArg("render", parseArgs(flags, args))
+	if err != nil {
+		return err
+	}
+
+	opts, err := options()
+	if err != nil {
+		return usageError(err.Error())
+	}
+
+	scene, err := loadScene(context.Background(), configPath)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+
+	encode := renderer.PNG
+	if strings.EqualFold(filepath.Ext(*out), ".svg") {
+		encode = renderer.SVG
+	}
+
+	if err := encode(f, scene, opts); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// renderOptionFlags defines the size and the color flags of the rendered
+// images and returns a func which returns the options set by them
+func renderOptionFlags(flags *flag.FlagSet) func() (renderer.Options, error) {
+	width := flags.Int("width", 0, "image width in pixels, computed from the height when 0")
+	height := flags.Int("height", 0, "image height in pixels, computed from the width when 0")
+
+	colors := map[string]*string{}
+	for _, name := range renderer.ColorNames {
+		colors[name] = flags.String(name, "", fmt.Sprintf("%s color as RRGGBB or RRGGBBAA", name))
+	}
+
+	return func() (renderer.Options, error) {
+		opts := renderer.DefaultOptions()
+		opts.Width, opts.Height = *width, *height
+
+		for name, value := range colors {
+			if *value == "" {
+				continue
+			}
+
+			if err := opts.SetColor(name, *value); err != nil {
+				return opts, err
+			}
+		}
+
+		return opts, nil
+	}
+}
@@ -0,0 +1,408 @@
+package main
+
+import (
+	"context"
+	"expvar"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+	"github.com/iliyanmotovski/raytracer/backend/health"
+	"github.com/iliyanmotovski/raytracer/backend/logging"
+	"github.com/iliyanmotovski/raytracer/backend/persistent"
+	renderer "github.com/iliyanmotovski/raytracer/backend/render"
+	"github.com/iliyanmotovski/raytracer/backend/server/http/api"
+	"github.com/iliyanmotovski/raytracer/backend/tracing"
+	"github.com/iliyanmotovski/raytracer/frontend"
+)
+
+// maxAnimations is the number of animation jobs kept by the server
+const maxAnimations = 16
+
+// serve is the serve subcommand, it starts the HTTP server with the
+// scene of the config file and reloads it when the file changes
+func serve(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	httpPort := flags.String("port", "8008", "http listen address")
+	configPath := flags.String("config", "config.txt", "path to config file")
+	storage := flags.String("storage", "memory", "persistence storage - memory, file or bolt")
+	dataDir := flags.String("data", "data", "data directory used by the file and bolt storages")
+	timeout := flags.Duration("timeout", 0, "maximum time to process a single config, 0 means no limit")
+	watch := flags.Duration("watch", 500*time.Millisecond, "how often the config file is checked for changes, 0 disables it")
+	debounce := flags.Duration("debounce", time.Second, "how long the config file must stay unchanged before it is reloaded")
+	frontendDir := flags.String("frontend", "", "serve the frontend from this directory instead of the embedded one, e.g. ../frontend while developing it")
+	workers := flags.Int("workers", runtime.NumCPU(), "number of workers which process the configs and render the animation frames")
+	logLevel := flags.String("log-level", "info", "lowest level of the logged lines - debug, info, warn or error")
+	logFormat := flags.String("log-format", "text", "format of the log lines - text or json")
+	traces := flags.Int("traces", 20, "number of the latest traces shown on /debug/traces, 0 disables it")
+	traceStdout := flags.Bool("trace-stdout", false, "write every trace to stdout as a line of JSON")
+	traceFile := flags.String("trace-file", "", "append every trace to this file in the OTLP JSON format")
+	stallTimeout := flags.Duration("stall-timeout", 30*time.Second, "how long the jobs may wait while no worker picks or finishes one before the server is no longer ready")
+	shutdownTimeout := flags.Duration("shutdown-timeout", 10*time.Second, "how long the requests and the pending jobs are waited for on SIGINT and SIGTERM before they are cancelled")
+
+	if len(parseArgs(flags, args)) > 0 {
+		return usageError("serve takes no arguments")
+	}
+
+	level, err := logging.ParseLevel(*logLevel)
+	if err != nil {
+		return usageError(err.Error())
+	}
+
+	format, err := logging.ParseFormat(*logFormat)
+	if err != nil {
+		return usageError(err.Error())
+	}
+
+	logging.SetDefault(logging.New(os.Stderr, level, format))
+	logger := logging.Default()
+
+	recorder, tracer, err := newTracer(*traces, *traceStdout, *traceFile)
+	if err != nil {
+		return err
+	}
+	if tracer != nil {
+		tracing.SetDefault(tracer)
+		defer func() {
+			if err := tracer.Close(); err != nil {
+				logger.Error("traces not flushed", "error", err)
+			}
+		}()
+	}
+
+	sceneRepo, configRepo, closeRepositories, err := newRepositories(*storage, *dataDir)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := closeRepositories(); err != nil {
+			logger.Error("repositories not closed", "error", err)
+		}
+	}()
+
+	// the readiness checks use the repositories as they are, so the probes do not flood the traces
+	readiness := newReadiness(sceneRepo, configRepo)
+
+	// every stored scene is published to the event streams of its scene
+	sceneEvents := backend.NewSceneEvents()
+	sceneRepo = backend.NewTracedSceneRepository(backend.NewPublishingSceneRepository(sceneRepo, sceneEvents))
+	configRepo = backend.NewTracedConfigRepository(configRepo)
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+
+	configurator := backend.NewTextFileConfigurator(*configPath)
+
+	// a default scene persisted by a previous run takes precedence over
+	// the config file, which can still be reloaded with SIGHUP
+	loadID := logging.NewRequestID()
+	loadCtx, loadSpan := tracing.Start(logging.WithRequestID(ctx, loadID), "config.load", "path", *configPath, "request_id", loadID)
+
+	restored, err := sceneRepo.Get(loadCtx, backend.DefaultSceneID)
+	if err != nil {
+		loadSpan.SetError(err)
+		loadSpan.End()
+		return err
+	}
+
+	var c *backend.Config
+	if restored == nil {
+		c, err = configurator.Parse(loadCtx, configRepo)
+		if err != nil {
+			loadSpan.SetError(err)
+			loadSpan.End()
+			return err
+		}
+	}
+
+	cc := make(chan *backend.ConfigChan)
+
+	sceneReloadDaemon := backend.NewSceneReloadDaemon(sceneRepo, cc)
+	sceneReloadDaemon.SetProcessingTimeout(*timeout)
+	serverMetrics := newServerMetrics(sceneReloadDaemon)
+	sceneReloadDaemon.Start(*workers)
+	readiness.Add("daemon", func(context.Context) error {
+		if sceneReloadDaemon.Stalled(*stallTimeout) {
+			return fmt.Errorf("no worker has picked or finished a job since %s", sceneReloadDaemon.Heartbeat().Format(time.RFC3339))
+		}
+
+		return nil
+	})
+
+	// the frames of the animations are rendered by the workers of the daemon
+	animations := renderer.NewAnimations(ctx, daemonRunner(cc), maxAnimations)
+
+	expvar.Publish("daemon", expvar.Func(func() interface{} {
+		return sceneReloadDaemon.Metrics()
+	}))
+
+	apiRoot := mux.NewRouter().PathPrefix("/api/v1").Subrouter()
+	apiRoot.Use(api.RequestLogging, api.RouteMetrics(serverMetrics.Registry), api.RequestTracing)
+
+	// the /scene routes are aliases to the same /scenes/{scene} routes of the default scene
+	for _, prefix := range []string{"/scene", "/scenes/{scene:[a-zA-Z0-9_-]+}"} {
+		apiRoot.Handle(prefix, api.GetScene(sceneRepo)).Methods("GET")
+		apiRoot.Handle(prefix+".png", api.GetScenePNG(sceneRepo)).Methods("GET")
+		apiRoot.Handle(prefix+".svg", api.GetSceneSVG(sceneRepo)).Methods("GET")
+		apiRoot.Handle(prefix+"/config", api.CreateConfiguration(cc)).Methods("POST")
+		apiRoot.Handle(prefix+"/animations", api.CreateAnimation(sceneRepo, animations)).Methods("POST")
+		apiRoot.Handle(prefix+"/light", api.MoveLight(cc)).Methods("PATCH")
+		apiRoot.Handle(prefix+"/ws", api.SceneSocket(cc)).Methods("GET")
+		apiRoot.Handle(prefix+"/events", api.SceneEventStream(sceneRepo, sceneEvents)).Methods("GET")
+		apiRoot.Handle(prefix+"/versions", api.ListSceneVersions(sceneRepo)).Methods("GET")
+		apiRoot.Handle(prefix+"/versions/{version:[0-9]+}", api.GetSceneVersion(sceneRepo)).Methods("GET")
+		apiRoot.Handle(prefix+"/versions/{version:[0-9]+}/restore", api.RestoreSceneVersion(sceneRepo, cc)).Methods("POST")
+		apiRoot.Handle(prefix+"/versions/{version:[0-9]+}/diff", api.DiffSceneVersions(sceneRepo)).Methods("GET")
+	}
+
+	apiRoot.Handle("/animations/{animation:[0-9a-f]+}", api.GetAnimation(animations)).Methods("GET")
+	apiRoot.Handle("/animations/{animation:[0-9a-f]+}/result", api.GetAnimationResult(animations)).Methods("GET")
+
+	http.Handle("/", http.FileServer(http.FS(frontendAssets(*frontendDir))))
+	http.Handle("/api/v1/", apiRoot)
+	http.Handle("/metrics", serverMetrics)
+	http.HandleFunc("/healthz", health.Alive)
+	http.Handle("/readyz", readiness)
+	http.Handle("/version", health.NewBuildInfo(version, commit, buildTime))
+	if recorder != nil {
+		http.Handle("/debug/traces", recorder)
+	}
+
+	l, err := net.Listen("tcp", ":"+*httpPort)
+	if err != nil {
+		return err
+	}
+
+	// the requests are cancelled once the server is shut down, which also ends the
+	// hijacked websocket connections, those are not waited for by http.Server.Shutdown
+	requestsCtx, cancelRequests := context.WithCancel(ctx)
+	defer cancelRequests()
+
+	var requests sync.WaitGroup
+	server := &http.Server{
+		Handler:     countRequests(&requests, http.DefaultServeMux),
+		BaseContext: func(net.Listener) context.Context { return requestsCtx },
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		if err := server.Serve(l); err != http.ErrServerClosed {
+			errChan <- err
+		}
+	}()
+
+	// configuration hot reload on SIGHUP and on changes of the config file
+	var changes <-chan struct{}
+	if *watch > 0 {
+		changes = backend.WatchFile(ctx, *configPath, *watch, *debounce)
+	}
+
+	sigchan := make(chan os.Signal, 1)
+	signal.Notify(sigchan, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigchan)
+
+	// the server is already listening, so it is alive but not ready while the initial scene is processed
+	if restored == nil {
+		_, err := backend.ReloadSceneFrom(loadCtx, cc, c, backend.InitialSource)
+		if err != nil {
+			loadSpan.SetError(err)
+			logger.Error("config not loaded", "path", *configPath, "error", err)
+		}
+		readiness.loaded(err)
+	} else {
+		logger.Info("persisted scene restored", "dir", *dataDir)
+		serverMetrics.scene(restored)
+		readiness.loaded(nil)
+	}
+	loadSpan.End()
+
+	var serveErr error
+loop:
+	for {
+		select {
+		case s := <-sigchan:
+			if s == syscall.SIGHUP {
+				logger.Info("reloading configuration", "path", *configPath)
+				readiness.reloaded(reloadConfig(ctx, configurator, configRepo, cc))
+				continue
+			}
+
+			logger.Info("shutting down", "signal", s)
+			break loop
+		case <-changes:
+			logger.Info("reloading changed configuration", "path", *configPath)
+			readiness.reloaded(reloadConfig(ctx, configurator, configRepo, cc))
+		case serveErr = <-errChan:
+			break loop
+		}
+	}
+
+	readiness.Shutdown()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
+
+	// the event streams never end on their own, so they are closed before
+	// the server stops accepting connections and waits for the requests
+	sceneEvents.Close()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Warn("connections closed before their requests were handled", "error", err)
+		server.Close()
+	}
+
+	cancelRequests()
+	requests.Wait()
+
+	// no job is sent to the daemon anymore, so its workers finish the pending ones and stop,
+	// the jobs which are not finished in time are cancelled
+	animations.Close()
+	close(cc)
+	if err := sceneReloadDaemon.Wait(shutdownCtx); err != nil {
+		logger.Warn("pending jobs cancelled", "error", err)
+	}
+
+	return serveErr
+}
+
+// countRequests counts the requests being handled by h, including the hijacked connections
+func countRequests(requests *sync.WaitGroup, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		defer requests.Done()
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+// reloadConfig parses the config file again and reloads the default scene with it as a hot
+// reload job, the errors are logged and returned, an invalid config keeps the current scene.
+// Every reload gets its own request ID, so its lines can be correlated with the ones of the
+// daemon processing it
+func reloadConfig(ctx context.Context, configurator backend.Configurator, configRepo backend.ConfigRepository, cc chan *backend.ConfigChan) error {
+	id := logging.NewRequestID()
+	ctx, span := tracing.Start(logging.WithRequestID(ctx, id), "config.reload", "request_id", id)
+	defer span.End()
+
+	c, err := configurator.Parse(ctx, configRepo)
+	if err != nil {
+		span.SetError(err)
+		logging.FromContext(ctx).Warn("config not reloaded", "error", err)
+		return err
+	}
+
+	if _, err := backend.ReloadSceneFrom(ctx, cc, c, backend.HotReloadSource); err != nil {
+		span.SetError(err)
+		logging.FromContext(ctx).Warn("config not reloaded", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// newTracer creates the Tracer which keeps the latest traces in the returned Recorder and exports
+// them to stdout and to the OTLP file when they are set. It returns a nil Recorder when no trace
+// is kept and a nil Tracer, which disables tracing, when the traces are neither kept nor exported
+func newTracer(traces int, stdout bool, file string) (*tracing.Recorder, *tracing.Tracer, error) {
+	var recorder *tracing.Recorder
+	var exporters []tracing.Exporter
+
+	if traces > 0 {
+		recorder = tracing.NewRecorder(traces)
+		exporters = append(exporters, recorder)
+	}
+
+	if stdout {
+		exporters = append(exporters, tracing.NewJSONExporter(os.Stdout))
+	}
+
+	if file != "" {
+		exporter, err := tracing.NewOTLPFileExporter(file, "raytracer")
+		if err != nil {
+			return nil, nil, err
+		}
+
+		exporters = append(exporters, exporter)
+	}
+
+	if len(exporters) == 0 {
+		return nil, nil, nil
+	}
+
+	return recorder, tracing.NewTracer(func(err error) {
+		logging.Default().Warn("trace not exported", "error", err)
+	}, exporters...), nil
+}
+
+// frontendAssets returns the embedded frontend, or the files of the directory when it is set,
+// which are read on every request, so the changes of the frontend show up without a rebuild
+func frontendAssets(dir string) fs.FS {
+	if dir != "" {
+		return os.DirFS(dir)
+	}
+
+	return frontend.Assets
+}
+
+// newRepositories creates the scene and config repositories for the given storage
+// and a func which closes them, it releases the data directory and the database
+func newRepositories(storage, dir string) (backend.SceneRepository, backend.ConfigRepository, func() error, error) {
+	switch storage {
+	case "memory":
+		return persistent.NewInMemorySceneRepository(), persistent.NewInMemoryConfigRepository(), func() error { return nil }, nil
+	case "file":
+		sceneRepo, err := persistent.NewFileSceneRepository(dir)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		configRepo, err := persistent.NewFileConfigRepository(dir)
+		if err != nil {
+			sceneRepo.(io.Closer).Close()
+			return nil, nil, nil, err
+		}
+
+		closeRepositories := func() error {
+			sceneErr := sceneRepo.(io.Closer).Close()
+			if err := configRepo.(io.Closer).Close(); err != nil {
+				return err
+			}
+
+			return sceneErr
+		}
+
+		return sceneRepo, configRepo, closeRepositories, nil
+	case "bolt":
+		db, err := persistent.OpenBoltDB(dir)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		sceneRepo, err := persistent.NewBoltSceneRepository(db)
+		if err != nil {
+			db.Close()
+			return nil, nil, nil, err
+		}
+
+		configRepo, err := persistent.NewBoltConfigRepository(db)
+		if err != nil {
+			db.Close()
+			return nil, nil, nil, err
+		}
+
+		return sceneRepo, configRepo, db.Close, nil
+	default:
+		return nil, nil, nil, fmt.Errorf("unknown storage %q", storage)
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+	"github.com/iliyanmotovski/raytracer/backend/persistent"
+)
+
+func TestServeShutsDownGracefully(t *testing.T) {
+	for _, signal := range []syscall.Signal{syscall.SIGTERM, syscall.SIGINT} {
+		t.Run(signal.String(), func(t *testing.T) {
+			testServeShutsDownGracefully(t, signal)
+		})
+	}
+}
+
+// testServeShutsDownGracefully starts the server with the file storage, sends it the signal
+// while a config is processed and checks that it is stored before the server exits
+func testServeShutsDownGracefully(t *testing.T, signal syscall.Signal) {
+	if testing.Short() {
+		t.Skip("builds and runs the server")
+	}
+
+	dir, err := ioutil.TempDir("", "raytracer-serve")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	binary := filepath.Join(dir, "raytracer")
+	build := exec.Command("go", "build", "-o", binary, "-ldflags", "-X main.version=v1.2.0 -X main.commit=abc123", ".")
+	build.Stderr = os.Stderr
+	if !assert.Nil(t, build.Run()) {
+		return
+	}
+
+	config := "800 500\n250 300\n1\n3 600 200 646 133 646 261\n"
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "config.txt"), []byte(config), 0644))
+
+	port := freePort(t)
+	url := "http://localhost:" + port
+
+	server := exec.Command(binary, "serve", "-port", port, "-storage", "file", "-watch", "0")
+	server.Dir = dir
+	server.Stderr = os.Stderr
+	if !assert.Nil(t, server.Start()) {
+		return
+	}
+	defer server.Process.Kill()
+
+	// the server is ready once the scene of the config file is processed
+	if !assert.Eventually(t, func() bool {
+		resp, err := http.Get(url + "/readyz")
+		if err != nil {
+			return false
+		}
+
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusOK
+	}, 10*time.Second, 10*time.Millisecond) {
+		return
+	}
+
+	loaded, err := http.Get(url + "/api/v1/scene")
+	assert.Nil(t, err)
+	loaded.Body.Close()
+	assert.Equal(t, http.StatusOK, loaded.StatusCode)
+
+	alive, err := http.Get(url + "/healthz")
+	assert.Nil(t, err)
+	alive.Body.Close()
+	assert.Equal(t, http.StatusOK, alive.StatusCode)
+
+	info := struct{ Version, Commit string }{}
+	resp, err := http.Get(url + "/version")
+	assert.Nil(t, err)
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&info))
+	resp.Body.Close()
+	assert.Equal(t, "v1.2.0", info.Version)
+	assert.Equal(t, "abc123", info.Commit)
+
+	// the event stream is ended by the shutdown
+	events, err := http.Get(url + "/api/v1/scene/events")
+	assert.Nil(t, err)
+	defer events.Body.Close()
+
+	streamEnded := make(chan struct{})
+	go func() {
+		ioutil.ReadAll(events.Body)
+		close(streamEnded)
+	}()
+
+	// the config takes a while to process, so it is still running when the signal is sent
+	body := configBody(t, benchConfig(40, false))
+
+	status := make(chan int, 1)
+	go func() {
+		resp, err := http.Post(url+"/api/v1/scene/config", "application/json", bytes.NewReader(body))
+		if err != nil {
+			status <- 0
+			return
+		}
+
+		resp.Body.Close()
+		status <- resp.StatusCode
+	}()
+
+	assert.Eventually(t, func() bool { return daemonMetrics(t, url).Running == 1 }, 10*time.Second, 10*time.Millisecond)
+	assert.Nil(t, server.Process.Signal(signal))
+
+	exited := make(chan error, 1)
+	go func() { exited <- server.Wait() }()
+
+	select {
+	case err := <-exited:
+		assert.Nil(t, err)
+	case <-time.After(20 * time.Second):
+		t.Fatal("the server has not exited")
+	}
+
+	assert.Equal(t, http.StatusCreated, <-status)
+
+	select {
+	case <-streamEnded:
+	case <-time.After(time.Second):
+		t.Error("the event stream has not ended")
+	}
+
+	// the repository is closed, so the data directory can be locked again
+	sceneRepo, err := persistent.NewFileSceneRepository(filepath.Join(dir, "data"))
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	scene, err := sceneRepo.Get(context.Background(), backend.DefaultSceneID)
+	assert.Nil(t, err)
+	assert.Len(t, scene.Polygons, 40*40)
+}
+
+func TestReloadConfigIsHotReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "raytracer-reload")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.txt")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("800 500\n250 300\n1\n3 600 200 646 133 646 261\n"), 0644))
+
+	cc := make(chan *backend.ConfigChan)
+	jobs := make(chan *backend.ConfigChan, 1)
+	go func() {
+		job := <-cc
+		jobs <- job
+		job.Response <- &backend.SceneReloadResponse{Scene: &backend.Scene{}}
+	}()
+
+	assert.Nil(t, reloadConfig(context.Background(), backend.NewTextFileConfigurator(path), persistent.NewInMemoryConfigRepository(), cc))
+	assert.Equal(t, backend.HotReloadSource, (<-jobs).Source)
+}
+
+func freePort(t *testing.T) string {
+	l, err := net.Listen("tcp", "localhost:0")
+	assert.Nil(t, err)
+	defer l.Close()
+
+	return strconv.Itoa(l.Addr().(*net.TCPAddr).Port)
+}
+
+// configBody encodes the config as the body of the config endpoint, in which the polygons are lists of vertices
+func configBody(t *testing.T, config *backend.Config) []byte {
+	type xy struct{ X, Y float64 }
+
+	dto := struct {
+		Light, Scene xy
+		Polygons     [][]xy
+	}{
+		Light: xy{config.Light.X, config.Light.Y},
+		Scene: xy{config.Scene.X, config.Scene.Y},
+	}
+
+	for _, polygon := range config.Polygons {
+		vertices := make([]xy, len(polygon.Loop))
+		for i, v := range polygon.Loop {
+			vertices[i] = xy{v.X, v.Y}
+		}
+
+		dto.Polygons = append(dto.Polygons, vertices)
+	}
+
+	body, err := json.Marshal(dto)
+	assert.Nil(t, err)
+
+	return body
+}
+
+// daemonMetrics returns the metrics of the daemon published by the server with expvar
+func daemonMetrics(t *testing.T, url string) backend.DaemonMetrics {
+	vars := struct{ Daemon backend.DaemonMetrics }{}
+
+	resp, err := http.Get(url + "/debug/vars")
+	if err != nil {
+		return vars.Daemon
+	}
+	defer resp.Body.Close()
+
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&vars))
+	return vars.Daemon
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/iliyanmotovski/raytracer/backend"
+	"github.com/iliyanmotovski/raytracer/backend/persistent"
+)
+
+// validate is the validate subcommand, it parses the config file and validates its polygons,
+// walls and ellipses without casting the rays. It fails when the config is invalid
+func validate(args []string) error {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+
+	configPath, err := configArg("validate", parseArgs(flags, args))
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	config, err := backend.NewTextFileConfigurator(configPath).Parse(ctx, persistent.NewInMemoryConfigRepository())
+	if err != nil {
+		return fmt.Errorf("%s is invalid: %v", configPath, err)
+	}
+
+	if err := backend.NewScene(config).Validate(ctx); err != nil {
+		return fmt.Errorf("%s is invalid: %v", configPath, err)
+	}
+
+	fmt.Printf("%s is valid: %d polygons, %d walls, %d ellipses\n", configPath, len(config.Polygons), len(config.Walls), len(config.Ellipses))
+	return nil
+}
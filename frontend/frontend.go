@@ -0,0 +1,10 @@
+// Package frontend holds the assets of the browser app, they are embedded
+// into the binary, so the server runs from any working directory
+package frontend
+
+import "embed"
+
+// Assets are the files of the browser app with index.html at the root
+//
+//go:embed index.html *.js *.png
+var Assets embed.FS
@@ -0,0 +1,27 @@
+package frontend_test
+
+import (
+	"io/fs"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iliyanmotovski/raytracer/frontend"
+)
+
+func TestAssetsHaveTheScriptsOfIndex(t *testing.T) {
+	index, err := fs.ReadFile(frontend.Assets, "index.html")
+	assert.Nil(t, err)
+
+	scripts := regexp.MustCompile(`<script src="([^"]+)"`).FindAllStringSubmatch(string(index), -1)
+	assert.NotEmpty(t, scripts)
+
+	for _, script := range scripts {
+		_, err := fs.Stat(frontend.Assets, script[1])
+		assert.Nil(t, err, script[1])
+	}
+
+	_, err = fs.Stat(frontend.Assets, "sun.png")
+	assert.Nil(t, err)
+}
@@ -13,7 +13,19 @@ class Polygons {
             }
 
             beginShape();
-            polygon.forEach(vertice => vertex(vertice.X, invert(vertice.Y)));
+            vertex(polygon[0].X, invert(polygon[0].Y));
+            polygon.forEach((vertice, i) => {
+                let next = polygon[(i + 1) % polygon.length];
+                let controls = vertice.Controls || [];
+
+                if (controls.length === 1) {
+                    quadraticVertex(controls[0].X, invert(controls[0].Y), next.X, invert(next.Y));
+                } else if (controls.length === 2) {
+                    bezierVertex(controls[0].X, invert(controls[0].Y), controls[1].X, invert(controls[1].Y), next.X, invert(next.Y));
+                } else if (i !== polygon.length - 1) {
+                    vertex(next.X, invert(next.Y));
+                }
+            });
             endShape(CLOSE);
         });
     };
@@ -1,16 +1,67 @@
 let img;
 let scene;
 let columns;
+let walls;
+let ellipses;
 let particle;
 let triangles;
-let refreshIntervalId;
 
 let dragged = false;
-let getSceneUrl = 'http://localhost:8008/api/v1/scene'
-let postConfigUrl = 'http://localhost:8008/api/v1/scene/config'
+// the scene is selected with the "scene" query parameter, e.g. ?scene=kitchen,
+// without it the default scene is shown
+let sceneId = new URLSearchParams(window.location.search).get('scene');
+// the API is served by the same server as the page, on whatever host and port it runs
+let apiUrl = new URL('api/v1/', window.location.href).href
+let defaultSceneUrl = apiUrl + 'scene'
+let sceneUrl = sceneId ? apiUrl + 'scenes/' + encodeURIComponent(sceneId) : defaultSceneUrl
+let getSceneUrl = sceneUrl
+let postConfigUrl = sceneUrl + '/config'
+let patchLightUrl = sceneUrl + '/light'
+let socketUrl = sceneUrl.replace(/^http/, 'ws') + '/ws'
+let socket;
 
 function preload() {
-    httpGet(getSceneUrl, 'json', false, resp, err);
+    httpGet(getSceneUrl, 'json', false, resp, sceneId ? copyDefaultScene : err);
+    listenForChanges();
+}
+
+// the scene is fetched again whenever someone else stores a new version of it,
+// the browser reconnects on its own and resumes after the last received version
+function listenForChanges() {
+    let events = new EventSource(sceneUrl + '/events');
+    events.addEventListener('scene', () => {
+        if (!dragged) {
+            httpGet(getSceneUrl, 'json', false, resp, err);
+        }
+    });
+}
+
+// a scene which does not exist yet starts as a copy of the default scene
+function copyDefaultScene() {
+    httpGet(defaultSceneUrl, 'json', false, (response) => {
+        httpPost(postConfigUrl, 'json', configOf(response), () => {
+            httpGet(getSceneUrl, 'json', false, resp, err);
+        }, err);
+    }, err);
+}
+
+// the light positions are streamed over the socket while the light is dragged
+// and the server streams back the lit triangles of the latest position
+function openSocket() {
+    socket = new WebSocket(socketUrl);
+    socket.onmessage = (event) => {
+        let message = JSON.parse(event.data);
+        if (message.Error) {
+            err(message.Error);
+            return;
+        }
+
+        scene.Triangles = message.Triangles;
+        scene.LitArea = message.LitArea;
+    };
+    socket.onclose = () => {
+        socket = null;
+    };
 }
 
 function setupScene() {
@@ -28,6 +79,12 @@ function draw() {
     triangles = new Polygons(scene.Triangles, [217, 206, 189], false);
     triangles.display();
 
+    walls = new Walls(scene.Walls, [181, 121, 24]);
+    walls.display();
+
+    ellipses = new Polygons((scene.Ellipses || []).map(ellipse => ellipse.Loop), [181, 121, 24], true);
+    ellipses.display();
+
     fill(0,0,0);
     textSize(19);
     text('Lit area is: ' + scene.LitArea + '%', 10, 30);
@@ -50,22 +107,45 @@ function mousePressed() {
 
     if (mouseX > x && mouseX < x + width && mouseY > y && mouseY < y + height) {
         dragged = true;
-        updateConfig(100)
+        if (!socket) {
+            openSocket();
+        }
     }
 }
 
+function mouseDragged() {
+    if (dragged) {
+        updateLight(mouseX, invert(mouseY));
+    }
+}
+
+// the released position is the only one of the drag which is stored as a new version
 function mouseReleased() {
+    if (dragged && socket && socket.readyState === WebSocket.OPEN) {
+        socket.send(JSON.stringify({x: scene.Light.X, y: scene.Light.Y, release: true}));
+    }
+
     dragged = false;
-    clearInterval(refreshIntervalId)
 }
 
-function updateConfig(interval) {
-    refreshIntervalId = setInterval(() => {
-        postData = {light: scene.Light, polygons: scene.Polygons, scene: {X: scene.Width, Y: scene.Height}};
-        httpPost(postConfigUrl, 'json', postData, () => {
-            httpGet(getSceneUrl, 'json', false, resp, err);
-        }, err);
-    },interval);
+// only the light position is sent while dragging, over the socket when it is open
+// and as a single request otherwise, the response is the scene lit from it
+function updateLight(x, y) {
+    if (socket && socket.readyState === WebSocket.OPEN) {
+        socket.send(JSON.stringify({x: x, y: y}));
+        return;
+    }
+
+    httpDo(patchLightUrl, 'PATCH', 'json', {x: x, y: y}, resp, (e) => {
+        // a newer light position has been sent meanwhile, its response wins
+        if (e.status !== 409) {
+            err(e);
+        }
+    });
+}
+
+function configOf(scene) {
+    return {light: scene.Light, polygons: scene.Polygons, walls: scene.Walls, ellipses: scene.Ellipses, flatteningTolerance: scene.FlatteningTolerance, scene: {X: scene.Width, Y: scene.Height}};
 }
 
 function resp(response) {
@@ -0,0 +1,21 @@
+class Walls {
+    constructor(walls, color) {
+        this.walls = walls || [];
+        this.color = color;
+    };
+
+    display() {
+        this.walls.map(wall => {
+            stroke(this.color[0], this.color[1], this.color[2]);
+            strokeWeight(3);
+            noFill();
+
+            beginShape();
+            wall.forEach(vertice => vertex(vertice.X, invert(vertice.Y)));
+            endShape();
+
+            strokeWeight(1);
+            stroke(0);
+        });
+    };
+}
\ No newline at end of file
@@ -1,8 +1,10 @@
 module github.com/iliyanmotovski/raytracer
 
-go 1.13
+go 1.16
 
 require (
 	github.com/gorilla/mux v1.7.4
+	github.com/gorilla/websocket v1.4.2
 	github.com/stretchr/testify v1.5.1
+	go.etcd.io/bbolt v1.3.6
 )